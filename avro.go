@@ -0,0 +1,608 @@
+package gron
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statementsFromAvro takes an io.Reader containing an Avro object
+// container file and returns statements or an error on failure. The
+// records are decoded with the schema embedded in the file, and are
+// treated as elements of a top-level array, as GronStream treats lines
+// of JSON; so there's always an array, even for a single record
+func statementsFromAvro(r io.Reader, prefix statement, c *config) (statements, error) {
+	in, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	records, err := readAvro(in)
+	if err != nil {
+		return nil, err
+	}
+
+	ss := make(statements, 0, 32)
+	ss.addWithValue(prefix, token{"[]", typEmptyArray})
+	for i, record := range records {
+		ss.fill(prefix.withNumericKey(i), record, 0, c)
+	}
+	return ss, nil
+}
+
+// avroMagic is what every object container file starts with
+const avroMagic = "Obj\x01"
+
+// readAvro decodes the records in an object container file: a header
+// with the schema, the codec and a sync marker; then blocks of records,
+// each followed by the sync marker
+func readAvro(in []byte) ([]interface{}, error) {
+	if !bytes.HasPrefix(in, []byte(avroMagic)) {
+		return nil, fmt.Errorf("not an Avro object container file")
+	}
+	d := &avroDecoder{r: bytes.NewReader(in[len(avroMagic):])}
+
+	meta := make(map[string][]byte)
+	err := d.blocks(&avroSchema{typ: "bytes"}, func() error {
+		k, err := d.bytes()
+		if err != nil {
+			return err
+		}
+		meta[string(k)], err = d.bytes()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid Avro header: %s", err)
+	}
+	sync, err := d.next(16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Avro header: %s", err)
+	}
+
+	rawSchema, ok := meta["avro.schema"]
+	if !ok {
+		return nil, fmt.Errorf("Avro file has no schema")
+	}
+	var schemaJSON interface{}
+	if err := json.Unmarshal(rawSchema, &schemaJSON); err != nil {
+		return nil, fmt.Errorf("invalid Avro schema: %s", err)
+	}
+	schema, err := newAvroSchemas().parse(schemaJSON, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid Avro schema: %s", err)
+	}
+
+	codec := "null"
+	if v, ok := meta["avro.codec"]; ok {
+		codec = string(v)
+	}
+	if codec != "null" && codec != "deflate" {
+		return nil, fmt.Errorf("unsupported Avro codec %q; only null and deflate are supported", codec)
+	}
+
+	records := make([]interface{}, 0)
+	for d.r.Len() > 0 {
+		count, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		size, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		data, err := d.next(size)
+		if err != nil {
+			return nil, err
+		}
+		if codec == "deflate" {
+			data, err = ioutil.ReadAll(flate.NewReader(bytes.NewReader(data)))
+			if err != nil {
+				return nil, fmt.Errorf("failed to inflate Avro block: %s", err)
+			}
+		}
+
+		block := &avroDecoder{r: bytes.NewReader(data)}
+		if err := block.checkCount(count, schema); err != nil {
+			return nil, err
+		}
+		for i := int64(0); i < count; i++ {
+			v, err := block.value(schema)
+			if err != nil {
+				return nil, fmt.Errorf("Avro record %d: %s", len(records), err)
+			}
+			records = append(records, v)
+		}
+		if block.r.Len() > 0 {
+			return nil, fmt.Errorf("Avro block has %d bytes left over after its records", block.r.Len())
+		}
+
+		marker, err := d.next(16)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(marker, sync) {
+			return nil, fmt.Errorf("Avro block isn't followed by the file's sync marker")
+		}
+	}
+	return records, nil
+}
+
+// An avroSchema is a parsed Avro schema. The typ is the name of a
+// primitive type (e.g. long), or one of record, enum, array, map,
+// union or fixed; references to named types are resolved as the
+// schema is parsed, so records can refer to themselves
+type avroSchema struct {
+	typ      string
+	name     string
+	fields   []avroField
+	symbols  []string
+	items    *avroSchema
+	branches []*avroSchema
+	size     int
+	logical  string
+	scale    int
+}
+
+// an avroField is a field of a record
+type avroField struct {
+	name   string
+	schema *avroSchema
+}
+
+// avroPrimitives are the names of the primitive types
+var avroPrimitives = map[string]bool{
+	"null": true, "boolean": true, "int": true, "long": true,
+	"float": true, "double": true, "bytes": true, "string": true,
+}
+
+// avroSchemas holds the named types, by their full names,
+// that have been seen while parsing a schema
+type avroSchemas struct {
+	named map[string]*avroSchema
+}
+
+// newAvroSchemas returns an avroSchemas with no named types
+func newAvroSchemas() *avroSchemas {
+	return &avroSchemas{named: make(map[string]*avroSchema)}
+}
+
+// parse parses a schema, decoded from JSON, in the provided namespace
+func (p *avroSchemas) parse(v interface{}, namespace string) (*avroSchema, error) {
+	switch vv := v.(type) {
+	case string:
+		if avroPrimitives[vv] {
+			return &avroSchema{typ: vv}, nil
+		}
+		return p.lookup(vv, namespace)
+
+	case []interface{}:
+		s := &avroSchema{typ: "union"}
+		for _, b := range vv {
+			branch, err := p.parse(b, namespace)
+			if err != nil {
+				return nil, err
+			}
+			s.branches = append(s.branches, branch)
+		}
+		return s, nil
+
+	case map[string]interface{}:
+		typ, ok := vv["type"].(string)
+		if !ok {
+			// e.g. {"type": {"type": "array", "items": "int"}}
+			return p.parse(vv["type"], namespace)
+		}
+		return p.parseComplex(typ, vv, namespace)
+	}
+	return nil, fmt.Errorf("unexpected schema %v", v)
+}
+
+// parseComplex parses a schema written as an object with the provided type
+func (p *avroSchemas) parseComplex(typ string, v map[string]interface{}, namespace string) (*avroSchema, error) {
+	s := &avroSchema{typ: typ}
+	s.logical, _ = v["logicalType"].(string)
+	if scale, ok := v["scale"].(float64); ok {
+		s.scale = int(scale)
+	}
+
+	switch typ {
+	case "record", "error", "enum", "fixed":
+		if err := p.register(s, v, &namespace); err != nil {
+			return nil, err
+		}
+	}
+
+	switch typ {
+	case "record", "error":
+		s.typ = "record"
+		fields, _ := v["fields"].([]interface{})
+		for _, f := range fields {
+			fm, ok := f.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid field in record %s", s.name)
+			}
+			name, _ := fm["name"].(string)
+			fs, err := p.parse(fm["type"], namespace)
+			if err != nil {
+				return nil, fmt.Errorf("field %s of record %s: %s", name, s.name, err)
+			}
+			s.fields = append(s.fields, avroField{name, fs})
+		}
+
+	case "enum":
+		symbols, _ := v["symbols"].([]interface{})
+		for _, sym := range symbols {
+			str, _ := sym.(string)
+			s.symbols = append(s.symbols, str)
+		}
+
+	case "fixed":
+		size, ok := v["size"].(float64)
+		if !ok || size < 0 {
+			return nil, fmt.Errorf("fixed %s has no size", s.name)
+		}
+		s.size = int(size)
+
+	case "array", "map":
+		key := "items"
+		if typ == "map" {
+			key = "values"
+		}
+		items, err := p.parse(v[key], namespace)
+		if err != nil {
+			return nil, err
+		}
+		s.items = items
+
+	default:
+		if !avroPrimitives[typ] {
+			return p.lookup(typ, namespace)
+		}
+	}
+	return s, nil
+}
+
+// register adds a named type, so that it can be referred to by its
+// name; setting the namespace to the one its contents are in
+func (p *avroSchemas) register(s *avroSchema, v map[string]interface{}, namespace *string) error {
+	name, _ := v["name"].(string)
+	if name == "" {
+		return fmt.Errorf("%s has no name", s.typ)
+	}
+	if ns, ok := v["namespace"].(string); ok && !strings.Contains(name, ".") {
+		*namespace = ns
+	}
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		*namespace = name[:i]
+	} else if *namespace != "" {
+		name = *namespace + "." + name
+	}
+	s.name = name
+	p.named[name] = s
+	return nil
+}
+
+// lookup returns the named type with the provided name,
+// which is relative to the namespace unless it has dots in it
+func (p *avroSchemas) lookup(name, namespace string) (*avroSchema, error) {
+	if s, ok := p.named[name]; ok {
+		return s, nil
+	}
+	if s, ok := p.named[namespace+"."+name]; ok && namespace != "" {
+		return s, nil
+	}
+	return nil, fmt.Errorf("unknown type %s", name)
+}
+
+// zeroWidth returns true if values of the type take no bytes at all
+func (s *avroSchema) zeroWidth() bool {
+	return s.zeroWidthWithin(make(map[*avroSchema]bool))
+}
+
+// zeroWidthWithin is zeroWidth for a type inside the provided records;
+// a record inside itself can't be decoded, so it's not zero width
+func (s *avroSchema) zeroWidthWithin(records map[*avroSchema]bool) bool {
+	switch s.typ {
+	case "null":
+		return true
+	case "fixed":
+		return s.size == 0
+	case "record":
+		if records[s] {
+			return false
+		}
+		records[s] = true
+		defer delete(records, s)
+		for _, f := range s.fields {
+			if !f.schema.zeroWidthWithin(records) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// An avroDecoder decodes Avro's binary encoding into the types used for
+// values decoded from JSON. Records and maps are objects, enums are
+// their symbols and unions are whichever of their types the value is;
+// unlike Avro's own JSON encoding, which wraps them in an object with
+// the type as its key. Bytes and fixed are base64, as encoding/json
+// writes a []byte. Of the logical types, dates, times and timestamps
+// are written as strings like RFC 3339's, and decimals as numbers.
+// Floats that JSON can't represent (infinities and NaN) are an error
+type avroDecoder struct {
+	r     *bytes.Reader
+	depth int
+}
+
+// maxAvroDepth limits how deeply values can be nested, as a record
+// can contain itself; so a schema can make the decoder recurse forever
+const maxAvroDepth = 10000
+
+// errAvroTooDeep is returned for values nested more than maxAvroDepth
+var errAvroTooDeep = fmt.Errorf("values are nested more than %d deep", maxAvroDepth)
+
+// maxAvroZeroWidth limits how many values that take no bytes, like
+// nulls, there can be in an array; as their count can't be checked
+// against the number of bytes that are left
+const maxAvroZeroWidth = 1 << 20
+
+// checkCount returns an error if there can't be as many values
+// of a type as the provided count in what's left of the input
+func (d *avroDecoder) checkCount(count int64, s *avroSchema) error {
+	limit := int64(d.r.Len())
+	if s.zeroWidth() {
+		limit = maxAvroZeroWidth
+	}
+	if count < 0 || count > limit {
+		return fmt.Errorf("count of %d values is more than there can be", count)
+	}
+	return nil
+}
+
+// next returns the next n bytes
+func (d *avroDecoder) next(n int64) ([]byte, error) {
+	if n < 0 || n > int64(d.r.Len()) {
+		return nil, fmt.Errorf("length %d runs past the end of the input", n)
+	}
+	b := make([]byte, n)
+	d.r.Read(b)
+	return b, nil
+}
+
+// bytes returns the next bytes or string, which start with their length
+func (d *avroDecoder) bytes() ([]byte, error) {
+	n, err := d.long()
+	if err != nil {
+		return nil, err
+	}
+	return d.next(n)
+}
+
+// long returns the next int or long, which are zigzag varints
+func (d *avroDecoder) long() (int64, error) {
+	n, err := binary.ReadVarint(d.r)
+	return n, unexpectedEOF(err)
+}
+
+// value returns the next value, of the provided type
+func (d *avroDecoder) value(s *avroSchema) (interface{}, error) {
+	d.depth++
+	defer func() { d.depth-- }()
+	if d.depth > maxAvroDepth {
+		return nil, errAvroTooDeep
+	}
+
+	switch s.typ {
+	case "null":
+		return nil, nil
+
+	case "boolean":
+		b, err := d.next(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+
+	case "int", "long":
+		n, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		return avroLogicalInt(n, s.logical), nil
+
+	case "float":
+		b, err := d.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return avroFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), 32)
+	case "double":
+		b, err := d.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return avroFloat(math.Float64frombits(binary.LittleEndian.Uint64(b)), 64)
+
+	case "bytes", "string":
+		b, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		if s.typ == "string" {
+			return string(b), nil
+		}
+		return avroBytes(b, s), nil
+
+	case "fixed":
+		b, err := d.next(int64(s.size))
+		if err != nil {
+			return nil, err
+		}
+		return avroBytes(b, s), nil
+
+	case "enum":
+		n, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 || n >= int64(len(s.symbols)) {
+			return nil, fmt.Errorf("enum %s has no symbol %d", s.name, n)
+		}
+		return s.symbols[n], nil
+
+	case "union":
+		n, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 || n >= int64(len(s.branches)) {
+			return nil, fmt.Errorf("union has no type %d", n)
+		}
+		return d.value(s.branches[n])
+
+	case "record":
+		out := make(map[string]interface{}, len(s.fields))
+		for _, f := range s.fields {
+			v, err := d.value(f.schema)
+			if err == errAvroTooDeep {
+				return nil, err
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", f.name, err)
+			}
+			out[f.name] = v
+		}
+		return out, nil
+
+	case "array":
+		out := make([]interface{}, 0)
+		err := d.blocks(s.items, func() error {
+			v, err := d.value(s.items)
+			out = append(out, v)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return out, nil
+
+	case "map":
+		out := make(map[string]interface{})
+		err := d.blocks(s.items, func() error {
+			k, err := d.bytes()
+			if err != nil {
+				return err
+			}
+			v, err := d.value(s.items)
+			out[string(k)] = v
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+
+	return nil, fmt.Errorf("unexpected type %s", s.typ)
+}
+
+// blocks reads the blocks that arrays and maps are written in, calling
+// the provided function for each item. Each block starts with a count
+// of its items; a negative count is followed by the block's size
+func (d *avroDecoder) blocks(items *avroSchema, fn func() error) error {
+	for {
+		count, err := d.long()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		if count < 0 {
+			count = -count
+			if _, err := d.long(); err != nil {
+				return err
+			}
+		}
+		if err := d.checkCount(count, items); err != nil {
+			return err
+		}
+		for i := int64(0); i < count; i++ {
+			if err := fn(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// avroLogicalInt returns an int or long, or a string for
+// the logical types that are dates, times or timestamps
+func avroLogicalInt(n int64, logical string) interface{} {
+	midnight := time.Unix(0, 0).UTC()
+	switch logical {
+	case "date":
+		return midnight.AddDate(0, 0, int(n)).Format("2006-01-02")
+	case "time-millis":
+		return midnight.Add(time.Duration(n) * time.Millisecond).Format("15:04:05.999")
+	case "time-micros":
+		return midnight.Add(time.Duration(n) * time.Microsecond).Format("15:04:05.999999")
+	case "timestamp-millis":
+		return time.Unix(n/1e3, n%1e3*1e6).UTC().Format(time.RFC3339Nano)
+	case "timestamp-micros":
+		return time.Unix(n/1e6, n%1e6*1e3).UTC().Format(time.RFC3339Nano)
+	case "timestamp-nanos":
+		return time.Unix(0, n).UTC().Format(time.RFC3339Nano)
+	case "local-timestamp-millis":
+		return time.Unix(n/1e3, n%1e3*1e6).UTC().Format("2006-01-02T15:04:05.999999999")
+	case "local-timestamp-micros":
+		return time.Unix(n/1e6, n%1e6*1e3).UTC().Format("2006-01-02T15:04:05.999999999")
+	}
+	return json.Number(strconv.FormatInt(n, 10))
+}
+
+// avroBytes returns bytes or fixed as base64, or as a number
+// for decimals, which are big-endian two's complement integers
+// with the number of decimal places given by the scale
+func avroBytes(b []byte, s *avroSchema) interface{} {
+	if s.logical != "decimal" || len(b) == 0 {
+		return base64.StdEncoding.EncodeToString(b)
+	}
+
+	n := new(big.Int).SetBytes(b)
+	if b[0]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+	}
+	sign := ""
+	if n.Sign() < 0 {
+		sign = "-"
+		n.Neg(n)
+	}
+	digits := n.String()
+	if s.scale <= 0 {
+		return json.Number(sign + digits)
+	}
+	if len(digits) <= s.scale {
+		digits = strings.Repeat("0", s.scale-len(digits)+1) + digits
+	}
+	point := len(digits) - s.scale
+	return json.Number(sign + digits[:point] + "." + digits[point:])
+}
+
+// avroFloat returns a float of the provided bit size as a json.Number
+func avroFloat(f float64, bitSize int) (interface{}, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, fmt.Errorf("Avro number %v can't be represented in JSON", f)
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, bitSize)), nil
+}
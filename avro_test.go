@@ -0,0 +1,175 @@
+package gron
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+// avroLong returns an Avro int or long
+func avroLong(n int64) string {
+	b := make([]byte, binary.MaxVarintLen64)
+	return string(b[:binary.PutVarint(b, n)])
+}
+
+// avroString returns Avro bytes or a string
+func avroString(s string) string {
+	return avroLong(int64(len(s))) + s
+}
+
+// avroDouble returns an Avro double
+func avroDouble(f float64) string {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, math.Float64bits(f))
+	return string(b)
+}
+
+const avroSync = "0123456789abcdef"
+
+// avroFile returns an object container file with the provided schema
+// and codec, and a block for each of the provided lists of records
+func avroFile(schema, codec string, blocks ...[]string) string {
+	out := avroMagic + avroLong(2) +
+		avroString("avro.schema") + avroString(schema) +
+		avroString("avro.codec") + avroString(codec) +
+		avroLong(0) + avroSync
+
+	for _, records := range blocks {
+		data := strings.Join(records, "")
+		if codec == "deflate" {
+			buf := &bytes.Buffer{}
+			w, _ := flate.NewWriter(buf, flate.DefaultCompression)
+			w.Write([]byte(data))
+			w.Close()
+			data = buf.String()
+		}
+		out += avroLong(int64(len(records))) + avroString(data) + avroSync
+	}
+	return out
+}
+
+func TestGronAvro(t *testing.T) {
+	users := `{"type": "record", "name": "User", "namespace": "com.example", "fields": [
+		{"name": "name", "type": "string"},
+		{"name": "age", "type": "int"},
+		{"name": "email", "type": ["null", "string"]},
+		{"name": "role", "type": {"type": "enum", "name": "Role", "symbols": ["ADMIN", "USER"]}},
+		{"name": "tags", "type": {"type": "array", "items": "string"}},
+		{"name": "attrs", "type": {"type": "map", "values": "long"}},
+		{"name": "born", "type": {"type": "int", "logicalType": "date"}},
+		{"name": "seen", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+		{"name": "balance", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}},
+		{"name": "score", "type": "double"},
+		{"name": "boss", "type": ["null", "com.example.User"]}
+	]}`
+
+	ann := avroString("Ann") + avroLong(50) +
+		avroLong(1) + avroString("ann@example.com") +
+		avroLong(0) +
+		avroLong(0) +
+		avroLong(0) +
+		avroLong(0) + avroLong(0) +
+		avroString("\x05") +
+		avroDouble(0) +
+		avroLong(0)
+	tom := avroString("Tom") + avroLong(30) +
+		avroLong(0) +
+		avroLong(1) +
+		avroLong(2) + avroString("a") + avroString("b") + avroLong(0) +
+		avroLong(-1) + avroLong(2) + avroString("x") + avroLong(5) + avroLong(0) +
+		avroLong(1) +
+		avroLong(1595764800000) +
+		avroString("\xfb\x2e") +
+		avroDouble(1.5) +
+		avroLong(1) + ann
+
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{
+			avroFile(users, "null", []string{tom}),
+			`json = [];
+json[0] = {};
+json[0].age = 30;
+json[0].attrs = {};
+json[0].attrs.x = 5;
+json[0].balance = -12.34;
+json[0].born = "1970-01-02";
+json[0].boss = {};
+json[0].boss.age = 50;
+json[0].boss.attrs = {};
+json[0].boss.balance = 0.05;
+json[0].boss.born = "1970-01-01";
+json[0].boss.boss = null;
+json[0].boss.email = "ann@example.com";
+json[0].boss.name = "Ann";
+json[0].boss.role = "ADMIN";
+json[0].boss.score = 0;
+json[0].boss.seen = "1970-01-01T00:00:00Z";
+json[0].boss.tags = [];
+json[0].email = null;
+json[0].name = "Tom";
+json[0].role = "USER";
+json[0].score = 1.5;
+json[0].seen = "2020-07-26T12:00:00Z";
+json[0].tags = [];
+json[0].tags[0] = "a";
+json[0].tags[1] = "b";
+`,
+		},
+		{
+			// Records in several compressed blocks
+			avroFile(`"long"`, "deflate", []string{avroLong(1), avroLong(-2)}, []string{avroLong(3)}),
+			`json = [];
+json[0] = 1;
+json[1] = -2;
+json[2] = 3;
+`,
+		},
+		{
+			avroFile(`"string"`, "null"),
+			`json = [];
+`,
+		},
+	}
+
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		code, err := Gron(strings.NewReader(c.in), out, OptMonochrome|OptAvro)
+		if code != ExitOK || err != nil {
+			t.Errorf("want ExitOK and nil error for %q; have %d and %v", c.in, code, err)
+			continue
+		}
+		if out.String() != c.want {
+			t.Logf("want: %s", c.want)
+			t.Logf("have: %s", out.String())
+			t.Errorf("output for %q does not match", c.in)
+		}
+	}
+}
+
+func TestGronAvroInvalid(t *testing.T) {
+	self := `{"type": "record", "name": "R", "fields": [{"name": "r", "type": "R"}]}`
+	cases := []string{
+		`{"not": "avro"}`,
+		avroFile(`"long"`, "snappy", []string{avroLong(1)}),
+		avroFile(`"nope"`, "null", []string{avroLong(1)}),
+		avroFile(`["null", "long"]`, "null", []string{avroLong(2)}),
+		avroFile(`"string"`, "null", []string{avroLong(10) + "abc"}),
+		avroFile(`"long"`, "null", []string{avroLong(1)}) + avroLong(1) + avroString(avroLong(1)) + "wrong sync marker",
+		avroFile(`{"type": "array", "items": "null"}`, "null", []string{avroLong(math.MaxInt64)}),
+		avroFile(self, "null", []string{"x"}),
+		avroFile(`"double"`, "null", []string{avroDouble(math.Inf(1))}),
+	}
+
+	for _, c := range cases {
+		code, err := Gron(strings.NewReader(c), &bytes.Buffer{}, OptMonochrome|OptAvro)
+		if code != ExitFormStatements || err == nil {
+			t.Errorf("want ExitFormStatements and an error for %q; have %d and %v", c, code, err)
+		}
+	}
+}
@@ -0,0 +1,483 @@
+package gron
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statementsFromBSON takes an io.Reader containing BSON and returns
+// statements or an error on failure. A single document is treated like
+// a JSON document; several, one after the other as mongodump writes
+// them, are treated as elements of a top-level array, as GronStream
+// treats lines of JSON
+func statementsFromBSON(r io.Reader, prefix statement, c *config) (statements, error) {
+	br := bufio.NewReader(r)
+	var docs []interface{}
+	for {
+		// The end of the input is only fine between documents
+		if _, err := br.Peek(1); err == io.EOF {
+			break
+		}
+		doc, err := readBSONDocument(br)
+		if err != nil {
+			return nil, fmt.Errorf("BSON document %d: %s", len(docs), err)
+		}
+		docs = append(docs, doc)
+	}
+
+	ss := make(statements, 0, 32)
+	switch len(docs) {
+	case 0:
+		return nil, fmt.Errorf("no BSON documents in input")
+	case 1:
+		ss.fill(prefix, docs[0], 0, c)
+	default:
+		ss.addWithValue(prefix, token{"[]", typEmptyArray})
+		for i, doc := range docs {
+			ss.fill(prefix.withNumericKey(i), doc, 0, c)
+		}
+	}
+	return ss, nil
+}
+
+// readBSONDocument reads one whole document, which starts with its
+// length, and decodes it
+func readBSONDocument(r io.Reader) (map[string]interface{}, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	n := int64(int32(binary.LittleEndian.Uint32(size[:])))
+	if n < 5 {
+		return nil, fmt.Errorf("invalid length %d", n)
+	}
+
+	// Reading no more than is there keeps a huge length
+	// in a short input from allocating much
+	rest, err := ioutil.ReadAll(io.LimitReader(r, n-4))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(rest)) < n-4 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	d := &bsonDecoder{b: append(size[:], rest...)}
+	doc, err := d.document()
+	if err != nil {
+		return nil, err
+	}
+	return doc.(map[string]interface{}), nil
+}
+
+// A bsonDecoder decodes BSON into the types used for values decoded
+// from JSON. Doubles, 32-bit and 64-bit integers are numbers; other
+// types that JSON doesn't have are written as MongoDB's relaxed
+// Extended JSON writes them, so that they can be told apart from
+// strings and objects, and so that ungronning the output gives JSON
+// that mongoimport understands; e.g. {"$oid": "5f1d..."} for an
+// ObjectId, {"$date": "2020-07-26T12:00:00Z"} for a date, and
+// {"$binary": {"base64": "AQI=", "subType": "00"}} for binary data
+type bsonDecoder struct {
+	b     []byte
+	pos   int
+	depth int
+}
+
+// maxBSONDepth limits how deeply documents can be nested, so a document
+// can't make the decoder recurse until it runs out of stack
+const maxBSONDepth = 10000
+
+// errBSONTooDeep is returned for documents nested more than maxBSONDepth
+var errBSONTooDeep = fmt.Errorf("documents are nested more than %d deep", maxBSONDepth)
+
+// next returns the next n bytes
+func (d *bsonDecoder) next(n int) ([]byte, error) {
+	if n < 0 || n > len(d.b)-d.pos {
+		return nil, fmt.Errorf("value at offset %d runs past the end of the document", d.pos)
+	}
+	b := d.b[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// int32 returns the next little-endian 32-bit integer
+func (d *bsonDecoder) int32() (int32, error) {
+	b, err := d.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.LittleEndian.Uint32(b)), nil
+}
+
+// uint64 returns the next little-endian 64-bit integer
+func (d *bsonDecoder) uint64() (uint64, error) {
+	b, err := d.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+// cstring returns the next NUL-terminated string
+func (d *bsonDecoder) cstring() (string, error) {
+	for i := d.pos; i < len(d.b); i++ {
+		if d.b[i] == 0 {
+			s := string(d.b[d.pos:i])
+			d.pos = i + 1
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("string at offset %d has no end", d.pos)
+}
+
+// string returns the next string, which starts with
+// its length and ends with a NUL that's included in it
+func (d *bsonDecoder) string() (string, error) {
+	n, err := d.int32()
+	if err != nil {
+		return "", err
+	}
+	if n < 1 {
+		return "", fmt.Errorf("invalid string length %d at offset %d", n, d.pos-4)
+	}
+	b, err := d.next(int(n))
+	if err != nil {
+		return "", err
+	}
+	if b[n-1] != 0 {
+		return "", fmt.Errorf("string at offset %d has no end", d.pos-int(n))
+	}
+	return string(b[:n-1]), nil
+}
+
+// objectID returns the next ObjectId as hex
+func (d *bsonDecoder) objectID() (string, error) {
+	b, err := d.next(12)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// document returns the next document as an object
+func (d *bsonDecoder) document() (interface{}, error) {
+	out := make(map[string]interface{})
+	err := d.elements(func(k string, v interface{}) {
+		out[k] = v
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// array returns the next array, which is a document with the
+// keys "0", "1" and so on, in order, as an array
+func (d *bsonDecoder) array() (interface{}, error) {
+	out := make([]interface{}, 0)
+	err := d.elements(func(k string, v interface{}) {
+		out = append(out, v)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// elements reads a document, passing each of its keys
+// and values to the provided function in turn
+func (d *bsonDecoder) elements(fn func(string, interface{})) error {
+	d.depth++
+	defer func() { d.depth-- }()
+	if d.depth > maxBSONDepth {
+		return errBSONTooDeep
+	}
+
+	start := d.pos
+	n, err := d.int32()
+	if err != nil {
+		return err
+	}
+	if n < 5 || int(n) > len(d.b)-start {
+		return fmt.Errorf("invalid document length %d at offset %d", n, start)
+	}
+	end := start + int(n) - 1
+
+	for d.pos < end {
+		typ := d.b[d.pos]
+		d.pos++
+		k, err := d.cstring()
+		if err != nil {
+			return err
+		}
+		v, err := d.value(typ)
+		if err == errBSONTooDeep {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %s", quoteString(k), err)
+		}
+		fn(k, v)
+	}
+	if d.pos != end || d.b[end] != 0 {
+		return fmt.Errorf("document at offset %d doesn't end where its length says", start)
+	}
+	d.pos++
+	return nil
+}
+
+// value returns the next value, of the provided type
+func (d *bsonDecoder) value(typ byte) (interface{}, error) {
+	switch typ {
+	case 0x01:
+		v, err := d.uint64()
+		if err != nil {
+			return nil, err
+		}
+		f := math.Float64frombits(v)
+		switch {
+		case math.IsNaN(f):
+			return map[string]interface{}{"$numberDouble": "NaN"}, nil
+		case math.IsInf(f, 1):
+			return map[string]interface{}{"$numberDouble": "Infinity"}, nil
+		case math.IsInf(f, -1):
+			return map[string]interface{}{"$numberDouble": "-Infinity"}, nil
+		}
+		return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), nil
+
+	case 0x02:
+		return d.string()
+	case 0x03:
+		return d.document()
+	case 0x04:
+		return d.array()
+
+	case 0x05:
+		n, err := d.int32()
+		if err != nil {
+			return nil, err
+		}
+		sub, err := d.next(1)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.next(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"$binary": map[string]interface{}{
+			"base64":  base64.StdEncoding.EncodeToString(b),
+			"subType": hex.EncodeToString(sub),
+		}}, nil
+
+	case 0x06:
+		return map[string]interface{}{"$undefined": true}, nil
+
+	case 0x07:
+		id, err := d.objectID()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"$oid": id}, nil
+
+	case 0x08:
+		b, err := d.next(1)
+		if err != nil {
+			return nil, err
+		}
+		switch b[0] {
+		case 0:
+			return false, nil
+		case 1:
+			return true, nil
+		}
+		return nil, fmt.Errorf("invalid boolean %d", b[0])
+
+	case 0x09:
+		v, err := d.uint64()
+		if err != nil {
+			return nil, err
+		}
+		return bsonDate(int64(v)), nil
+
+	case 0x0a:
+		return nil, nil
+
+	case 0x0b:
+		pattern, err := d.cstring()
+		if err != nil {
+			return nil, err
+		}
+		options, err := d.cstring()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"$regularExpression": map[string]interface{}{
+			"pattern": pattern,
+			"options": options,
+		}}, nil
+
+	case 0x0c:
+		ns, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		id, err := d.objectID()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"$dbPointer": map[string]interface{}{
+			"$ref": ns,
+			"$id":  map[string]interface{}{"$oid": id},
+		}}, nil
+
+	case 0x0d:
+		code, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"$code": code}, nil
+
+	case 0x0e:
+		symbol, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"$symbol": symbol}, nil
+
+	case 0x0f:
+		// The length of the whole thing comes first; the
+		// string and document have lengths of their own
+		if _, err := d.int32(); err != nil {
+			return nil, err
+		}
+		code, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		scope, err := d.document()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"$code": code, "$scope": scope}, nil
+
+	case 0x10:
+		v, err := d.int32()
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(strconv.Itoa(int(v))), nil
+
+	case 0x11:
+		v, err := d.uint64()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"$timestamp": map[string]interface{}{
+			"t": json.Number(strconv.FormatUint(v>>32, 10)),
+			"i": json.Number(strconv.FormatUint(v&0xffffffff, 10)),
+		}}, nil
+
+	case 0x12:
+		v, err := d.uint64()
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(strconv.FormatInt(int64(v), 10)), nil
+
+	case 0x13:
+		lo, err := d.uint64()
+		if err != nil {
+			return nil, err
+		}
+		hi, err := d.uint64()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"$numberDecimal": decimal128String(hi, lo)}, nil
+
+	case 0xff:
+		return map[string]interface{}{"$minKey": json.Number("1")}, nil
+	case 0x7f:
+		return map[string]interface{}{"$maxKey": json.Number("1")}, nil
+	}
+
+	return nil, fmt.Errorf("invalid BSON type 0x%02x", typ)
+}
+
+// bsonDate returns a date, in milliseconds since the epoch, as an ISO
+// 8601 string for years that have four digits; otherwise as the number
+// of milliseconds, as relaxed Extended JSON writes them
+func bsonDate(ms int64) interface{} {
+	t := time.Unix(ms/1000, ms%1000*int64(time.Millisecond)).UTC()
+	if t.Year() < 1970 || t.Year() > 9999 {
+		return map[string]interface{}{"$date": map[string]interface{}{
+			"$numberLong": strconv.FormatInt(ms, 10),
+		}}
+	}
+	return map[string]interface{}{"$date": t.Format("2006-01-02T15:04:05.999Z07:00")}
+}
+
+// decimal128String returns a Decimal128, as its high and low 64 bits,
+// as a string in the form that the BSON spec gives for it
+func decimal128String(hi, lo uint64) string {
+	sign := ""
+	if hi>>63 == 1 {
+		sign = "-"
+	}
+
+	var exp int
+	coef := new(big.Int)
+	if hi>>61&3 == 3 {
+		switch hi >> 58 & 0x1f {
+		case 0x1e:
+			return sign + "Infinity"
+		case 0x1f:
+			return "NaN"
+		}
+		// Coefficients this big are more than 34 digits, which
+		// isn't allowed; so they're taken as zero
+		exp = int(hi>>47&0x3fff) - 6176
+	} else {
+		exp = int(hi>>49&0x3fff) - 6176
+		coef.SetUint64(hi & (1<<49 - 1))
+		coef.Lsh(coef, 64)
+		coef.Or(coef, new(big.Int).SetUint64(lo))
+	}
+
+	digits := coef.String()
+	adjusted := exp + len(digits) - 1
+	if exp > 0 || adjusted < -6 {
+		// Scientific notation
+		s := digits[:1]
+		if len(digits) > 1 {
+			s += "." + digits[1:]
+		}
+		if adjusted >= 0 {
+			return fmt.Sprintf("%s%sE+%d", sign, s, adjusted)
+		}
+		return fmt.Sprintf("%s%sE%d", sign, s, adjusted)
+	}
+
+	if exp == 0 {
+		return sign + digits
+	}
+	// Plain notation with a decimal point
+	point := len(digits) + exp
+	if point <= 0 {
+		return sign + "0." + strings.Repeat("0", -point) + digits
+	}
+	return sign + digits[:point] + "." + digits[point:]
+}
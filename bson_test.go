@@ -0,0 +1,170 @@
+package gron
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// bsonDoc returns a BSON document made of the provided elements,
+// each of which is a type byte, a NUL-terminated key and a value
+func bsonDoc(elems ...string) string {
+	body := strings.Join(elems, "") + "\x00"
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, uint32(len(body)+4))
+	return string(size) + body
+}
+
+// bsonStr returns a BSON string value
+func bsonStr(s string) string {
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, uint32(len(s)+1))
+	return string(size) + s + "\x00"
+}
+
+func TestGronBSON(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{
+			bsonDoc(
+				"\x07_id\x00\x5f\x1d\x7a\x2b\x3c\x4d\x5e\x6f\x70\x81\x92\x03",
+				"\x02name\x00"+bsonStr("Tom"),
+				"\x10age\x00\x1e\x00\x00\x00",
+				"\x12big\x00\x00\x00\x00\x00\x00\x01\x00\x00",
+				"\x01score\x00\x00\x00\x00\x00\x00\x00\xf8\x3f",
+				"\x09at\x00\x00\x7a\xfe\x8a\x73\x01\x00\x00",
+				"\x04tags\x00"+bsonDoc("\x020\x00"+bsonStr("a"), "\x081\x00\x01", "\x0a2\x00"),
+				"\x05bin\x00\x02\x00\x00\x00\x00\x01\x02",
+				"\x13dec\x00\x0f\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x3e\x30",
+			),
+			`json = {};
+json._id = {};
+json._id.$oid = "5f1d7a2b3c4d5e6f70819203";
+json.age = 30;
+json.at = {};
+json.at.$date = "2020-07-26T12:00:00Z";
+json.big = 1099511627776;
+json.bin = {};
+json.bin.$binary = {};
+json.bin.$binary.base64 = "AQI=";
+json.bin.$binary.subType = "00";
+json.dec = {};
+json.dec.$numberDecimal = "1.5";
+json.name = "Tom";
+json.score = 1.5;
+json.tags = [];
+json.tags[0] = "a";
+json.tags[1] = true;
+json.tags[2] = null;
+`,
+		},
+		{
+			// Several documents one after the other, like mongodump writes
+			bsonDoc("\x10n\x00\x01\x00\x00\x00") + bsonDoc("\x10n\x00\x02\x00\x00\x00"),
+			`json = [];
+json[0] = {};
+json[0].n = 1;
+json[1] = {};
+json[1].n = 2;
+`,
+		},
+		{
+			bsonDoc(
+				"\x01nan\x00\x01\x00\x00\x00\x00\x00\xf8\x7f",
+				"\x09old\x00\x18\xfc\xff\xff\xff\xff\xff\xff",
+				"\x0bre\x00^a\x00i\x00",
+				"\x11ts\x00\x02\x00\x00\x00\x01\x00\x00\x00",
+				"\xffmin\x00",
+			),
+			`json = {};
+json.min = {};
+json.min.$minKey = 1;
+json.nan = {};
+json.nan.$numberDouble = "NaN";
+json.old = {};
+json.old.$date = {};
+json.old.$date.$numberLong = "-1000";
+json.re = {};
+json.re.$regularExpression = {};
+json.re.$regularExpression.options = "i";
+json.re.$regularExpression.pattern = "^a";
+json.ts = {};
+json.ts.$timestamp = {};
+json.ts.$timestamp.i = 2;
+json.ts.$timestamp.t = 1;
+`,
+		},
+	}
+
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		code, err := Gron(strings.NewReader(c.in), out, OptMonochrome|OptBSON)
+		if code != ExitOK || err != nil {
+			t.Errorf("want ExitOK and nil error for %q; have %d and %v", c.in, code, err)
+			continue
+		}
+		if out.String() != c.want {
+			t.Logf("want: %s", c.want)
+			t.Logf("have: %s", out.String())
+			t.Errorf("output for %q does not match", c.in)
+		}
+	}
+}
+
+func TestGronBSONInvalid(t *testing.T) {
+	// Each document holding the next one is 8 bytes bigger than it;
+	// building them by wrapping would copy the lot on every level
+	const levels = 20000
+	var deep bytes.Buffer
+	for i := levels; i > 0; i-- {
+		binary.Write(&deep, binary.LittleEndian, uint32(5+8*i))
+		deep.WriteString("\x03d\x00")
+	}
+	deep.WriteString(bsonDoc() + strings.Repeat("\x00", levels))
+
+	cases := []string{
+		"\x05\x00\x00",
+		"\xff\xff\xff\x7f\x00",
+		"\x04\x00\x00\x00",
+		bsonDoc("\x10n\x00\x01\x00"),
+		bsonDoc("\x02s\x00\x09\x00\x00\x00abc\x00"),
+		bsonDoc("\x20n\x00"),
+		bsonDoc("\x08b\x00\x02"),
+		bsonDoc("\x10n\x00\x01\x00\x00\x00") + "\x05",
+		deep.String(),
+	}
+
+	for _, c := range cases {
+		code, err := Gron(strings.NewReader(c), &bytes.Buffer{}, OptMonochrome|OptBSON)
+		if code != ExitFormStatements || err == nil {
+			t.Errorf("want ExitFormStatements and an error for %q; have %d and %v", c, code, err)
+		}
+	}
+}
+
+func TestDecimal128String(t *testing.T) {
+	cases := []struct {
+		hi, lo uint64
+		want   string
+	}{
+		{0x3040000000000000, 0, "0"},
+		{0x3040000000000000, 12345, "12345"},
+		{0xb040000000000000, 1, "-1"},
+		{0x303e000000000000, 15, "1.5"},
+		{0x3034000000000000, 1, "0.000001"},
+		{0x3022000000000000, 1, "1E-15"},
+		{0x3042000000000000, 1, "1E+1"},
+		{0x7800000000000000, 0, "Infinity"},
+		{0xf800000000000000, 0, "-Infinity"},
+		{0x7c00000000000000, 0, "NaN"},
+	}
+
+	for _, c := range cases {
+		if have := decimal128String(c.hi, c.lo); have != c.want {
+			t.Errorf("want %s for %x %x; have %s", c.want, c.hi, c.lo, have)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package gron
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+)
+
+// Hash is like the gron action with OptCanonical, but rather than the
+// statements it writes the SHA-256 hash of them in hex, followed by a
+// newline; so that documents that are equal as JSON have the same hash.
+//
+// The canonical form is the output of OptDeterministic, with any options
+// that change how statements are written (OptJSON, OptEvents, OptEnv,
+// OptPreorder, OptSortByValue, OptInlineScalarArrays, WithFloatFormat and
+// WithTruncate) ignored. That means:
+//
+//   - one statement per line, ending in a single '\n' and with exactly one
+//     space either side of the '=', and no other whitespace
+//   - keys written as bare words where they're valid identifiers, and
+//     quoted otherwise
+//   - statements sorted by path as for OptDeterministic: bare keys before
+//     quoted keys, each ordered by their text, and array indexes in numeric
+//     order; so the order of keys in the input doesn't matter, but the order
+//     of array elements does (unless OptArrayAsSet is used)
+//   - strings written with the same escaping whatever escaping the input
+//     used; e.g. "\u00e9" and "é" are both written as "é"
+//   - numbers normalized with the same rules as OptDeterministic; integers
+//     as they are, apart from -0 which becomes 0, and anything else in the
+//     shortest form that parses back to the same float64 (e.g. 1.0, 1e0
+//     and 1 are all written as 1)
+//
+// Options that change which statements are written, like WithGlob, are
+// still applied; so a hash can be taken of part of a document.
+func Hash(r io.Reader, w io.Writer, opts int, options ...Option) (int, error) {
+	h := sha256.New()
+	code, err := Gron(r, h, opts|OptCanonical, options...)
+	if err != nil {
+		return code, err
+	}
+	fmt.Fprintf(w, "%x\n", h.Sum(nil))
+	return ExitOK, nil
+}
@@ -0,0 +1,74 @@
+package gron
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGronCanonical(t *testing.T) {
+	in := `{"b": [1, 2.0, -0], "a": {"y": 1e2, "x": "café", "a b": true}}`
+	want := `json = {};
+json.a = {};
+json.a.x = "café";
+json.a.y = 100;
+json.a["a b"] = true;
+json.b = [];
+json.b[0] = 1;
+json.b[1] = 2;
+json.b[2] = 0;
+`
+
+	// Options that only change how statements are written are ignored
+	out := &bytes.Buffer{}
+	code, err := Gron(
+		strings.NewReader(in), out,
+		OptCanonical|OptJSON|OptPreorder|OptInlineScalarArrays,
+		WithFloatFormat("%.2f"),
+	)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	if out.String() != want {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", out.String())
+		t.Errorf("canonical output does not match")
+	}
+}
+
+func TestHash(t *testing.T) {
+	hash := func(in string) string {
+		out := &bytes.Buffer{}
+		code, err := Hash(strings.NewReader(in), out, 0)
+		if code != ExitOK || err != nil {
+			t.Fatalf("want ExitOK and nil error for %s; have %d and %v", in, code, err)
+		}
+		return out.String()
+	}
+
+	same := []string{
+		`{"id": 1, "name": "Tom", "tags": ["a", "b"], "score": 1.5}`,
+		`{"tags":["a","b"],"score":15e-1,"name":"Tom","id":1.0}`,
+		"{\n  \"score\": 1.50,\n  \"tags\": [\"a\", \"b\"],\n  \"id\": 1,\n  \"name\": \"Tom\"\n}",
+	}
+	want := hash(same[0])
+	if len(want) != 65 || !strings.HasSuffix(want, "\n") {
+		t.Errorf("want a hex SHA-256 hash and a newline; have %q", want)
+	}
+	for _, in := range same[1:] {
+		if have := hash(in); have != want {
+			t.Errorf("want %s to hash to %q; have %q", in, want, have)
+		}
+	}
+
+	different := []string{
+		`{"id": 1, "name": "Tom", "tags": ["b", "a"], "score": 1.5}`,
+		`{"id": "1", "name": "Tom", "tags": ["a", "b"], "score": 1.5}`,
+		`{"id": 1, "name": "Tom", "tags": ["a", "b"], "score": 1.5, "x": null}`,
+	}
+	for _, in := range different {
+		if have := hash(in); have == want {
+			t.Errorf("want %s to hash differently to %s", in, same[0])
+		}
+	}
+}
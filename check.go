@@ -0,0 +1,55 @@
+package gron
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// Check is an action that validates its input as JSON without
+// producing any output. If the input is invalid, the error says
+// where the problem is with a line and column number. Empty input
+// is ErrEmptyInput, as it is for the other actions.
+func Check(r io.Reader, w io.Writer, opts int, options ...Option) (code int, err error) {
+	c := newConfig(options)
+	r = c.limitInput(r)
+	defer c.checkInputSize(&code, &err)
+	if r, code, err = checkEmpty(r); err != nil {
+		return code, err
+	}
+	in, err := ioutil.ReadAll(r)
+	if err != nil {
+		return ExitReadInput, fmt.Errorf("failed to read input: %s", err)
+	}
+
+	_, err = decodeJSON(bytes.NewReader(in), opts&OptLenient > 0, nil)
+	if err == nil {
+		return ExitOK, nil
+	}
+
+	// A SyntaxError's offset is the number of bytes read
+	// before the error, so it includes the offending byte
+	offset := int64(len(in))
+	if serr, ok := err.(*json.SyntaxError); ok && serr.Offset > 0 {
+		offset = serr.Offset - 1
+	}
+	if terr, ok := err.(trailingDataError); ok {
+		offset = terr.offset
+	}
+	line, col := position(in, offset)
+	return ExitFormStatements, fmt.Errorf("invalid JSON at line %d, column %d: %s", line, col, err)
+}
+
+// position converts a byte offset into the input into a 1-based
+// line and column number. The column is counted in bytes.
+func position(in []byte, offset int64) (int, int) {
+	if offset > int64(len(in)) {
+		offset = int64(len(in))
+	}
+	before := in[:offset]
+	line := bytes.Count(before, []byte{'\n'}) + 1
+	col := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
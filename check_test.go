@@ -0,0 +1,44 @@
+package gron
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	cases := []struct {
+		in      string
+		code    int
+		wantErr string
+	}{
+		{`{"a": [1, 2]}`, ExitOK, ""},
+		{"{\n  \"a\": 1,\n  \"b\" 2\n}", ExitFormStatements, "line 3, column 7"},
+		{"[1, 2", ExitFormStatements, "line 1, column 6"},
+		{"", ExitEmptyInput, ErrEmptyInput.Error()},
+		{" \n\t", ExitEmptyInput, ErrEmptyInput.Error()},
+		{"{\"a\": 1}\n  garbage", ExitFormStatements, "line 2, column 3"},
+		{"{\"a\": 1}\n\n", ExitOK, ""},
+	}
+
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		code, err := Check(strings.NewReader(c.in), out, OptMonochrome)
+
+		if code != c.code {
+			t.Errorf("want exit code %d for %q; have %d", c.code, c.in, code)
+		}
+		if out.Len() != 0 {
+			t.Errorf("want no output for %q; have %q", c.in, out.String())
+		}
+		if c.wantErr == "" {
+			if err != nil {
+				t.Errorf("want nil error for %q; have %s", c.in, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+			t.Errorf("want error containing %q for %q; have %v", c.wantErr, c.in, err)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+)
+
+// appendFile collects everything written to it and appends it to a file
+// with a single write when it's closed; so that, on local filesystems at
+// least, the output of concurrent runs isn't interleaved. A run that
+// fails still closes it, so the output it got to isn't lost.
+type appendFile struct {
+	f   *os.File
+	buf bytes.Buffer
+}
+
+// openAppend opens a file for appending, creating it if needed
+func openAppend(name string) (*appendFile, error) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+	return &appendFile{f: f}, nil
+}
+
+// marker returns the line that starts each run's output, like
+// '-- gron 2006-01-02T15:04:05Z', which ungron ignores; preceded
+// by a blank line if there's output from earlier runs in the file
+func (a *appendFile) marker() string {
+	m := fmt.Sprintf("-- gron %s\n", time.Now().UTC().Format(time.RFC3339))
+	if fi, err := a.f.Stat(); err == nil && fi.Size() > 0 {
+		m = "\n" + m
+	}
+	return m
+}
+
+func (a *appendFile) Write(p []byte) (int, error) {
+	return a.buf.Write(p)
+}
+
+func (a *appendFile) Close() error {
+	_, err := a.f.Write(a.buf.Bytes())
+	if cerr := a.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
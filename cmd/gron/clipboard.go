@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// The clipboard is accessed using whichever of the platform's
+// clipboard tools is installed, so that gron doesn't need any
+// extra dependencies, and headless systems are unaffected.
+// pasteCommands and copyCommands are defined per-platform.
+
+// readClipboard returns the contents of the clipboard
+func readClipboard() (io.Reader, error) {
+	args, err := findCommand(pasteCommands)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := exec.Command(args[0], args[1:]...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clipboard with %s: %s", args[0], err)
+	}
+	return bytes.NewReader(out), nil
+}
+
+// writeClipboard replaces the contents of the clipboard
+func writeClipboard(r io.Reader) error {
+	args, err := findCommand(copyCommands)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = r
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to write clipboard with %s: %s", args[0], err)
+	}
+	return nil
+}
+
+// findCommand returns the first of the commands that is installed
+func findCommand(commands [][]string) ([]string, error) {
+	names := make([]string, 0, len(commands))
+	for _, args := range commands {
+		if _, err := exec.LookPath(args[0]); err == nil {
+			return args, nil
+		}
+		names = append(names, args[0])
+	}
+	return nil, fmt.Errorf("no clipboard tool found; install one of: %s", strings.Join(names, ", "))
+}
@@ -0,0 +1,9 @@
+package main
+
+var pasteCommands = [][]string{
+	{"pbpaste"},
+}
+
+var copyCommands = [][]string{
+	{"pbcopy"},
+}
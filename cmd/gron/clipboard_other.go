@@ -0,0 +1,16 @@
+//go:build !darwin && !windows
+// +build !darwin,!windows
+
+package main
+
+var pasteCommands = [][]string{
+	{"wl-paste", "--no-newline"},
+	{"xclip", "-selection", "clipboard", "-out"},
+	{"xsel", "--clipboard", "--output"},
+}
+
+var copyCommands = [][]string{
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard", "-in"},
+	{"xsel", "--clipboard", "--input"},
+}
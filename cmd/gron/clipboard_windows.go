@@ -0,0 +1,9 @@
+package main
+
+var pasteCommands = [][]string{
+	{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard -Raw"},
+}
+
+var copyCommands = [][]string{
+	{"clip.exe"},
+}
@@ -0,0 +1,23 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// checkReadable returns an error if the file descriptor n
+// is open for writing only, so can't be read from
+func checkReadable(n int) error {
+	flags, err := unix.FcntlInt(uintptr(n), unix.F_GETFL, 0)
+	if err != nil {
+		return err
+	}
+	if flags&unix.O_ACCMODE == unix.O_WRONLY {
+		return fmt.Errorf("it's open for writing only")
+	}
+	return nil
+}
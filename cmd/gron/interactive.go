@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"gron"
+
+	"github.com/mattn/go-isatty"
+)
+
+// The interactive mode hands the statements to fzf, if it's
+// installed, rather than gron having a TUI of its own; so that
+// gron doesn't need any extra dependencies
+
+// fzfArgs makes fzf write every statement that matches the
+// query when enter is pressed, rather than just the current one
+var fzfArgs = []string{"--multi", "--no-sort", "--prompt", "gron> ", "--bind", "enter:select-all+accept"}
+
+// interactive wraps an action so that the statements it writes can be
+// filtered with fzf, with the statements that match when fzf exits
+// being written to the output
+func interactive(a gron.ActionFn) gron.ActionFn {
+	return func(r io.Reader, w io.Writer, opts int, options ...gron.Option) (int, error) {
+		if !isatty.IsTerminal(os.Stderr.Fd()) && !isatty.IsCygwinTerminal(os.Stderr.Fd()) {
+			return gron.ExitUsage, errors.New("--interactive needs a terminal")
+		}
+		fzf, err := exec.LookPath("fzf")
+		if err != nil {
+			return gron.ExitUsage, errors.New("--interactive needs fzf to be installed; see https://github.com/junegunn/fzf")
+		}
+
+		statements := &bytes.Buffer{}
+		code, err := a(r, statements, opts|gron.OptMonochrome, options...)
+		if code != gron.ExitOK {
+			return code, err
+		}
+
+		cmd := exec.Command(fzf, fzfArgs...)
+		cmd.Stdin = statements
+		cmd.Stdout = w
+		cmd.Stderr = os.Stderr
+		err = cmd.Run()
+
+		// fzf exits with 1 when nothing matched and 130
+		// when it's cancelled, neither of which is an error
+		if exit, ok := err.(*exec.ExitError); ok {
+			if ws, ok := exit.Sys().(syscall.WaitStatus); ok {
+				if s := ws.ExitStatus(); s == 1 || s == 130 {
+					return gron.ExitOK, nil
+				}
+			}
+		}
+		if err != nil {
+			return gron.ExitFormStatements, err
+		}
+		return gron.ExitOK, nil
+	}
+}
@@ -1,10 +1,20 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"gron"
 
@@ -21,18 +31,104 @@ func init() {
 		h := "Transform JSON (from a file, URL, or stdin) into discrete assignments to make it greppable\n\n"
 
 		h += "Usage:\n"
-		h += "  gron [OPTIONS] [FILE|URL|-]\n\n"
+		h += "  gron [OPTIONS] [FILE|URL|-]...\n\n"
 
 		h += "Options:\n"
 		h += "  -u, --ungron     Reverse the operation (turn assignments back into JSON)\n"
 		h += "  -c, --colorize   Colorize output (default on tty)\n"
 		h += "  -m, --monochrome Monochrome (don't colorize output)\n"
-		h += "  -s, --stream     Treat each line of input as a separate JSON object\n"
+		h += "  -s, --stream     Treat each line of input as a separate JSON object; or with --ungron, write each element of a top-level array as soon as it's complete\n"
+		h += "      --stream-key With --stream, key each line by the value of this field rather than its line number; no two lines can share a key\n"
+		h += "      --array-start With --stream, number the lines from this index rather than 0; e.g. to follow on from another run\n"
+		h += "      --skip-errors With --stream, warn about lines that aren't valid JSON and skip them rather than stopping\n"
+		h += "  -y, --yaml       Read YAML rather than JSON; several documents are treated as an array of them\n"
+		h += "      --toml       Read TOML rather than JSON\n"
+		h += "      --xml        Read XML rather than JSON; attributes are @name keys, and text alongside them #text\n"
+		h += "      --input      Read this format: json, yaml, toml, xml, msgpack, bson or avro (default from the FILE's extension, e.g. .yml; otherwise json)\n"
+		h += "      --check      Validate the input as JSON without any output\n"
+		h += "      --precision-check Only output numbers that would change if parsed as a float64, and what they'd become\n"
+		h += "      --base       Write a patch that turns this JSON file into the input, or with --ungron apply the input to it\n"
+		h += "      --diff       Write the statements that differ between this JSON file (-) and the input (+)\n"
+		h += "      --diff-context With --diff, also write this many unchanged statements around each change\n"
+		h += "      --schema     Infer a JSON Schema (draft-07) from the input and print that\n"
+		h += "      --clipboard  Read input from the clipboard, or with --ungron write output to it (desktop only)\n"
+		h += "      --fd         Read input from this open file descriptor rather than stdin; e.g. --fd 3 3<file.json\n"
+		h += "      --interactive Filter the statements interactively with fzf, writing those that match on exit\n"
 		h += "  -k, --insecure   Disable certificate validation\n"
+		h += "      --tls-servername Server name to use for TLS verification and SNI when fetching URLs\n"
+		h += "  -H, --header     Send a header when fetching a URL, as 'Key: Value' (repeatable)\n"
+		h += "      --timeout    Time limit for fetching a URL, e.g. 5s or 1m; 0 for no limit (default 20s)\n"
+		h += "      --retry      Retry fetching a URL this many times on connection errors and 5xx responses; failing on 4xx\n"
+		h += "      --retry-delay Time to wait before the first retry, doubling for each after that (default 1s)\n"
+		h += "  -o, --output     Write output to a file rather than stdout (gzipped if the name ends in .gz; monochrome unless -c)\n"
+		h += "      --append     With --output, append to the file rather than replacing it, starting with a '-- gron TIME' line\n"
+		h += "      --gzip       Gzip the output\n"
 		h += "  -j, --json       Represent gron data as JSON stream\n"
+		h += "  -V, --values     Only output the values of statements, one per line; e.g. for piping to sort | uniq -c\n"
+		h += "      --env        Write values as commands that set environment variables, e.g. export JSON_A_B='x' (can't be ungronned)\n"
+		h += "      --shell      With --env, write commands for posix, fish or powershell (default posix)\n"
+		h += "      --events     Write each statement as a line of JSON with a structured path\n"
+		h += "      --inline-scalar-arrays Write arrays of strings, numbers, bools and nulls on one line (alias --flatten-arrays)\n"
+		h += "      --array-as-set Order array elements by value, so reordered arrays compare equal (for diffing)\n"
+		h += "      --skip-nulls Leave out statements that assign null, as if the nulls weren't there\n"
+		h += "      --unwrap-strings Gron strings that contain JSON objects or arrays as part of the input (can't be undone by --ungron)\n"
+		h += "      --lenient    Ignore anything after the JSON value in the input rather than failing\n"
 		h += "      --no-sort    Don't sort output (faster)\n"
+		h += "      --keep-order Write keys in the order they're in the input rather than sorted\n"
+		h += "      --infer-types With --ungron, read 'key.path = value' lines and infer the type of values\n"
+		h += "      --sort-by-value Sort statements by their values rather than their paths, with numbers in numeric order\n"
+		h += "      --preorder   Sort parents before children with siblings ordered by key (alias --sort-keys-only)\n"
+		h += "      --float-format Format numbers with a float verb like %.2f (display only; can't be ungronned exactly)\n"
+		h += "      --truncate   Cut string values longer than this many characters short, with an ellipsis (display only; can't be ungronned)\n"
+		h += "      --deterministic Sorted, monochrome output with normalized numbers (for golden files)\n"
+		h += "      --canonical  Write the same output for any documents that are equal as JSON (implies --deterministic)\n"
+		h += "      --hash       Print a SHA-256 hash of the --canonical output rather than the output itself\n"
+		h += "      --keys-case  Convert object keys to snake, camel, lower or upper case (can't be undone by --ungron)\n"
+		h += "      --dup-keys   What to do about a key repeated in an object: last (default), warn or error\n"
+		h += "      --jsonpath   Write paths in JSONPath notation; e.g. $.users[0]['first name']\n"
+		h += "      --root       Start every statement with this name rather than 'json'\n"
+		h += "      --namespace  Insert dot-separated keys after the top-level 'json' (stripped by --ungron)\n"
+		h += "      --count      Print how many objects, arrays, strings, numbers, bools and nulls there are rather than the statements\n"
+		h += "      --count-by   Print a frequency table of a field's values across records\n"
+		h += "      --max-memory Refuse input estimated to need more memory than this, e.g. 512M, unless it's an array that can be streamed\n"
+		h += "      --max-input  Refuse input larger than this, e.g. 10M, rather than reading it all (for untrusted input)\n"
+		h += "      --max-line-size Maximum length of an input line in bytes for --stream and --ungron (default 1MB; alias --max-line-bytes)\n"
+		h += "      --max-array-index Refuse to ungron array indexes bigger than this (default 16777216)\n"
+		h += "      --depth      Only output statements this many levels deep, e.g. 2; deeper objects and arrays are written as {} or []\n"
+		h += "  -p, --prefix     Only output statements with paths starting with a path; e.g. 'json.users[3]'\n"
+		h += "      --glob       Only output statements with paths matching a glob; e.g. 'json.users[*].{name,email}' (repeatable)\n"
+		h += "  -i, --ignore-case Match --glob and --match patterns regardless of case\n"
+		h += "  -S, --smart-case Match --glob and --match patterns regardless of case unless they contain uppercase letters\n"
+		h += "      --glob-exclude Don't output statements with paths matching a glob (repeatable)\n"
+		h += "      --match      Only output statements with values matching a regex, as written; e.g. '^\"https?:' (keeps their containers)\n"
+		h += "      --invert-match With --match, only output statements with values that don't match\n"
+		h += "      --parents    With --glob, also output the containers that matching statements are in, so the output ungrons\n"
+		h += "      --select-index Only output these elements of a top-level array; e.g. 0,2,5\n"
+		h += "      --reindex    With --select-index, number the selected elements from zero\n"
+		h += "      --sample-rate Only output a random sample of statements; e.g. 0.01 for about 1% (lossy, can't be ungronned)\n"
+		h += "      --seed       Seed for --sample-rate, for a reproducible sample\n"
+		h += "      --highlight  Color values matching a regex, as PATTERN:COLOR; e.g. '^(error|fatal)$:red' (repeatable)\n"
+		h += "      --redact     Replace values with paths matching a regex with \"***\" (repeatable)\n"
+		h += "      --from-columns With --ungron, read path and value columns; tsv or csv\n"
+		h += "      --form       With --ungron, read a form or query string like a=1&b[0]=x&c[d]=y\n"
+		h += "      --strict     With --ungron, fail if two statements assign different values to the same path\n"
+		h += "      --split      With --ungron, treat blank lines as separators between documents, writing an array of them\n"
+		h += "      --split-keys With --ungron, write each top-level key's value as a separate document, after a '--- # KEY' line\n"
+		h += "      --split-on   With --ungron, treat lines matching a regex as separators between documents\n"
+		h += "      --indent     With --ungron or --schema, indent JSON with a number of spaces, 'tab' or a string of whitespace\n"
+		h += "  -C, --compact    With --ungron or --schema, write JSON on one line\n"
+		h += "      --to-ndjson  With --ungron, write each element of a top-level array (or with --split-keys, each key's value) as a line of JSON\n"
+		h += "      --merge      Gron all of the inputs together, as the elements of an array; e.g. json[1].name\n"
+		h += "      --named      Gron all of the inputs together, named after their files; e.g. json.users.name\n"
+		h += "      --keep-going Carry on with the remaining inputs when one of them fails\n"
+		h += "      --error-format Write errors as text (default) or json, e.g. {\"exitCode\":5,\"stage\":\"parse\",\"message\":\"...\",\"line\":2}\n"
+		h += "  -v, --verbose    Print a summary of statements, bytes read and time taken to stderr\n"
 		h += "      --version    Print version information\n\n"
 
+		h += "Environment:\n"
+		h += "  NO_COLOR         Don't colorize output unless --colorize is used, if it's set to anything\n"
+		h += "  GRON_COLORS      Colors for strings, numbers, bools, null, braces, keys and = ; as SGR codes; e.g. '0;32:0;31::1;30'\n\n"
+
 		h += "Exit Codes:\n"
 		h += fmt.Sprintf("  %d\t%s\n", gron.ExitOK, "OK")
 		h += fmt.Sprintf("  %d\t%s\n", gron.ExitOpenFile, "Failed to open file")
@@ -41,6 +137,11 @@ func init() {
 		h += fmt.Sprintf("  %d\t%s\n", gron.ExitFetchURL, "Failed to fetch URL")
 		h += fmt.Sprintf("  %d\t%s\n", gron.ExitParseStatements, "Failed to parse statements")
 		h += fmt.Sprintf("  %d\t%s\n", gron.ExitJSONEncode, "Failed to encode JSON")
+		h += fmt.Sprintf("  %d\t%s\n", gron.ExitUsage, "Invalid options")
+		h += fmt.Sprintf("  %d\t%s\n", gron.ExitInputTooLarge, "Input larger than --max-input")
+		h += fmt.Sprintf("  %d\t%s\n", gron.ExitNoValidLines, "No valid lines of input with --skip-errors")
+		h += fmt.Sprintf("  %d\t%s\n", gron.ExitDifferences, "Inputs differ with --diff")
+		h += fmt.Sprintf("  %d\t%s\n", gron.ExitEmptyInput, "Input empty or only whitespace")
 		h += "\n"
 
 		h += "Examples:\n"
@@ -49,7 +150,7 @@ func init() {
 		h += "  curl -s http://jsonplaceholder.typicode.com/users/1 | gron\n"
 		h += "  gron http://jsonplaceholder.typicode.com/users/1 | grep company | gron --ungron\n"
 
-		fmt.Fprintf(os.Stderr, h)
+		fmt.Fprint(os.Stderr, h)
 	}
 }
 
@@ -59,10 +160,91 @@ func main() {
 		colorizeFlag   bool
 		monochromeFlag bool
 		streamFlag     bool
+		streamKeyFlag  string
+		arrayStartFlag int
 		noSortFlag     bool
+		keepOrderFlag  bool
 		versionFlag    bool
 		insecureFlag   bool
 		jsonFlag       bool
+		redactFlag     stringSliceFlag
+		maxLineFlag    int
+		maxIndexFlag   int
+		countByFlag    string
+		countFlag      bool
+		namespaceFlag  string
+		determFlag     bool
+		inferFlag      bool
+		verboseFlag    bool
+		keepGoingFlag  bool
+		ndjsonFlag     bool
+		serverNameFlag string
+		globFlag       stringSliceFlag
+		globExclFlag   stringSliceFlag
+		preorderFlag   bool
+		columnsFlag    string
+		formFlag       bool
+		checkFlag      bool
+		clipboardFlag  bool
+		fdFlag         int
+		eventsFlag     bool
+		sampleFlag     float64
+		seedFlag       int64
+		inlineFlag     bool
+		maxMemoryFlag  string
+		arraySetFlag   bool
+		interactFlag   bool
+		outputFlag     string
+		gzipFlag       bool
+		lenientFlag    bool
+		precisionFlag  bool
+		parentsFlag    bool
+		matchFlag      string
+		invertFlag     bool
+		splitFlag      bool
+		splitKeysFlag  bool
+		splitOnFlag    string
+		floatFmtFlag   string
+		ignoreCaseFlag bool
+		smartCaseFlag  bool
+		schemaFlag     bool
+		appendFlag     bool
+		keysCaseFlag   string
+		dupKeysFlag    string
+		selectFlag     string
+		reindexFlag    bool
+		baseFlag       string
+		maxInputFlag   string
+		highlightFlag  stringSliceFlag
+		canonicalFlag  bool
+		hashFlag       bool
+		prefixFlag     string
+		yamlFlag       bool
+		tomlFlag       bool
+		xmlFlag        bool
+		inputFlag      string
+		rootFlag       string
+		valuesFlag     bool
+		timeoutFlag    time.Duration
+		retryFlag      int
+		retryDelayFlag time.Duration
+		headerFlag     stringSliceFlag
+		indentFlag     string
+		compactFlag    bool
+		skipErrFlag    bool
+		depthFlag      int
+		truncateFlag   int
+		strictFlag     bool
+		byValueFlag    bool
+		envFlag        bool
+		shellFlag      string
+		diffFlag       string
+		diffCtxFlag    int
+		mergeFlag      bool
+		namedFlag      bool
+		unwrapFlag     bool
+		skipNullsFlag  bool
+		jsonPathFlag   bool
 	)
 
 	flag.BoolVar(&ungronFlag, "ungron", false, "")
@@ -73,14 +255,115 @@ func main() {
 	flag.BoolVar(&monochromeFlag, "m", false, "")
 	flag.BoolVar(&streamFlag, "s", false, "")
 	flag.BoolVar(&streamFlag, "stream", false, "")
+	flag.StringVar(&streamKeyFlag, "stream-key", "", "")
+	flag.IntVar(&arrayStartFlag, "array-start", 0, "")
 	flag.BoolVar(&noSortFlag, "no-sort", false, "")
+	flag.BoolVar(&keepOrderFlag, "keep-order", false, "")
 	flag.BoolVar(&versionFlag, "version", false, "")
 	flag.BoolVar(&insecureFlag, "k", false, "")
 	flag.BoolVar(&insecureFlag, "insecure", false, "")
 	flag.BoolVar(&jsonFlag, "j", false, "")
 	flag.BoolVar(&jsonFlag, "json", false, "")
+	flag.Var(&redactFlag, "redact", "")
+	flag.IntVar(&maxLineFlag, "max-line-size", gron.DefaultMaxLineSize, "")
+	flag.IntVar(&maxLineFlag, "max-line-bytes", gron.DefaultMaxLineSize, "")
+	flag.IntVar(&maxIndexFlag, "max-array-index", gron.DefaultMaxArrayIndex, "")
+	flag.StringVar(&countByFlag, "count-by", "", "")
+	flag.BoolVar(&countFlag, "count", false, "")
+	flag.StringVar(&namespaceFlag, "namespace", "", "")
+	flag.BoolVar(&determFlag, "deterministic", false, "")
+	flag.BoolVar(&inferFlag, "infer-types", false, "")
+	flag.StringVar(&errorFormat, "error-format", "text", "")
+	flag.BoolVar(&verboseFlag, "v", false, "")
+	flag.BoolVar(&verboseFlag, "verbose", false, "")
+	flag.BoolVar(&keepGoingFlag, "keep-going", false, "")
+	flag.BoolVar(&ndjsonFlag, "to-ndjson", false, "")
+	flag.StringVar(&serverNameFlag, "tls-servername", "", "")
+	flag.Var(&globFlag, "glob", "")
+	flag.Var(&globExclFlag, "glob-exclude", "")
+	flag.BoolVar(&preorderFlag, "preorder", false, "")
+	flag.BoolVar(&preorderFlag, "sort-keys-only", false, "")
+	flag.StringVar(&columnsFlag, "from-columns", "", "")
+	flag.BoolVar(&formFlag, "form", false, "")
+	flag.BoolVar(&checkFlag, "check", false, "")
+	flag.BoolVar(&clipboardFlag, "clipboard", false, "")
+	flag.IntVar(&fdFlag, "fd", -1, "")
+	flag.BoolVar(&eventsFlag, "events", false, "")
+	flag.Float64Var(&sampleFlag, "sample-rate", 0, "")
+	flag.Int64Var(&seedFlag, "seed", 0, "")
+	flag.BoolVar(&inlineFlag, "inline-scalar-arrays", false, "")
+	flag.BoolVar(&inlineFlag, "flatten-arrays", false, "")
+	flag.StringVar(&maxMemoryFlag, "max-memory", "", "")
+	flag.BoolVar(&arraySetFlag, "array-as-set", false, "")
+	flag.BoolVar(&interactFlag, "interactive", false, "")
+	flag.StringVar(&outputFlag, "o", "", "")
+	flag.StringVar(&outputFlag, "output", "", "")
+	flag.BoolVar(&gzipFlag, "gzip", false, "")
+	flag.BoolVar(&lenientFlag, "lenient", false, "")
+	flag.BoolVar(&precisionFlag, "precision-check", false, "")
+	flag.BoolVar(&parentsFlag, "parents", false, "")
+	flag.StringVar(&matchFlag, "match", "", "")
+	flag.BoolVar(&invertFlag, "invert-match", false, "")
+	flag.BoolVar(&splitFlag, "split", false, "")
+	flag.BoolVar(&splitKeysFlag, "split-keys", false, "")
+	flag.StringVar(&splitOnFlag, "split-on", "", "")
+	flag.StringVar(&floatFmtFlag, "float-format", "", "")
+	flag.BoolVar(&ignoreCaseFlag, "i", false, "")
+	flag.BoolVar(&ignoreCaseFlag, "ignore-case", false, "")
+	flag.BoolVar(&smartCaseFlag, "S", false, "")
+	flag.BoolVar(&smartCaseFlag, "smart-case", false, "")
+	flag.BoolVar(&schemaFlag, "schema", false, "")
+	flag.BoolVar(&appendFlag, "append", false, "")
+	flag.StringVar(&keysCaseFlag, "keys-case", "", "")
+	flag.StringVar(&dupKeysFlag, "dup-keys", "", "")
+	flag.StringVar(&selectFlag, "select-index", "", "")
+	flag.BoolVar(&reindexFlag, "reindex", false, "")
+	flag.StringVar(&baseFlag, "base", "", "")
+	flag.StringVar(&maxInputFlag, "max-input", "", "")
+	flag.Var(&highlightFlag, "highlight", "")
+	flag.BoolVar(&canonicalFlag, "canonical", false, "")
+	flag.BoolVar(&hashFlag, "hash", false, "")
+	flag.StringVar(&prefixFlag, "prefix", "", "")
+	flag.StringVar(&prefixFlag, "p", "", "")
+	flag.BoolVar(&yamlFlag, "yaml", false, "")
+	flag.BoolVar(&yamlFlag, "y", false, "")
+	flag.BoolVar(&tomlFlag, "toml", false, "")
+	flag.BoolVar(&xmlFlag, "xml", false, "")
+	flag.StringVar(&inputFlag, "input", "", "")
+	flag.StringVar(&rootFlag, "root", gron.DefaultRoot, "")
+	flag.BoolVar(&valuesFlag, "values", false, "")
+	flag.BoolVar(&valuesFlag, "V", false, "")
+	flag.DurationVar(&timeoutFlag, "timeout", gron.DefaultTimeout, "")
+	flag.IntVar(&retryFlag, "retry", 0, "")
+	flag.DurationVar(&retryDelayFlag, "retry-delay", gron.DefaultRetryDelay, "")
+	flag.Var(&headerFlag, "header", "")
+	flag.Var(&headerFlag, "H", "")
+	flag.StringVar(&indentFlag, "indent", "", "")
+	flag.BoolVar(&compactFlag, "compact", false, "")
+	flag.BoolVar(&compactFlag, "C", false, "")
+	flag.BoolVar(&skipErrFlag, "skip-errors", false, "")
+	flag.IntVar(&depthFlag, "depth", -1, "")
+	flag.IntVar(&truncateFlag, "truncate", 0, "")
+	flag.BoolVar(&strictFlag, "strict", false, "")
+	flag.BoolVar(&byValueFlag, "sort-by-value", false, "")
+	flag.BoolVar(&envFlag, "env", false, "")
+	flag.StringVar(&shellFlag, "shell", "", "")
+	flag.StringVar(&diffFlag, "diff", "", "")
+	flag.IntVar(&diffCtxFlag, "diff-context", 0, "")
+	flag.BoolVar(&mergeFlag, "merge", false, "")
+	flag.BoolVar(&namedFlag, "named", false, "")
+	flag.BoolVar(&unwrapFlag, "unwrap-strings", false, "")
+	flag.BoolVar(&skipNullsFlag, "skip-nulls", false, "")
+	flag.BoolVar(&jsonPathFlag, "jsonpath", false, "")
 
 	flag.Parse()
+	switch errorFormat {
+	case "text", "json":
+	default:
+		format := errorFormat
+		errorFormat = "text"
+		fatal(gron.ExitUsage, fmt.Errorf("invalid --error-format %q: must be text or json", format))
+	}
 
 	// Print version information
 	if versionFlag {
@@ -88,33 +371,296 @@ func main() {
 		os.Exit(gron.ExitOK)
 	}
 
-	// Determine what the program's input should be:
-	// file, HTTP URL or stdin
-	var rawInput io.Reader
-	filename := flag.Arg(0)
-	if filename == "" || filename == "-" {
-		rawInput = os.Stdin
-	} else if gron.ValidURL(filename) {
-		r, err := gron.GetURL(filename, insecureFlag, gronVersion)
+	// --yaml, --toml and --xml are short for --input with that format.
+	// Without any of them, files are recognised by their extensions when
+	// gronning whole documents; but only if every input has the same one.
+	// The document --diff compares against is read in the same format
+	for f, set := range map[string]bool{"yaml": yamlFlag, "toml": tomlFlag, "xml": xmlFlag} {
+		if !set {
+			continue
+		}
+		if inputFlag != "" && inputFlag != f {
+			fatal(gron.ExitUsage, fmt.Errorf("only one input format can be given; have %s and %s", inputFlag, f))
+		}
+		inputFlag = f
+	}
+	wholeDocument := !ungronFlag && !streamFlag && !checkFlag && !precisionFlag && !schemaFlag && countByFlag == "" && baseFlag == ""
+	if inputFlag == "" && wholeDocument {
+		inputFlag = inputsFormat(flag.Args())
+		if diffFlag != "" && inputsFormat([]string{diffFlag}) != inputFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--diff can only compare documents in the same format; use --input to choose one"))
+		}
+	}
+
+	// Otherwise input is read as JSON, but files that say they're in
+	// another format are refused rather than read as JSON regardless
+	if inputFlag == "" && !ungronFlag {
+		if f := inputsFormat(flag.Args()); f != "json" {
+			fatal(gron.ExitUsage, fmt.Errorf("%s input can only be read when gronning a whole document; use --input json to read it as JSON", strings.ToUpper(f)))
+		}
+	}
+	if inputFlag == "" {
+		inputFlag = "json"
+	}
+	formatOpt, ok := inputFormats[inputFlag]
+	if !ok {
+		fatal(gron.ExitUsage, fmt.Errorf("invalid --input %q: must be json, yaml, toml, xml, msgpack, bson or avro", inputFlag))
+	}
+	if inputFlag != "json" && !wholeDocument {
+		fatal(gron.ExitUsage, fmt.Errorf("%s input can only be read when gronning a whole document", strings.ToUpper(inputFlag)))
+	}
+
+	// Any further options need to be validated before
+	// we go to the trouble of opening the input
+	options := []gron.Option{gron.WithWarnings(func(msg string) {
+		fmt.Fprintf(os.Stderr, "gron: warning: %s\n", msg)
+	})}
+	if len(redactFlag) > 0 {
+		patterns := make([]*regexp.Regexp, 0, len(redactFlag))
+		for _, p := range redactFlag {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				fatal(gron.ExitUsage, fmt.Errorf("invalid --redact pattern: %s", err))
+			}
+			patterns = append(patterns, re)
+		}
+		options = append(options, gron.WithRedact(patterns...))
+	}
+	for _, h := range highlightFlag {
+		i := strings.LastIndex(h, ":")
+		if i == -1 {
+			fatal(gron.ExitUsage, fmt.Errorf("invalid --highlight %q: must be PATTERN:COLOR", h))
+		}
+		re, err := regexp.Compile(h[:i])
 		if err != nil {
-			fatal(gron.ExitFetchURL, err)
+			fatal(gron.ExitUsage, fmt.Errorf("invalid --highlight pattern: %s", err))
 		}
-		rawInput = r
-	} else {
-		r, err := os.Open(filename)
+		attr, ok := colorNames[h[i+1:]]
+		if !ok {
+			fatal(gron.ExitUsage, fmt.Errorf("invalid --highlight color %q: must be one of black, red, green, yellow, blue, magenta, cyan or white", h[i+1:]))
+		}
+		col := color.New(attr)
+		if colorizeFlag {
+			col.EnableColor()
+		}
+		options = append(options, gron.WithHighlight(re, col))
+	}
+	if (splitFlag || splitOnFlag != "") && !ungronFlag {
+		fatal(gron.ExitUsage, fmt.Errorf("--split and --split-on can only be used with --ungron"))
+	}
+	if splitFlag && splitOnFlag == "" {
+		splitOnFlag = `^\s*$`
+	}
+	if splitOnFlag != "" {
+		re, err := regexp.Compile(splitOnFlag)
 		if err != nil {
-			fatal(gron.ExitOpenFile, err)
+			fatal(gron.ExitUsage, fmt.Errorf("invalid --split-on pattern: %s", err))
+		}
+		options = append(options, gron.WithSplitOn(re))
+	}
+	if floatFmtFlag != "" {
+		if !floatVerb.MatchString(floatFmtFlag) {
+			fatal(gron.ExitUsage, fmt.Errorf("invalid --float-format %q: must be a single float verb like %%.2f", floatFmtFlag))
+		}
+		fmt.Fprintf(os.Stderr, "gron: warning: numbers formatted with --float-format can't be ungronned exactly\n")
+		options = append(options, gron.WithFloatFormat(floatFmtFlag))
+	}
+	if truncateFlag < 0 {
+		fatal(gron.ExitUsage, fmt.Errorf("invalid --truncate %d: must not be negative", truncateFlag))
+	}
+	if truncateFlag > 0 {
+		if ungronFlag || jsonFlag || eventsFlag || envFlag || baseFlag != "" || canonicalFlag || hashFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--truncate can't be used with --ungron, --json, --events, --env, --base, --canonical or --hash"))
+		}
+		options = append(options, gron.WithTruncate(truncateFlag))
+	}
+	if compactFlag && indentFlag != "" {
+		fatal(gron.ExitUsage, fmt.Errorf("--compact and --indent can't be used together"))
+	}
+	if compactFlag {
+		options = append(options, gron.WithIndent(""))
+	}
+	if indentFlag != "" {
+		indent, err := parseIndent(indentFlag)
+		if err != nil {
+			fatal(gron.ExitUsage, fmt.Errorf("invalid --indent %q: %s", indentFlag, err))
+		}
+		options = append(options, gron.WithIndent(indent))
+	}
+	if maxLineFlag <= 0 {
+		fatal(gron.ExitUsage, fmt.Errorf("invalid --max-line-size: must be greater than zero"))
+	}
+	options = append(options, gron.WithMaxLineSize(maxLineFlag))
+	if maxIndexFlag < 0 {
+		fatal(gron.ExitUsage, fmt.Errorf("invalid --max-array-index: must not be negative"))
+	}
+	options = append(options, gron.WithMaxArrayIndex(maxIndexFlag))
+	if maxMemoryFlag != "" {
+		n, err := parseSize(maxMemoryFlag)
+		if err != nil || n <= 0 {
+			fatal(gron.ExitUsage, fmt.Errorf("invalid --max-memory %q: must be a size like 512M", maxMemoryFlag))
+		}
+		options = append(options, gron.WithMaxMemory(n))
+	}
+	if maxInputFlag != "" {
+		n, err := parseSize(maxInputFlag)
+		if err != nil || n <= 0 {
+			fatal(gron.ExitUsage, fmt.Errorf("invalid --max-input %q: must be a size like 10M", maxInputFlag))
+		}
+		options = append(options, gron.WithMaxInputBytes(n))
+	}
+	if depthFlag >= 0 {
+		options = append(options, gron.WithMaxDepth(depthFlag))
+	}
+	if jsonPathFlag {
+		if ungronFlag || jsonFlag || eventsFlag || envFlag || valuesFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--jsonpath can't be used with --ungron, --json, --events, --env or --values"))
+		}
+		if rootFlag != gron.DefaultRoot {
+			fatal(gron.ExitUsage, fmt.Errorf("--jsonpath and --root can't be used together"))
+		}
+		options = append(options, gron.WithPathDialect(gron.PathJSONPath))
+	}
+	if !gron.ValidIdentifier(rootFlag) {
+		fatal(gron.ExitUsage, fmt.Errorf("invalid --root %q: must be a name that doesn't need quoting, like users", rootFlag))
+	}
+	options = append(options, gron.WithRoot(rootFlag))
+	if prefixFlag != "" {
+		// A valid path makes a valid statement with a value added
+		if _, err := gron.ParseStatement(prefixFlag + " = {};"); err != nil {
+			fatal(gron.ExitUsage, fmt.Errorf("invalid --prefix %q: must be a path like json.users[3]", prefixFlag))
+		}
+		options = append(options, gron.WithPathPrefix(prefixFlag))
+	}
+	if len(globFlag) > 0 {
+		options = append(options, gron.WithGlob(globFlag...))
+	}
+	if len(globExclFlag) > 0 {
+		options = append(options, gron.WithGlobExclude(globExclFlag...))
+	}
+	if invertFlag && matchFlag == "" {
+		fatal(gron.ExitUsage, fmt.Errorf("--invert-match can only be used with --match"))
+	}
+	if matchFlag != "" {
+		if ungronFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--match can't be used with --ungron"))
+		}
+		// -i and -S apply to --match as they do to --glob patterns
+		pattern := matchFlag
+		if ignoreCaseFlag || (smartCaseFlag && strings.ToLower(matchFlag) == matchFlag) {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			fatal(gron.ExitUsage, fmt.Errorf("invalid --match pattern: %s", err))
+		}
+		options = append(options, gron.WithValueMatch(re, invertFlag))
+	}
+	switch {
+	case ignoreCaseFlag:
+		options = append(options, gron.WithCaseMode(gron.IgnoreCase))
+	case smartCaseFlag:
+		options = append(options, gron.WithCaseMode(gron.SmartCase))
+	}
+	if keysCaseFlag != "" {
+		valid := false
+		for _, kc := range gron.KeysCases {
+			if gron.KeysCase(keysCaseFlag) == kc {
+				valid = true
+			}
+		}
+		if !valid {
+			fatal(gron.ExitUsage, fmt.Errorf("invalid --keys-case %q: must be snake, camel, lower or upper", keysCaseFlag))
+		}
+		options = append(options, gron.WithKeysCase(gron.KeysCase(keysCaseFlag)))
+	}
+	if dupKeysFlag != "" {
+		valid := false
+		for _, m := range gron.DupKeysModes {
+			if gron.DupKeys(dupKeysFlag) == m {
+				valid = true
+			}
+		}
+		if !valid {
+			fatal(gron.ExitUsage, fmt.Errorf("invalid --dup-keys %q: must be last, warn or error", dupKeysFlag))
+		}
+		if ungronFlag || inputFlag != "json" || checkFlag || schemaFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--dup-keys can only be used when gronning JSON"))
+		}
+		options = append(options, gron.WithDupKeys(gron.DupKeys(dupKeysFlag)))
+	}
+	if selectFlag != "" {
+		if streamFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--select-index can't be used with --stream"))
+		}
+		var indexes []int
+		for _, s := range strings.Split(selectFlag, ",") {
+			i, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil || i < 0 {
+				fatal(gron.ExitUsage, fmt.Errorf("invalid --select-index %q: must be a list of indexes like 0,2,5", selectFlag))
+			}
+			indexes = append(indexes, i)
+		}
+		options = append(options, gron.WithSelectIndex(indexes, reindexFlag))
+	}
+	if sampleFlag != 0 {
+		if sampleFlag < 0 || sampleFlag > 1 {
+			fatal(gron.ExitUsage, fmt.Errorf("invalid --sample-rate: must be greater than 0 and at most 1"))
+		}
+
+		// Without --seed every run gets a different sample
+		seeded := false
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "seed" {
+				seeded = true
+			}
+		})
+		if !seeded {
+			seedFlag = time.Now().UnixNano()
+		}
+		options = append(options, gron.WithSample(sampleFlag, seedFlag))
+	}
+	if serverNameFlag != "" {
+		options = append(options, gron.WithTLSServerName(serverNameFlag))
+	}
+	if timeoutFlag < 0 {
+		fatal(gron.ExitUsage, fmt.Errorf("invalid --timeout: must not be negative"))
+	}
+	options = append(options, gron.WithTimeout(timeoutFlag))
+	if retryFlag < 0 || retryDelayFlag < 0 {
+		fatal(gron.ExitUsage, fmt.Errorf("invalid --retry or --retry-delay: must not be negative"))
+	}
+	if retryFlag > 0 {
+		options = append(options, gron.WithRetry(retryFlag, retryDelayFlag))
+	}
+	for _, h := range headerFlag {
+		parts := strings.SplitN(h, ":", 2)
+		key := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || key == "" {
+			fatal(gron.ExitUsage, fmt.Errorf("invalid --header %q: must be like 'Key: Value'", h))
+		}
+		options = append(options, gron.WithHeader(key, strings.TrimSpace(parts[1])))
+	}
+	if namespaceFlag != "" {
+		options = append(options, gron.WithNamespace(strings.Split(namespaceFlag, ".")...))
+	}
+
+	if theme := os.Getenv("GRON_COLORS"); theme != "" {
+		if err := gron.SetColors(theme); err != nil {
+			fmt.Fprintf(os.Stderr, "gron: warning: ignoring GRON_COLORS: %s\n", err)
 		}
-		rawInput = r
 	}
 
 	var opts int
 	// The monochrome option should be forced if the output isn't a terminal
-	// to avoid doing unnecessary work calling the color functions
+	// to avoid doing unnecessary work calling the color functions. Setting
+	// NO_COLOR, to anything, does the same unless --colorize is used
+	_, noColorEnv := os.LookupEnv("NO_COLOR")
 	switch {
 	case colorizeFlag:
 		color.NoColor = false
-	case monochromeFlag || color.NoColor:
+		gron.EnableColors()
+	case monochromeFlag || color.NoColor || noColorEnv:
 		opts = opts | gron.OptMonochrome
 	}
 	if noSortFlag {
@@ -123,24 +669,710 @@ func main() {
 	if jsonFlag {
 		opts = opts | gron.OptJSON
 	}
+	if determFlag {
+		opts = opts | gron.OptDeterministic
+	}
+	opts = opts | formatOpt
+	if canonicalFlag {
+		opts = opts | gron.OptCanonical
+	}
+	if inferFlag {
+		opts = opts | gron.OptInferTypes
+	}
+	if ndjsonFlag {
+		opts = opts | gron.OptNDJSON
+	}
+	if preorderFlag {
+		opts = opts | gron.OptPreorder
+	}
+	if byValueFlag {
+		if noSortFlag || preorderFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--sort-by-value can't be used with --no-sort or --preorder"))
+		}
+		opts = opts | gron.OptSortByValue
+	}
+	if keepOrderFlag {
+		if ungronFlag || inputFlag != "json" {
+			fatal(gron.ExitUsage, fmt.Errorf("--keep-order can only be used when gronning JSON"))
+		}
+		if noSortFlag || preorderFlag || byValueFlag || determFlag || canonicalFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--keep-order can't be used with --no-sort, --preorder, --sort-by-value, --deterministic or --canonical"))
+		}
+		opts = opts | gron.OptKeepOrder
+	}
+	if eventsFlag {
+		opts = opts | gron.OptEvents
+	}
+	if valuesFlag {
+		opts = opts | gron.OptValues
+	}
+	if envFlag {
+		if ungronFlag || jsonFlag || eventsFlag || valuesFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--env can't be used with --ungron, --json, --events or --values"))
+		}
+		opts = opts | gron.OptEnv
+	}
+	if shellFlag != "" {
+		if !envFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--shell can only be used with --env"))
+		}
+		valid := false
+		for _, sh := range gron.Shells {
+			if gron.Shell(shellFlag) == sh {
+				valid = true
+			}
+		}
+		if !valid {
+			fatal(gron.ExitUsage, fmt.Errorf("invalid --shell %q: must be one of posix, fish or powershell", shellFlag))
+		}
+		options = append(options, gron.WithShell(gron.Shell(shellFlag)))
+	}
+	if strictFlag {
+		if !ungronFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--strict can only be used with --ungron"))
+		}
+		opts = opts | gron.OptStrict
+	}
+	if splitKeysFlag {
+		if !ungronFlag || splitFlag || splitOnFlag != "" {
+			fatal(gron.ExitUsage, fmt.Errorf("--split-keys can only be used with --ungron, and not with --split or --split-on"))
+		}
+		opts = opts | gron.OptSplitKeys
+	}
+	if skipErrFlag {
+		if !streamFlag || ungronFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--skip-errors can only be used with --stream"))
+		}
+		opts = opts | gron.OptSkipErrors
+	}
+	if streamKeyFlag != "" {
+		if !streamFlag || ungronFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--stream-key can only be used with --stream"))
+		}
+		options = append(options, gron.WithStreamKey(streamKeyFlag))
+	}
+	if arrayStartFlag != 0 {
+		if !streamFlag || ungronFlag || countFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--array-start can only be used with --stream"))
+		}
+		if arrayStartFlag < 0 {
+			fatal(gron.ExitUsage, fmt.Errorf("invalid --array-start: must not be negative"))
+		}
+		options = append(options, gron.WithArrayStart(arrayStartFlag))
+	}
+	if inlineFlag {
+		opts = opts | gron.OptInlineScalarArrays
+	}
+	if arraySetFlag {
+		opts = opts | gron.OptArrayAsSet
+	}
+	if lenientFlag {
+		opts = opts | gron.OptLenient
+	}
+	if skipNullsFlag {
+		if ungronFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--skip-nulls can't be used with --ungron"))
+		}
+		opts = opts | gron.OptSkipNulls
+	}
+	if unwrapFlag {
+		if ungronFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--unwrap-strings can't be used with --ungron"))
+		}
+		opts = opts | gron.OptUnwrapStrings
+	}
+	if parentsFlag {
+		opts = opts | gron.OptParents
+	}
+	if baseFlag != "" {
+		if checkFlag || precisionFlag || schemaFlag || countByFlag != "" || streamFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--base can't be used with --check, --precision-check, --schema, --count-by or --stream"))
+		}
+		if !ungronFlag && (jsonFlag || eventsFlag || envFlag) {
+			fatal(gron.ExitUsage, fmt.Errorf("patches can't be written with --json, --events or --env"))
+		}
+		f, err := os.Open(baseFlag)
+		if err != nil {
+			fatal(gron.ExitOpenFile, err)
+		}
+		options = append(options, gron.WithBase(f))
+		f.Close()
+	}
+	if diffFlag != "" {
+		if baseFlag != "" || ungronFlag || checkFlag || precisionFlag || schemaFlag || countByFlag != "" || streamFlag || hashFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--diff can only be used when gronning a whole document"))
+		}
+		if jsonFlag || eventsFlag || envFlag || valuesFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--diff can't be used with --json, --events, --env or --values"))
+		}
+		f, err := os.Open(diffFlag)
+		if err != nil {
+			fatal(gron.ExitOpenFile, err)
+		}
+		options = append(options, gron.WithBase(f))
+		f.Close()
+	}
+	if diffCtxFlag != 0 {
+		if diffFlag == "" || diffCtxFlag < 0 {
+			fatal(gron.ExitUsage, fmt.Errorf("--diff-context must be a positive number of statements with --diff"))
+		}
+		options = append(options, gron.WithDiffContext(diffCtxFlag))
+	}
+	switch columnsFlag {
+	case "":
+	case "tsv":
+		opts = opts | gron.OptFromTSV
+	case "csv":
+		opts = opts | gron.OptFromCSV
+	default:
+		fatal(gron.ExitUsage, fmt.Errorf("invalid --from-columns format %q: must be tsv or csv", columnsFlag))
+	}
+	if formFlag {
+		if !ungronFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--form can only be used with --ungron"))
+		}
+		if columnsFlag != "" || jsonFlag || inferFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--form can't be used with --from-columns, --json or --infer-types"))
+		}
+		opts = opts | gron.OptFromForm
+	}
 
-	// Pick the appropriate action: gron, ungron or gronStream
+	// Pick the appropriate action: gron, ungron, ungronStream, check, precisionCheck, schema, countBy, hash, count, countStream, gronStream, diff or gronDiff
+	if hashFlag && (ungronFlag || checkFlag || precisionFlag || schemaFlag || countByFlag != "" || streamFlag || baseFlag != "") {
+		fatal(gron.ExitUsage, fmt.Errorf("--hash can only be used when gronning a whole document"))
+	}
+	if countFlag && (ungronFlag || checkFlag || precisionFlag || schemaFlag || countByFlag != "" || hashFlag || baseFlag != "" || diffFlag != "") {
+		fatal(gron.ExitUsage, fmt.Errorf("--count can only be used when gronning"))
+	}
 	var a gron.ActionFn = gron.Gron
-	if ungronFlag {
+	if ungronFlag && streamFlag {
+		a = gron.UngronStream
+	} else if ungronFlag {
 		a = gron.Ungron
+	} else if checkFlag {
+		a = gron.Check
+	} else if precisionFlag {
+		a = gron.PrecisionCheck
+	} else if schemaFlag {
+		a = gron.Schema
+	} else if countByFlag != "" {
+		a = gron.CountBy(countByFlag)
+	} else if hashFlag {
+		a = gron.Hash
+	} else if countFlag && streamFlag {
+		a = gron.CountStream
+	} else if countFlag {
+		a = gron.Count
 	} else if streamFlag {
 		a = gron.GronStream
+	} else if baseFlag != "" {
+		a = gron.Diff
+	} else if diffFlag != "" {
+		a = gron.GronDiff
+	}
+	if interactFlag {
+		if ungronFlag || checkFlag || precisionFlag || schemaFlag || countByFlag != "" || hashFlag || countFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--interactive can only be used when gronning"))
+		}
+		a = interactive(a)
+	}
+	if verboseFlag {
+		unit := "statements"
+		if ungronFlag || schemaFlag || countByFlag != "" || hashFlag || countFlag {
+			unit = "lines"
+		}
+		a = verbose(a, unit)
+	}
+
+	// Each input is processed in turn; with --keep-going a failure
+	// is reported and the remaining inputs are still processed, with
+	// the exit code of the last failure used at the end
+	inputs := flag.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+	if clipboardFlag && !ungronFlag {
+		if len(flag.Args()) > 0 {
+			fatal(gron.ExitUsage, fmt.Errorf("--clipboard can't be used with a FILE or URL, except with --ungron"))
+		}
+		inputs = []string{"clipboard"}
+	}
+
+	// With --fd the descriptor is read in place of stdin
+	if fdFlag != -1 {
+		if len(flag.Args()) > 0 || clipboardFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--fd can't be used with a FILE or URL, or with --clipboard"))
+		}
+		f, err := openFD(fdFlag)
+		if err != nil {
+			fatal(gron.ExitOpenFile, err)
+		}
+		stdin = f
+	}
+
+	// With --merge or --named the inputs are gronned together
+	// as the elements of an array or the values of an object
+	var names []string
+	if namedFlag {
+		mergeFlag = true
+		seen := make(map[string]string)
+		for _, input := range inputs {
+			name := inputName(input)
+			if other, exists := seen[name]; exists {
+				fatal(gron.ExitUsage, fmt.Errorf("inputs %s and %s would both be named %s", other, input, name))
+			}
+			seen[name] = input
+			names = append(names, name)
+		}
+	}
+	if mergeFlag {
+		if ungronFlag || checkFlag || precisionFlag || schemaFlag || countByFlag != "" || hashFlag || streamFlag || baseFlag != "" || diffFlag != "" || clipboardFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--merge and --named can only be used when gronning whole documents"))
+		}
+		if interactFlag || verboseFlag {
+			fatal(gron.ExitUsage, fmt.Errorf("--merge and --named can't be used with --interactive or --verbose"))
+		}
+	}
+
+	// With --clipboard the ungronned JSON is copied to the clipboard
+	// rather than written to stdout, so there's no point in color
+	var out io.Writer = colorable.NewColorableStdout()
+	clip := &bytes.Buffer{}
+	if clipboardFlag && ungronFlag {
+		if outputFlag != "" {
+			fatal(gron.ExitUsage, fmt.Errorf("--clipboard and --output can't be used together"))
+		}
+		out = clip
+		opts = opts | gron.OptMonochrome
+	}
+
+	// Output written to a file, and compressed output in particular,
+	// shouldn't have color codes in it unless they're asked for
+	var marker string
+	if appendFlag && outputFlag == "" {
+		fatal(gron.ExitUsage, fmt.Errorf("--append needs --output"))
+	}
+	if outputFlag != "" {
+		var f io.WriteCloser
+		var err error
+		if appendFlag {
+			var af *appendFile
+			af, err = openAppend(outputFlag)
+			if err == nil {
+				marker = af.marker()
+			}
+			f = af
+		} else {
+			f, err = os.Create(outputFlag)
+		}
+		if err != nil {
+			fatal(gron.ExitOpenFile, err)
+		}
+		out = f
+		outputClosers = append(outputClosers, f)
+		if !colorizeFlag {
+			opts = opts | gron.OptMonochrome
+		}
+	}
+	if gzipFlag || strings.HasSuffix(outputFlag, ".gz") {
+		gz := gzip.NewWriter(out)
+		out = gz
+		outputClosers = append([]io.Closer{gz}, outputClosers...)
+		opts = opts | gron.OptMonochrome
+	}
+	fmt.Fprint(out, marker)
+
+	exitCode := gron.ExitOK
+	if mergeFlag {
+		code, err := processMerged(inputs, names, out, opts, options, insecureFlag)
+		if code != gron.ExitOK {
+			fatal(code, err)
+		}
+	} else {
+		for _, input := range inputs {
+			var code int
+			var err error
+			if clipboardFlag && !ungronFlag {
+				code, err = processClipboard(a, out, opts, options)
+			} else {
+				code, err = processInput(input, a, out, opts, options, insecureFlag)
+			}
+			if code == gron.ExitOK {
+				continue
+			}
+			if !keepGoingFlag {
+				fatal(code, err)
+			}
+			if err != nil && errorFormat == "json" {
+				writeJSONError(code, input, err)
+			} else if err != nil {
+				fmt.Fprintf(os.Stderr, "gron: %s: %s\n", input, withHint(err))
+			}
+			exitCode = code
+		}
+	}
+
+	if err := closeOutput(); err != nil {
+		fatal(gron.ExitOpenFile, err)
+	}
+
+	if clipboardFlag && ungronFlag && exitCode == gron.ExitOK {
+		if err := writeClipboard(clip); err != nil {
+			fatal(gron.ExitJSONEncode, err)
+		}
+	}
+
+	os.Exit(exitCode)
+}
+
+// processClipboard runs the action on the contents of the clipboard
+func processClipboard(a gron.ActionFn, w io.Writer, opts int, options []gron.Option) (int, error) {
+	r, err := readClipboard()
+	if err != nil {
+		return gron.ExitReadInput, err
+	}
+	return a(r, w, opts, options...)
+}
+
+// withHint adds a suggestion for how to fix an error, if there is one
+func withHint(err error) error {
+	if _, ok := err.(gron.LineTooLongError); ok {
+		return fmt.Errorf("%s; raise it with --max-line-bytes", err)
+	}
+	return err
+}
+
+// processInput opens an input, which is a file, an HTTP URL or
+// '-' for stdin, and runs the action on it; decompressing it first
+// if it's gzipped
+func processInput(input string, a gron.ActionFn, w io.Writer, opts int, options []gron.Option, insecure bool) (int, error) {
+	r, closer, code, err := openInput(input, options, insecure)
+	if err != nil {
+		return code, err
+	}
+	defer closer.Close()
+
+	return a(r, w, opts, options...)
+}
+
+// processMerged opens all of the inputs and grons them together, as
+// the elements of an array or with names as the values of an object
+func processMerged(inputs, names []string, w io.Writer, opts int, options []gron.Option, insecure bool) (int, error) {
+	rs := make([]io.Reader, 0, len(inputs))
+	for _, input := range inputs {
+		r, closer, code, err := openInput(input, options, insecure)
+		if err != nil {
+			return code, fmt.Errorf("%s: %s", input, err)
+		}
+		defer closer.Close()
+		rs = append(rs, r)
+	}
+
+	if names != nil {
+		options = append(options, gron.WithInputNames(names...))
+	}
+	return gron.GronInputs(rs, w, opts, options...)
+}
+
+// openInput opens an input, which is a file, an HTTP URL or '-'
+// for stdin, decompressing it if it's gzipped; returning an exit
+// code for any error
+func openInput(input string, options []gron.Option, insecure bool) (io.Reader, io.Closer, int, error) {
+	var rawInput io.Reader
+	var closer io.Closer = ioutil.NopCloser(nil)
+	if input == "" || input == "-" {
+		rawInput = stdin
+	} else if gron.ValidURL(input) {
+		r, err := gron.GetURL(input, insecure, gronVersion, options...)
+		if err != nil {
+			return nil, nil, gron.ExitFetchURL, err
+		}
+		rawInput = r
+	} else {
+		f, err := os.Open(input)
+		if err != nil {
+			return nil, nil, gron.ExitOpenFile, err
+		}
+		rawInput = f
+		closer = f
 	}
-	exitCode, err := a(rawInput, colorable.NewColorableStdout(), opts)
 
-	if exitCode != gron.ExitOK {
-		fatal(exitCode, err)
+	r, err := gron.Decompress(rawInput)
+	if err != nil {
+		closer.Close()
+		return nil, nil, gron.ExitReadInput, fmt.Errorf("failed to decompress input: %s", err)
 	}
+	return r, closer, gron.ExitOK, nil
+}
+
+// stdin is what's read for the input '-'; --fd replaces it
+var stdin io.Reader = os.Stdin
+
+// openFD returns the file descriptor n, which must already be open
+// (e.g. by the shell with 3<file), as a file to read input from
+func openFD(n int) (*os.File, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("invalid file descriptor %d", n)
+	}
+	f := os.NewFile(uintptr(n), fmt.Sprintf("/dev/fd/%d", n))
+	if f == nil {
+		return nil, fmt.Errorf("invalid file descriptor %d", n)
+	}
+	if _, err := f.Stat(); err != nil {
+		return nil, fmt.Errorf("can't read from file descriptor %d: %s", n, err)
+	}
+	if err := checkReadable(n); err != nil {
+		return nil, fmt.Errorf("can't read from file descriptor %d: %s", n, err)
+	}
+	return f, nil
+}
+
+// inputName returns the name that an input is given with --named: the
+// name of the file or the last part of the URL's path without any
+// extensions, or stdin for '-'
+func inputName(input string) string {
+	if input == "" || input == "-" {
+		return "stdin"
+	}
+	if gron.ValidURL(input) {
+		if u, err := url.Parse(input); err == nil {
+			input = u.Host + u.Path
+		}
+	}
+	name := filepath.Base(strings.TrimRight(input, "/"))
+	if i := strings.Index(name, "."); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// inputFormats are the formats that --input accepts,
+// and the option that makes gron read each of them
+var inputFormats = map[string]int{
+	"json":    0,
+	"yaml":    gron.OptYAML,
+	"toml":    gron.OptTOML,
+	"xml":     gron.OptXML,
+	"msgpack": gron.OptMsgpack,
+	"bson":    gron.OptBSON,
+	"avro":    gron.OptAvro,
+}
+
+// inputsFormat returns the format, e.g. yaml, that the extensions of
+// every one of the inputs say they're in; or json if they don't agree,
+// or if any of them has another extension or none. Files are often
+// gzipped, so a .gz extension is looked past
+func inputsFormat(inputs []string) string {
+	formats := map[string]string{".yaml": "yaml", ".yml": "yaml", ".toml": "toml", ".xml": "xml", ".msgpack": "msgpack", ".mpk": "msgpack", ".bson": "bson", ".avro": "avro"}
+	format := ""
+	for _, input := range inputs {
+		if gron.ValidURL(input) {
+			if u, err := url.Parse(input); err == nil {
+				input = u.Path
+			}
+		}
+		ext := strings.ToLower(filepath.Ext(strings.TrimSuffix(input, ".gz")))
+		f, ok := formats[ext]
+		if !ok || (format != "" && f != format) {
+			return "json"
+		}
+		format = f
+	}
+	if format == "" {
+		return "json"
+	}
+	return format
+}
+
+// verbose wraps an action so that a summary of how many lines it
+// wrote, how many bytes it read and how long it took is printed to
+// stderr when it's finished; keeping the summary out of the output
+func verbose(a gron.ActionFn, unit string) gron.ActionFn {
+	return func(r io.Reader, w io.Writer, opts int, options ...gron.Option) (int, error) {
+		cr := &countingReader{r: r}
+		cw := &countingWriter{w: w}
+		start := time.Now()
+
+		code, err := a(cr, cw, opts, options...)
+
+		fmt.Fprintf(
+			os.Stderr, "gron: %d %s, %d bytes read in %s\n",
+			cw.lines, unit, cr.n, time.Since(start),
+		)
+		return code, err
+	}
+}
 
-	os.Exit(gron.ExitOK)
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
 }
 
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// countingWriter counts the lines written through it
+type countingWriter struct {
+	w     io.Writer
+	lines int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	for _, b := range p[:n] {
+		if b == '\n' {
+			c.lines++
+		}
+	}
+	return n, err
+}
+
+// floatVerb matches a single fmt verb for floats, with
+// optional flags, width and precision; e.g. %.2f or %8.3e
+var floatVerb = regexp.MustCompile(`^%[-+# 0]*[0-9]*(\.[0-9]*)?[eEfFgG]$`)
+
+// colorNames maps the names of the colors that
+// can be used with --highlight to their attributes
+var colorNames = map[string]color.Attribute{
+	"black":   color.FgBlack,
+	"red":     color.FgRed,
+	"green":   color.FgGreen,
+	"yellow":  color.FgYellow,
+	"blue":    color.FgBlue,
+	"magenta": color.FgMagenta,
+	"cyan":    color.FgCyan,
+	"white":   color.FgWhite,
+}
+
+// stringSliceFlag is a flag.Value that collects
+// every value given for a repeatable flag
+type stringSliceFlag []string
+
+func (f *stringSliceFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *stringSliceFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+// parseSize parses a number of bytes with an optional
+// K, M or G suffix (in powers of 1024); e.g. 512M
+func parseSize(s string) (int64, error) {
+	s = strings.TrimSuffix(strings.ToUpper(s), "B")
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		mult = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		mult = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		mult = 1 << 30
+	}
+	if mult > 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return n * mult, nil
+}
+
+// parseIndent parses an indent for JSON, which is a number of
+// spaces, 'tab' or a literal string of whitespace
+func parseIndent(s string) (string, error) {
+	if s == "tab" {
+		return "\t", nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 {
+			return "", fmt.Errorf("must not be negative")
+		}
+		return strings.Repeat(" ", n), nil
+	}
+	if strings.TrimSpace(s) != "" {
+		return "", fmt.Errorf("must be a number of spaces, 'tab' or whitespace")
+	}
+	return s, nil
+}
+
+// errorFormat is how fatal writes errors: text or json
+var errorFormat = "text"
+
+// fatal reports an error and exits with the code provided, closing the
+// output first so that whatever was written before the error isn't lost
 func fatal(code int, err error) {
-	fmt.Fprintf(os.Stderr, "%s\n", err)
+	if err != nil && errorFormat == "json" {
+		writeJSONError(code, "", err)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", withHint(err))
+	}
+	if err := closeOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+	}
 	os.Exit(code)
 }
+
+// outputClosers close the file that --output writes to, after the
+// gzip writer writing to it if there is one
+var outputClosers []io.Closer
+
+// closeOutput closes the output file, if there is one, flushing anything
+// that's buffered; e.g. so that a gzipped file is complete even when the
+// run has failed part of the way through
+func closeOutput() error {
+	closers := outputClosers
+	outputClosers = nil
+
+	var err error
+	for _, c := range closers {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to write output: %s", cerr)
+		}
+	}
+	return err
+}
+
+// errorStages names the stage that failed for each exit code
+var errorStages = map[int]string{
+	gron.ExitOpenFile:        "open",
+	gron.ExitReadInput:       "read",
+	gron.ExitFormStatements:  "gron",
+	gron.ExitFetchURL:        "fetch",
+	gron.ExitParseStatements: "parse",
+	gron.ExitJSONEncode:      "encode",
+	gron.ExitUsage:           "usage",
+	gron.ExitInputTooLarge:   "read",
+	gron.ExitNoValidLines:    "gron",
+	gron.ExitDifferences:     "diff",
+	gron.ExitEmptyInput:      "read",
+}
+
+// writeJSONError writes an error to stderr as a line of JSON, with
+// the input it's about if it's one of several (with --keep-going),
+// and the number of the line of input it's about if there is one
+func writeJSONError(code int, input string, err error) {
+	report := struct {
+		ExitCode int    `json:"exitCode"`
+		Stage    string `json:"stage"`
+		Message  string `json:"message"`
+		Input    string `json:"input,omitempty"`
+		Line     int    `json:"line,omitempty"`
+	}{code, errorStages[code], withHint(err).Error(), input, 0}
+
+	switch e := err.(type) {
+	case gron.LineError:
+		report.Line = e.Line
+	case gron.LineTooLongError:
+		report.Line = e.Line
+	}
+
+	j, _ := json.Marshal(report)
+	fmt.Fprintf(os.Stderr, "%s\n", j)
+}
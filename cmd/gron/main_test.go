@@ -0,0 +1,634 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gron"
+)
+
+// TestMain runs gron itself, rather than the tests, when the test binary
+// is run by runGron; so that the tests can check what main does, exit
+// codes included
+func TestMain(m *testing.M) {
+	if os.Getenv("GRON_TEST_MAIN") == "1" {
+		main()
+	}
+	os.Exit(m.Run())
+}
+
+// runGron runs gron with some arguments, extra environment variables
+// and input, returning what it wrote to stdout and stderr and its exit
+// code. Any files are passed as file descriptors from 3 up
+func runGron(t *testing.T, args []string, env []string, stdin string, files ...*os.File) (string, string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(append(os.Environ(), "GRON_TEST_MAIN=1"), env...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.ExtraFiles = files
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	err := cmd.Run()
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		t.Fatalf("failed to run gron %s: %s", strings.Join(args, " "), err)
+	}
+	return stdout.String(), stderr.String(), cmd.ProcessState.ExitCode()
+}
+
+func TestErrorStages(t *testing.T) {
+	// Every exit code other than ExitOK has a stage for --error-format json
+	for code := gron.ExitOpenFile; code <= gron.ExitEmptyInput; code++ {
+		if errorStages[code] == "" {
+			t.Errorf("want a stage for exit code %d; have none", code)
+		}
+	}
+	if have := errorStages[gron.ExitDifferences]; have != "diff" {
+		t.Errorf("want stage diff for ExitDifferences; have %q", have)
+	}
+}
+
+func TestColorizeWithNoColor(t *testing.T) {
+	// --colorize wins over NO_COLOR, for --highlight colors too
+	tests := []struct {
+		args []string
+		in   string
+		want string
+	}{
+		{[]string{"-c"}, `{"a":"x"}`, "\x1b[33m\"x\""},
+		{[]string{"-c", "--highlight", "^x$:green"}, `{"a":"x"}`, "\x1b[32m\"x\""},
+	}
+
+	for _, test := range tests {
+		stdout, stderr, code := runGron(t, test.args, []string{"NO_COLOR=1"}, test.in)
+		if code != gron.ExitOK {
+			t.Fatalf("want ExitOK for %v; have %d (%s)", test.args, code, stderr)
+		}
+		if !strings.Contains(stdout, test.want) {
+			t.Errorf("want %q in the output for %v; have %q", test.want, test.args, stdout)
+		}
+	}
+
+	// Without --colorize there's no color at all
+	stdout, _, _ := runGron(t, nil, []string{"NO_COLOR=1"}, `{"a":"x"}`)
+	if want := "json = {};\njson.a = \"x\";\n"; stdout != want {
+		t.Errorf("want %q without --colorize; have %q", want, stdout)
+	}
+}
+
+func TestGzipOutputOnError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gron")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The gzip stream is finished even though the run fails
+	name := filepath.Join(dir, "out.json.gz")
+	_, _, code := runGron(t, []string{"-s", "-o", name}, nil, "{\"a\": 1}\n{\n")
+	if code != gron.ExitFormStatements {
+		t.Errorf("want ExitFormStatements; have %d", code)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("want a valid gzip file; have %s", err)
+	}
+	have, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("want a complete gzip file; have %s", err)
+	}
+	if want := "json = [];\njson[0] = {};\njson[0].a = 1;\n"; string(have) != want {
+		t.Errorf("want %q; have %q", want, have)
+	}
+}
+
+func TestAppendOutputOnError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gron")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// What was written before the error is appended after the marker
+	name := filepath.Join(dir, "out.gron")
+	_, _, code := runGron(t, []string{"-s", "--append", "-o", name}, nil, "{\"a\": 1}\n{\n")
+	if code != gron.ExitFormStatements {
+		t.Errorf("want ExitFormStatements; have %d", code)
+	}
+
+	have, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitN(string(have), "\n", 2)
+	if !strings.HasPrefix(lines[0], "-- gron ") || len(lines) != 2 {
+		t.Fatalf("want a '-- gron TIME' marker first; have %q", have)
+	}
+	if want := "json = [];\njson[0] = {};\njson[0].a = 1;\n"; lines[1] != want {
+		t.Errorf("want %q after the marker; have %q", want, lines[1])
+	}
+}
+
+func TestFD(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gron")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "in.json")
+	if err := ioutil.WriteFile(name, []byte(`{"a": 1}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	r, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	w, err := os.OpenFile(name, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// The first of the files passed is descriptor 3
+	tests := []struct {
+		args  []string
+		files []*os.File
+		code  int
+		want  string
+	}{
+		{[]string{"--fd", "3"}, []*os.File{r}, gron.ExitOK, "json = {};\njson.a = 1;\n"},
+		{[]string{"--fd", "3"}, []*os.File{w}, gron.ExitOpenFile, ""},
+		{[]string{"--fd", "3"}, nil, gron.ExitOpenFile, ""},
+		{[]string{"--fd", "3", name}, []*os.File{r}, gron.ExitUsage, ""},
+	}
+
+	for _, test := range tests {
+		if _, err := r.Seek(0, 0); err != nil {
+			t.Fatal(err)
+		}
+		stdout, stderr, code := runGron(t, append([]string{"-m"}, test.args...), nil, "", test.files...)
+		if code != test.code {
+			t.Errorf("want exit code %d for %v; have %d (%s)", test.code, test.args, code, stderr)
+		}
+		if stdout != test.want {
+			t.Errorf("want %q for %v; have %q", test.want, test.args, stdout)
+		}
+	}
+}
+
+func TestCheckExitCodes(t *testing.T) {
+	tests := []struct {
+		in   string
+		code int
+	}{
+		{`{"a": 1}`, gron.ExitOK},
+		{`{"a": `, gron.ExitFormStatements},
+		{"", gron.ExitEmptyInput},
+		{"  \n", gron.ExitEmptyInput},
+	}
+
+	for _, test := range tests {
+		stdout, stderr, code := runGron(t, []string{"--check"}, nil, test.in)
+		if code != test.code {
+			t.Errorf("want exit code %d for %q; have %d (%s)", test.code, test.in, code, stderr)
+		}
+		if stdout != "" {
+			t.Errorf("want no output for %q; have %q", test.in, stdout)
+		}
+	}
+}
+
+func TestKeepGoing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gron")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"a.json":   `{"a": 1}`,
+		"bad.json": `{"a": `,
+		"b.json":   `{"b": 2}`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	inputs := []string{
+		filepath.Join(dir, "bad.json"),
+		filepath.Join(dir, "a.json"),
+		filepath.Join(dir, "missing.json"),
+		filepath.Join(dir, "b.json"),
+	}
+
+	// Every input is processed, and the exit code is the last failure's
+	stdout, stderr, code := runGron(t, append([]string{"-m", "--keep-going"}, inputs...), nil, "")
+	if code != gron.ExitOpenFile {
+		t.Errorf("want ExitOpenFile; have %d (%s)", code, stderr)
+	}
+	if want := "json = {};\njson.a = 1;\njson = {};\njson.b = 2;\n"; stdout != want {
+		t.Errorf("want %q; have %q", want, stdout)
+	}
+	for _, input := range []string{inputs[0], inputs[2]} {
+		if !strings.Contains(stderr, "gron: "+input+": ") {
+			t.Errorf("want an error for %s; have %q", input, stderr)
+		}
+	}
+
+	// Without --keep-going the first failure stops it
+	stdout, stderr, code = runGron(t, append([]string{"-m"}, inputs...), nil, "")
+	if code != gron.ExitFormStatements {
+		t.Errorf("want ExitFormStatements without --keep-going; have %d (%s)", code, stderr)
+	}
+	if stdout != "" {
+		t.Errorf("want no output without --keep-going; have %q", stdout)
+	}
+}
+
+func TestKeepGoingJSONErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gron")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	good := filepath.Join(dir, "good.json")
+	if err := ioutil.WriteFile(good, []byte(`{"a": 1}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.json")
+
+	// Each input that fails is reported as a line of JSON that says
+	// which input it was, rather than as text
+	args := []string{"--keep-going", "--error-format", "json", missing, good}
+	_, stderr, code := runGron(t, args, nil, "")
+	if code != gron.ExitOpenFile {
+		t.Errorf("want ExitOpenFile; have %d (%s)", code, stderr)
+	}
+
+	var report struct {
+		ExitCode int    `json:"exitCode"`
+		Stage    string `json:"stage"`
+		Input    string `json:"input"`
+	}
+	if err := json.Unmarshal([]byte(stderr), &report); err != nil {
+		t.Fatalf("want a JSON error; have %q (%s)", stderr, err)
+	}
+	if report.ExitCode != gron.ExitOpenFile || report.Stage != "open" || report.Input != missing {
+		t.Errorf("want an open error for %s; have %+v", missing, report)
+	}
+}
+
+func TestInputFormatFromExtension(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gron")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "in.yml")
+	if err := ioutil.WriteFile(name, []byte(`{"a": 1}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only whole documents can be read in another format, so a file
+	// that says it's YAML is refused by the other modes
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{[]string{name}, gron.ExitOK},
+		{[]string{"-s", name}, gron.ExitUsage},
+		{[]string{"--check", name}, gron.ExitUsage},
+		{[]string{"-s", "--input", "json", name}, gron.ExitOK},
+	}
+
+	for _, test := range tests {
+		_, stderr, code := runGron(t, test.args, nil, "")
+		if code != test.code {
+			t.Errorf("want exit code %d for %v; have %d (%s)", test.code, test.args, code, stderr)
+		}
+	}
+}
+
+func TestFlagValidation(t *testing.T) {
+	// Each of these is refused before any input is read
+	tests := [][]string{
+		{"--error-format", "xml"},
+		{"--input", "csv"},
+		{"--yaml", "--toml"},
+		{"--redact", "("},
+		{"--highlight", "x"},
+		{"--highlight", "x:pink"},
+		{"--float-format", "%d"},
+		{"--truncate", "-1"},
+		{"--truncate", "5", "-u"},
+		{"--compact", "--indent", "2"},
+		{"--indent", "x"},
+		{"--max-line-size", "0"},
+		{"--max-memory", "lots"},
+		{"--max-input", "0"},
+		{"--root", "a b"},
+		{"--jsonpath", "-u"},
+		{"--prefix", "json..a"},
+		{"--invert-match"},
+		{"--keys-case", "kebab"},
+		{"--dup-keys", "first"},
+		{"--select-index", "1,x"},
+		{"--select-index", "1", "-s"},
+		{"--sample-rate", "2"},
+		{"--timeout", "-1s"},
+		{"--retry", "-1"},
+		{"--header", "nocolon"},
+		{"--shell", "posix"},
+		{"--env", "--shell", "csh"},
+		{"--strict"},
+		{"--split"},
+		{"--split-on", "^$"},
+		{"--skip-errors"},
+		{"--stream-key", "id"},
+		{"--array-start", "5"},
+		{"-s", "--array-start", "-1"},
+		{"--keep-order", "--no-sort"},
+		{"--sort-by-value", "--preorder"},
+		{"--from-columns", "xsv", "-u"},
+		{"--form"},
+		{"--append"},
+		{"--hash", "-u"},
+		{"--count", "-u"},
+		{"--merge", "-u"},
+		{"--diff-context", "2"},
+		{"--clipboard", "in.json"},
+	}
+
+	for _, args := range tests {
+		stdout, stderr, code := runGron(t, args, nil, `{"a": 1}`)
+		if code != gron.ExitUsage {
+			t.Errorf("want ExitUsage for %v; have %d (%s)", args, code, stderr)
+		}
+		if stdout != "" || stderr == "" {
+			t.Errorf("want an error and no output for %v; have %q and %q", args, stdout, stderr)
+		}
+	}
+
+	// With --error-format json the error is a line of JSON
+	_, stderr, _ := runGron(t, []string{"--error-format", "json", "--strict"}, nil, "")
+	if !strings.HasPrefix(stderr, `{"exitCode":7,"stage":"usage","message":"--strict can only be used with --ungron"}`) {
+		t.Errorf("want a JSON error; have %q", stderr)
+	}
+}
+
+func TestMatchCase(t *testing.T) {
+	in := `{"a": "Hello", "b": "hello"}`
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--match", "hello"}, "json = {};\njson.b = \"hello\";\n"},
+		{[]string{"-i", "--match", "hello"}, "json = {};\njson.a = \"Hello\";\njson.b = \"hello\";\n"},
+		{[]string{"-S", "--match", "hello"}, "json = {};\njson.a = \"Hello\";\njson.b = \"hello\";\n"},
+		{[]string{"-S", "--match", "Hello"}, "json = {};\njson.a = \"Hello\";\n"},
+	}
+
+	for _, test := range tests {
+		stdout, stderr, code := runGron(t, append([]string{"-m"}, test.args...), nil, in)
+		if code != gron.ExitOK {
+			t.Fatalf("want ExitOK for %v; have %d (%s)", test.args, code, stderr)
+		}
+		if stdout != test.want {
+			t.Errorf("want %q for %v; have %q", test.want, test.args, stdout)
+		}
+	}
+}
+
+func TestOutputFlags(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gron")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	in := `{"a": 1}`
+	want := "json = {};\njson.a = 1;\n"
+
+	// --output writes to the file rather than stdout, replacing it
+	name := filepath.Join(dir, "out.gron")
+	for i := 0; i < 2; i++ {
+		stdout, stderr, code := runGron(t, []string{"-o", name}, nil, in)
+		if code != gron.ExitOK || stdout != "" {
+			t.Fatalf("want ExitOK and no output; have %d and %q (%s)", code, stdout, stderr)
+		}
+	}
+	if have, _ := ioutil.ReadFile(name); string(have) != want {
+		t.Errorf("want %q in the --output file; have %q", want, have)
+	}
+
+	// --append adds to it, after a marker line
+	runGron(t, []string{"--append", "-o", name}, nil, in)
+	have, _ := ioutil.ReadFile(name)
+	parts := strings.SplitN(string(have), "\n\n-- gron ", 2)
+	if len(parts) != 2 || parts[0]+"\n" != want || !strings.HasSuffix(parts[1], "\n"+want) {
+		t.Errorf("want the output again after a marker with --append; have %q", have)
+	}
+
+	// The output is gzipped with --gzip or a .gz name, and it's
+	// monochrome unless -c is used, either way
+	for _, args := range [][]string{
+		{"--gzip", "-o", filepath.Join(dir, "out.gron")},
+		{"-o", filepath.Join(dir, "out.gron.gz")},
+		{"-c", "-o", filepath.Join(dir, "out.gron.gz")},
+	} {
+		_, stderr, code := runGron(t, args, nil, in)
+		if code != gron.ExitOK {
+			t.Fatalf("want ExitOK for %v; have %d (%s)", args, code, stderr)
+		}
+		f, err := os.Open(args[len(args)-1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			t.Fatalf("want gzipped output for %v; have %s", args, err)
+		}
+		have, err := ioutil.ReadAll(gz)
+		f.Close()
+		if err != nil || string(have) != want {
+			t.Errorf("want %q for %v; have %q and %v", want, args, have, err)
+		}
+	}
+
+	// Ungronned output is gzipped too, and is valid JSON once it's
+	// decompressed; with no color codes, as it's monochrome
+	name = filepath.Join(dir, "out.json.gz")
+	_, stderr, code := runGron(t, []string{"-u", "-o", name}, nil, want)
+	if code != gron.ExitOK {
+		t.Fatalf("want ExitOK for -u -o %s; have %d (%s)", name, code, stderr)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("want gzipped ungron output; have %s", err)
+	}
+	var v map[string]interface{}
+	if err := json.NewDecoder(gz).Decode(&v); err != nil || v["a"] != 1.0 {
+		t.Errorf("want {\"a\": 1} from the gzipped ungron output; have %v and %v", v, err)
+	}
+
+	// Without --output, --gzip writes to stdout
+	stdout, _, _ := runGron(t, []string{"--gzip"}, nil, in)
+	gz, err = gzip.NewReader(strings.NewReader(stdout))
+	if err != nil {
+		t.Fatalf("want gzipped output on stdout; have %s", err)
+	}
+	if have, _ := ioutil.ReadAll(gz); string(have) != want {
+		t.Errorf("want %q gzipped on stdout; have %q", want, have)
+	}
+
+	// A file that can't be created is ExitOpenFile
+	_, _, code = runGron(t, []string{"-o", filepath.Join(dir, "missing", "out.gron")}, nil, in)
+	if code != gron.ExitOpenFile {
+		t.Errorf("want ExitOpenFile for an --output file that can't be created; have %d", code)
+	}
+}
+
+func TestStreamKeyExitCodes(t *testing.T) {
+	tests := []struct {
+		args []string
+		in   string
+		code int
+		want string
+	}{
+		{
+			[]string{"-s", "--stream-key", "id"},
+			"{\"id\": 2, \"n\": 2}\n{\"n\": 3}\n",
+			gron.ExitOK,
+			"json = {};\njson[\"2\"] = {};\njson[\"2\"].id = 2;\njson[\"2\"].n = 2;\njson[\"#1\"] = {};\njson[\"#1\"].n = 3;\n",
+		},
+		{
+			[]string{"-s", "--stream-key", "id"},
+			"{\"id\": 1}\n{\"id\": 1}\n",
+			gron.ExitFormStatements,
+			"json = {};\njson[\"1\"] = {};\njson[\"1\"].id = 1;\n",
+		},
+		{
+			[]string{"-s", "--stream-key", "id", "--skip-errors"},
+			"{\"id\": 1}\n{\"id\": 1}\n",
+			gron.ExitOK,
+			"json = {};\njson[\"1\"] = {};\njson[\"1\"].id = 1;\n",
+		},
+	}
+
+	for _, test := range tests {
+		stdout, stderr, code := runGron(t, test.args, nil, test.in)
+		if code != test.code {
+			t.Errorf("want exit code %d for %v and %q; have %d (%s)", test.code, test.args, test.in, code, stderr)
+		}
+		if stdout != test.want {
+			t.Errorf("want %q for %v and %q; have %q", test.want, test.args, test.in, stdout)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"512", 512, true},
+		{"10K", 10 << 10, true},
+		{"512M", 512 << 20, true},
+		{"2gb", 2 << 30, true},
+		{"1.5M", 0, false},
+		{"M", 0, false},
+	}
+
+	for _, test := range tests {
+		have, err := parseSize(test.in)
+		if (err == nil) != test.ok || have != test.want {
+			t.Errorf("want %d (ok %t) for %q; have %d and %v", test.want, test.ok, test.in, have, err)
+		}
+	}
+}
+
+func TestParseIndent(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"2", "  ", true},
+		{"0", "", true},
+		{"tab", "\t", true},
+		{" \t", " \t", true},
+		{"-1", "", false},
+		{"x", "", false},
+	}
+
+	for _, test := range tests {
+		have, err := parseIndent(test.in)
+		if (err == nil) != test.ok || have != test.want {
+			t.Errorf("want %q (ok %t) for %q; have %q and %v", test.want, test.ok, test.in, have, err)
+		}
+	}
+}
+
+func TestInputsFormat(t *testing.T) {
+	tests := []struct {
+		in   []string
+		want string
+	}{
+		{nil, "json"},
+		{[]string{"a.yml", "b.yaml.gz"}, "yaml"},
+		{[]string{"a.yml", "b.toml"}, "json"},
+		{[]string{"a.yml", "-"}, "json"},
+		{[]string{"https://example.com/config.TOML?x=1"}, "toml"},
+		{[]string{"dump.bson"}, "bson"},
+	}
+
+	for _, test := range tests {
+		if have := inputsFormat(test.in); have != test.want {
+			t.Errorf("want %s for %v; have %s", test.want, test.in, have)
+		}
+	}
+}
+
+func TestInputName(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"-", "stdin"},
+		{"", "stdin"},
+		{"data/users.json.gz", "users"},
+		{"https://example.com/api/users.json", "users"},
+		{"https://example.com/", "example"},
+	}
+
+	for _, test := range tests {
+		if have := inputName(test.in); have != test.want {
+			t.Errorf("want %s for %q; have %s", test.want, test.in, have)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package gron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// ColorFields names the colors in a theme for SetColors, in order
+var ColorFields = []string{"string", "number", "bool", "null", "brace", "key", "punct"}
+
+// SetColors sets the output colors from a theme like the GRON_COLORS
+// environment variable: a colon-separated list of SGR codes, as for jq's
+// JQ_COLORS, for strings, numbers, bools, null, braces, keys and the = and
+// ; punctuation in that order; e.g. 0;32:0;31 for green strings and red
+// numbers. Colors that
+// are left out, or empty, stay as they are. An invalid theme is an error,
+// and none of the colors are changed.
+func SetColors(theme string) error {
+	fields := strings.Split(theme, ":")
+	if len(fields) > len(ColorFields) {
+		return fmt.Errorf("too many colors in %q: there are only %d (%s)", theme, len(ColorFields), strings.Join(ColorFields, ", "))
+	}
+
+	colors := []*color.Color{StrColor, NumColor, BoolColor, NullColor, BraceColor, BareColor, PunctColor}
+	for i, f := range fields {
+		if f == "" {
+			continue
+		}
+		var attrs []color.Attribute
+		for _, code := range strings.Split(f, ";") {
+			n, err := strconv.Atoi(code)
+			if err != nil || n < 0 || n > 255 {
+				return fmt.Errorf("invalid %s color %q: must be SGR codes separated by ';', like 1;31", ColorFields[i], f)
+			}
+			attrs = append(attrs, color.Attribute(n))
+		}
+		colors[i] = color.New(attrs...)
+	}
+
+	StrColor, NumColor, BoolColor, NullColor, BraceColor, BareColor, PunctColor = colors[0], colors[1], colors[2], colors[3], colors[4], colors[5], colors[6]
+	sprintFns = colorSprintFns()
+	return nil
+}
+
+// EnableColors turns on each of the output colors even if the NO_COLOR
+// environment variable is set, which fatih/color checks when a color is
+// made rather than when it's used; so setting color.NoColor to false
+// isn't enough. It's for when color is asked for, e.g. with --colorize
+func EnableColors() {
+	for _, c := range []*color.Color{StrColor, NumColor, BoolColor, NullColor, BraceColor, BareColor, PunctColor} {
+		c.EnableColor()
+	}
+}
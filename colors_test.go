@@ -0,0 +1,108 @@
+package gron
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestSetColors(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+
+	before := []*color.Color{StrColor, NumColor, BoolColor, NullColor, BraceColor, BareColor, PunctColor}
+	defer func() {
+		StrColor, NumColor, BoolColor, NullColor, BraceColor, BareColor, PunctColor = before[0], before[1], before[2], before[3], before[4], before[5], before[6]
+		sprintFns = colorSprintFns()
+	}()
+
+	// Invalid themes don't change anything
+	for _, theme := range []string{"0;32:x", "1:2:3:4:5:6:7:8", "0;256", "0;;1"} {
+		if err := SetColors(theme); err == nil {
+			t.Errorf("want an error for theme %q", theme)
+		}
+		if StrColor != before[0] {
+			t.Errorf("want the colors unchanged after invalid theme %q", theme)
+		}
+	}
+
+	if err := SetColors("0;32:::1;35:::0;34"); err != nil {
+		t.Fatalf("want nil error; have %s", err)
+	}
+
+	out := &bytes.Buffer{}
+	Gron(strings.NewReader(`{"a": "x", "n": null}`), out, 0)
+	// As in TestPunctColor, only the codes that start each color are checked
+	for _, want := range []string{"\x1b[0;32m\"x\"", "\x1b[1;35mnull", "\x1b[0;34m = ", "\x1b[0;34m;"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("want %q in output; have %q", want, out.String())
+		}
+	}
+
+	// Colors that are left out stay as they were
+	if NumColor != before[1] || BareColor != before[5] {
+		t.Errorf("want colors left out of the theme unchanged")
+	}
+}
+
+func TestPunctColor(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+
+	in := `{"a": [1, true]}`
+	out := &bytes.Buffer{}
+	Gron(strings.NewReader(in), out, 0)
+	// Only the codes that start each color are checked; how the
+	// color is reset afterwards depends on the version of fatih/color
+	for _, want := range []string{"\x1b[2m = ", "\x1b[2m;"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("want %q in output; have %q", want, out.String())
+		}
+	}
+
+	// Monochrome output doesn't have any escape codes at all
+	out.Reset()
+	Gron(strings.NewReader(in), out, OptMonochrome)
+	want := "json = {};\njson.a = [];\njson.a[0] = 1;\njson.a[1] = true;\n"
+	if out.String() != want {
+		t.Errorf("want %q; have %q", want, out.String())
+	}
+}
+
+func TestEnableColors(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+
+	before := []*color.Color{StrColor, NumColor, BoolColor, NullColor, BraceColor, BareColor, PunctColor}
+	defer func() {
+		StrColor, NumColor, BoolColor, NullColor, BraceColor, BareColor, PunctColor = before[0], before[1], before[2], before[3], before[4], before[5], before[6]
+		sprintFns = colorSprintFns()
+	}()
+
+	// Colors made while NO_COLOR is set stay off even with NoColor false
+	os.Setenv("NO_COLOR", "1")
+	err := SetColors("0;32")
+	os.Unsetenv("NO_COLOR")
+	if err != nil {
+		t.Fatalf("want nil error; have %s", err)
+	}
+
+	out := &bytes.Buffer{}
+	Gron(strings.NewReader(`{"a": "x"}`), out, 0)
+	if strings.Contains(out.String(), "\x1b[0;32m") {
+		t.Errorf("want no string color with NO_COLOR set; have %q", out.String())
+	}
+
+	EnableColors()
+	out.Reset()
+	Gron(strings.NewReader(`{"a": "x"}`), out, 0)
+	if !strings.Contains(out.String(), "\x1b[0;32m\"x\"") {
+		t.Errorf("want the string color after EnableColors; have %q", out.String())
+	}
+}
@@ -0,0 +1,191 @@
+package gron
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/fatih/color"
+)
+
+// Diff output colors
+var (
+	RemovedColor = color.New(color.FgRed)
+	AddedColor   = color.New(color.FgGreen)
+)
+
+// WithDiffContext makes GronDiff write up to n unchanged statements
+// either side of each change, like diff -U; with a line of -- between
+// changes that are further apart than that
+func WithDiffContext(n int) Option {
+	return func(c *config) {
+		c.diffContext = n
+	}
+}
+
+// GronDiff grons both the base document set with WithBase and the input,
+// and writes the statements that differ between them: those only in the
+// base prefixed with '-' and those only in the input prefixed with '+';
+// e.g. for a changed value:
+//
+//   -json.name = "Tom";
+//   +json.name = "Bob";
+//
+// Statements in both are left out, unless WithDiffContext is used, in
+// which case they're prefixed with a space. The statements are always
+// sorted, and filters like WithGlob apply to both documents. Both are
+// read in the input format set in opts; e.g. YAML with OptYAML. The exit
+// code is ExitDifferences, with no error, if there are any differences.
+func GronDiff(r io.Reader, w io.Writer, opts int, options ...Option) (int, error) {
+	c := newConfig(options)
+	opts = resolveOpts(opts)
+	c.setOpts(opts)
+
+	decode := decoderFor(opts)
+	var base statements
+	if c.baseErr != nil {
+		return ExitReadInput, fmt.Errorf("failed to read base: %s", c.baseErr)
+	}
+	if c.base != nil {
+		ss, err := decode(bytes.NewReader(c.base), c.prefix(), c)
+		if err != nil {
+			return ExitFormStatements, fmt.Errorf("failed to form statements from base: %s", err)
+		}
+		base = c.filter(append(c.namespaceStatements(), ss...))
+	}
+
+	target, err := decode(r, c.prefix(), c)
+	if err != nil {
+		return ExitFormStatements, fmt.Errorf("failed to form statements: %s", err)
+	}
+	target = c.filter(append(c.namespaceStatements(), target...))
+
+	// Numbers that are normalized the same aren't a difference
+	if opts&OptDeterministic > 0 {
+		for _, ss := range []statements{base, target} {
+			for i, s := range ss {
+				ss[i] = s.withNormalizedNumbers()
+			}
+		}
+	}
+
+	lines := mergeDiff(base, target)
+	var shown statements
+	for i, l := range lines {
+		if l.op != ' ' || lines.nearChange(i, c.diffContext) {
+			shown = append(shown, l.s)
+		}
+	}
+	if err := c.checkFloatFormat(shown); err != nil {
+		return ExitFormStatements, fmt.Errorf("failed to form statements: %s", err)
+	}
+
+	changed, printed, skipped := false, false, false
+	for i, l := range lines {
+		if l.op == ' ' && !lines.nearChange(i, c.diffContext) {
+			skipped = true
+			continue
+		}
+		if skipped && printed && c.diffContext > 0 {
+			c.writeLine(w, "--")
+		}
+		skipped = false
+		printed = true
+		changed = changed || l.op != ' '
+
+		if err := writeDiffLine(w, l, opts, c); err != nil {
+			return ExitFormStatements, fmt.Errorf("failed to form statements: %s", err)
+		}
+	}
+
+	if changed {
+		return ExitDifferences, nil
+	}
+	return ExitOK, nil
+}
+
+// a diffLine is a statement in a diff, with '-' if it was
+// removed, '+' if it was added, or ' ' if it's unchanged
+type diffLine struct {
+	op byte
+	s  statement
+}
+
+// diffLines is a list of diffLines
+type diffLines []diffLine
+
+// mergeDiff merges base and target statements into a single sorted
+// list with the operation for each. Both lists are sorted as a side
+// effect. Removed statements come before added ones with the same path.
+func mergeDiff(base, target statements) diffLines {
+	sort.Sort(base)
+	sort.Sort(target)
+
+	lines := make(diffLines, 0, len(target))
+	i, j := 0, 0
+	for i < len(base) || j < len(target) {
+		switch {
+		case j == len(target):
+			lines = append(lines, diffLine{'-', base[i]})
+			i++
+		case i == len(base):
+			lines = append(lines, diffLine{'+', target[j]})
+			j++
+		case sameStatement(base[i], target[j]):
+			lines = append(lines, diffLine{' ', target[j]})
+			i++
+			j++
+		case lessStatements(base[i], target[j]) || base[i].path().String() == target[j].path().String():
+			lines = append(lines, diffLine{'-', base[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', target[j]})
+			j++
+		}
+	}
+	return lines
+}
+
+// sameStatement returns true if two statements are identical; the
+// order of statements is total, so that's when neither comes first
+func sameStatement(a, b statement) bool {
+	return !lessStatements(a, b) && !lessStatements(b, a)
+}
+
+// nearChange returns true if there's a removed or added
+// line within n lines of the line at index i
+func (ls diffLines) nearChange(i, n int) bool {
+	for j := i - n; j <= i+n; j++ {
+		if j >= 0 && j < len(ls) && ls[j].op != ' ' {
+			return true
+		}
+	}
+	return false
+}
+
+// writeDiffLine writes a line of a diff to w, with removed lines in
+// RemovedColor and added lines in AddedColor unless OptMonochrome is set
+func writeDiffLine(w io.Writer, l diffLine, opts int, c *config) error {
+	s := l.s
+	if c.floatFormat != "" {
+		var err error
+		s, err = s.withFormattedNumbers(c.floatFormat)
+		if err != nil {
+			return err
+		}
+	}
+
+	line := string(l.op) + s.String()
+	switch {
+	case opts&OptMonochrome > 0:
+	case l.op == '-':
+		line = RemovedColor.Sprint(line)
+	case l.op == '+':
+		line = AddedColor.Sprint(line)
+	default:
+		line = " " + s.colorString()
+	}
+	c.writeLine(w, line)
+	return nil
+}
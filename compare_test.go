@@ -0,0 +1,85 @@
+package gron
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGronDiff(t *testing.T) {
+	base := `{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5, "f": {"g": 6}, "z": 1.0}`
+	in := `{"a": 1, "b": 20, "c": 3, "d": 4, "e": 5, "f": 6, "y": true, "z": 1}`
+
+	tests := []struct {
+		context int
+		want    string
+	}{
+		{0, `-json.b = 2;
++json.b = 20;
+-json.f = {};
++json.f = 6;
+-json.f.g = 6;
++json.y = true;
+-json.z = 1.0;
++json.z = 1;
+`},
+		{1, ` json.a = 1;
+-json.b = 2;
++json.b = 20;
+ json.c = 3;
+--
+ json.e = 5;
+-json.f = {};
++json.f = 6;
+-json.f.g = 6;
++json.y = true;
+-json.z = 1.0;
++json.z = 1;
+`},
+	}
+
+	for _, test := range tests {
+		out := &bytes.Buffer{}
+		code, err := GronDiff(strings.NewReader(in), out, OptMonochrome,
+			WithBase(strings.NewReader(base)),
+			WithDiffContext(test.context),
+		)
+		if code != ExitDifferences || err != nil {
+			t.Fatalf("want ExitDifferences and nil error; have %d and %v", code, err)
+		}
+		if out.String() != test.want {
+			t.Logf("want: %s", test.want)
+			t.Logf("have: %s", out.String())
+			t.Errorf("diff with context %d does not match", test.context)
+		}
+	}
+
+	// Normalized numbers that are the same aren't a difference
+	out := &bytes.Buffer{}
+	code, err := GronDiff(strings.NewReader(`{"z": 1}`), out, OptDeterministic, WithBase(strings.NewReader(`{"z": 1.0}`)))
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("want no output; have %q", out.String())
+	}
+
+	// Removed and added lines are colored
+	out.Reset()
+	GronDiff(strings.NewReader(`{"a": 2}`), out, 0, WithBase(strings.NewReader(`{"a": 1}`)))
+	want := RemovedColor.Sprint("-json.a = 1;") + "\n" + AddedColor.Sprint("+json.a = 2;") + "\n"
+	if out.String() != want {
+		t.Errorf("want %q; have %q", want, out.String())
+	}
+
+	// Both documents are read in the input format
+	out.Reset()
+	code, err = GronDiff(strings.NewReader("a: 2\n"), out, OptMonochrome|OptYAML, WithBase(strings.NewReader("a: 1\n")))
+	if code != ExitDifferences || err != nil {
+		t.Fatalf("want ExitDifferences and nil error; have %d and %v", code, err)
+	}
+	want = "-json.a = 1;\n+json.a = 2;\n"
+	if out.String() != want {
+		t.Errorf("want %q; have %q", want, out.String())
+	}
+}
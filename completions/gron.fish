@@ -7,10 +7,95 @@
 complete -c gron -s u -l ungron     --description "Reverse the operation (turn assignments back into JSON)"
 complete -c gron -s c -l colorize   --description "Colorize output (default on tty)"
 complete -c gron -s m -l monochrome --description "Monochrome (don't colorize output)"
-complete -c gron -s s -l stream     --description "Treat each line of input as a separate JSON object"
+complete -c gron -s s -l stream     --description "Treat each line of input as a separate JSON object, or with --ungron write array elements as they're complete"
+complete -c gron      -l stream-key --description "With --stream, key each line by the value of this field rather than its line number; no two lines can share a key" -x
+complete -c gron      -l array-start --description "With --stream, number the lines from this index rather than 0" -x
+complete -c gron      -l skip-errors --description "With --stream, skip lines that aren't valid JSON rather than stopping"
+complete -c gron -s y -l yaml       --description "Read YAML rather than JSON"
+complete -c gron      -l toml       --description "Read TOML rather than JSON"
+complete -c gron      -l xml        --description "Read XML rather than JSON"
+complete -c gron      -l input      --description "Read this format" -x -a "json yaml toml xml msgpack bson avro"
+complete -c gron      -l check      --description "Validate the input as JSON without any output"
+complete -c gron      -l precision-check --description "Only output numbers that would change if parsed as a float64"
+complete -c gron      -l base       --description "Write a patch that turns this JSON file into the input, or with --ungron apply the input to it" -r
+complete -c gron      -l diff       --description "Write the statements that differ between this JSON file and the input" -r
+complete -c gron      -l diff-context --description "With --diff, also write this many unchanged statements around each change" -x
+complete -c gron      -l schema     --description "Infer a JSON Schema (draft-07) from the input and print that"
+complete -c gron      -l clipboard  --description "Read input from the clipboard, or with --ungron write output to it"
+complete -c gron      -l fd         --description "Read input from this open file descriptor rather than stdin" -x
+complete -c gron      -l interactive --description "Filter the statements interactively with fzf"
 complete -c gron -s k -l insecure   --description "Disable certificate validation"
+complete -c gron      -l tls-servername --description "Server name to use for TLS verification and SNI when fetching URLs" -x
+complete -c gron -s H -l header     --description "Send a header when fetching a URL, as 'Key: Value' (repeatable)" -x
+complete -c gron      -l timeout    --description "Time limit for fetching a URL, e.g. 5s; 0 for no limit" -x
+complete -c gron      -l retry      --description "Retry fetching a URL this many times on connection errors and 5xx responses" -x
+complete -c gron      -l retry-delay --description "Time to wait before the first retry, doubling after that" -x
+complete -c gron -s o -l output     --description "Write output to a file rather than stdout (gzipped if the name ends in .gz)" -r
+complete -c gron      -l append     --description "With --output, append to the file rather than replacing it"
+complete -c gron      -l gzip       --description "Gzip the output"
 complete -c gron -s j -l json       --description "Represent gron data as JSON stream"
+complete -c gron -s V -l values     --description "Only output the values of statements, one per line"
+complete -c gron      -l env        --description "Write values as commands that set environment variables"
+complete -c gron      -l shell      --description "With --env, the shell to write commands for" -x -a "posix fish powershell"
+complete -c gron      -l events     --description "Write each statement as a line of JSON with a structured path"
+complete -c gron      -l inline-scalar-arrays --description "Write arrays of strings, numbers, bools and nulls on one line"
+complete -c gron      -l flatten-arrays --description "Same as --inline-scalar-arrays"
+complete -c gron      -l array-as-set --description "Order array elements by value, so reordered arrays compare equal"
+complete -c gron      -l skip-nulls --description "Leave out statements that assign null"
+complete -c gron      -l unwrap-strings --description "Gron strings that contain JSON objects or arrays as part of the input"
+complete -c gron      -l lenient    --description "Ignore anything after the JSON value in the input rather than failing"
 complete -c gron      -l no-sort    --description "Don't sort output (faster)"
+complete -c gron      -l keep-order --description "Write keys in the order they're in the input rather than sorted"
+complete -c gron      -l count      --description "Print how many objects, arrays, strings, numbers, bools and nulls there are"
+complete -c gron      -l count-by   --description "Print a frequency table of a field's values across records" -x
+complete -c gron      -l sort-by-value --description "Sort statements by their values rather than their paths"
+complete -c gron      -l preorder   --description "Sort parents before children with siblings ordered by key"
+complete -c gron      -l sort-keys-only --description "Same as --preorder"
+complete -c gron      -l float-format --description "Format numbers with a float verb like %.2f (display only)" -x
+complete -c gron      -l truncate   --description "Cut string values longer than this many characters short (display only)" -x
+complete -c gron      -l deterministic --description "Sorted, monochrome output with normalized numbers (for golden files)"
+complete -c gron      -l infer-types --description "With --ungron, read 'key.path = value' lines and infer value types"
+complete -c gron      -l from-columns --description "With --ungron, read path and value columns" -x -a "tsv csv"
+complete -c gron      -l form       --description "With --ungron, read a form or query string like a=1&b[0]=x"
+complete -c gron      -l strict     --description "With --ungron, fail if two statements assign different values to the same path"
+complete -c gron      -l split      --description "With --ungron, treat blank lines as separators between documents"
+complete -c gron      -l split-keys --description "With --ungron, write each top-level key's value as a separate document"
+complete -c gron      -l split-on   --description "With --ungron, treat lines matching a regex as separators between documents" -x
+complete -c gron      -l indent     --description "With --ungron or --schema, indent JSON with a number of spaces, 'tab' or whitespace" -x
+complete -c gron -s C -l compact    --description "With --ungron or --schema, write JSON on one line"
+complete -c gron      -l to-ndjson  --description "With --ungron, write each element of a top-level array as a line of JSON"
+complete -c gron      -l merge      --description "Gron all of the inputs together, as the elements of an array"
+complete -c gron      -l named      --description "Gron all of the inputs together, named after their files"
+complete -c gron      -l keep-going --description "Carry on with the remaining inputs when one of them fails"
+complete -c gron      -l max-memory --description "Refuse input estimated to need more memory than this, unless it's an array" -x
+complete -c gron      -l max-input  --description "Refuse input larger than this, e.g. 10M" -x
+complete -c gron      -l max-line-size --description "Maximum length of an input line in bytes for --stream and --ungron" -x
+complete -c gron      -l max-line-bytes --description "Same as --max-line-size" -x
+complete -c gron      -l max-array-index --description "Refuse to ungron array indexes bigger than this" -x
+complete -c gron      -l canonical  --description "Write the same output for any documents that are equal as JSON"
+complete -c gron      -l hash       --description "Print a SHA-256 hash of the canonical output"
+complete -c gron      -l keys-case  --description "Convert object keys to another case" -x -a "snake camel lower upper"
+complete -c gron      -l dup-keys   --description "What to do about a key repeated in an object" -x -a "last warn error"
+complete -c gron      -l jsonpath   --description "Write paths in JSONPath notation"
+complete -c gron      -l root       --description "Start every statement with this name rather than 'json'" -x
+complete -c gron      -l namespace  --description "Insert dot-separated keys after the top-level 'json'" -x
+complete -c gron      -l depth      --description "Only output statements this many levels deep" -x
+complete -c gron -s p -l prefix     --description "Only output statements with paths starting with a path" -x
+complete -c gron      -l glob       --description "Only output statements with paths matching a glob" -x
+complete -c gron -s i -l ignore-case --description "Match --glob patterns regardless of case"
+complete -c gron -s S -l smart-case --description "Match --glob patterns regardless of case unless they contain uppercase"
+complete -c gron      -l glob-exclude --description "Don't output statements with paths matching a glob" -x
+complete -c gron      -l match      --description "Only output statements with values matching a regex" -x
+complete -c gron      -l invert-match --description "With --match, only output statements with values that don't match"
+complete -c gron      -l parents    --description "With --glob, also output the containers that matching statements are in"
+complete -c gron      -l select-index --description "Only output these elements of a top-level array; e.g. 0,2,5" -x
+complete -c gron      -l reindex    --description "With --select-index, number the selected elements from zero"
+complete -c gron      -l sample-rate --description "Only output a random sample of statements (lossy)" -x
+complete -c gron      -l seed       --description "Seed for --sample-rate, for a reproducible sample" -x
+complete -c gron      -l highlight  --description "Color values matching a regex, as PATTERN:COLOR (repeatable)" -x
+complete -c gron      -l redact     --description "Replace values with paths matching a regex with \"***\"" -r
+complete -c gron      -l error-format --description "Write errors as text or as a line of JSON" -x -a "text json"
+complete -c gron -s v -l verbose    --description "Print a summary of statements, bytes read and time taken to stderr"
 complete -c gron      -l version    --description "Print version information"
 
 # eof
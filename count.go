@@ -0,0 +1,234 @@
+package gron
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// AbsentValue is the bucket used by CountBy for
+// records that do not have the counted field
+const AbsentValue = "(absent)"
+
+// CountBy returns an action that tallies the values of a field
+// across a set of records, printing a frequency table. Records
+// are the elements of a top-level array, or each value in a
+// stream of JSON values (e.g. one object per line). Records that
+// aren't objects, or that don't have the field, are counted as
+// AbsentValue. Values are told apart by type, so the string "1"
+// and the number 1 are counted separately; as are a string
+// "(absent)" and records without the field. Objects and arrays
+// are only told apart from other values, not from each other, so
+// they're all counted as {} or [] whatever they contain.
+//
+// Each line of output is the value followed by its count, most
+// common first. Strings are written without quotes, unless that
+// would make them look like another value; e.g. "1" or "(absent)".
+// With OptJSON each line is instead a JSON array of the value and
+// count; e.g. ["active",120] or [1,3]. Records without the field
+// are written as null with a third element, "absent", to tell
+// them from a null value; i.e. [null,2,"absent"].
+func CountBy(field string) ActionFn {
+	return func(r io.Reader, w io.Writer, opts int, options ...Option) (code int, err error) {
+		c := newConfig(options)
+		r = c.limitInput(r)
+		defer c.checkInputSize(&code, &err)
+
+		// Values are counted by their JSON; e.g. "1" for the string
+		// and 1 for the number. No JSON is empty, so an empty key is
+		// the count of records without the field
+		counts := make(map[string]int)
+
+		add := func(record interface{}) {
+			obj, ok := record.(map[string]interface{})
+			if !ok {
+				counts[""]++
+				return
+			}
+			v, exists := obj[field]
+			if !exists {
+				counts[""]++
+				return
+			}
+			counts[valueTokenFromInterface(v).text]++
+		}
+
+		d := json.NewDecoder(r)
+		d.UseNumber()
+		for {
+			var v interface{}
+			err := d.Decode(&v)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return ExitFormStatements, fmt.Errorf("failed to read records: %s", err)
+			}
+
+			if records, ok := v.([]interface{}); ok {
+				for _, record := range records {
+					add(record)
+				}
+				continue
+			}
+			add(v)
+		}
+
+		ts := make(tallies, 0, len(counts))
+		for v, n := range counts {
+			ts = append(ts, tally{v, n})
+		}
+		sort.Sort(ts)
+
+		for _, t := range ts {
+			if opts&OptJSON > 0 {
+				if t.value == "" {
+					fmt.Fprintf(w, "[null,%d,\"absent\"]\n", t.count)
+					continue
+				}
+				fmt.Fprintf(w, "[%s,%d]\n", t.value, t.count)
+				continue
+			}
+
+			// Strings are written without their quotes, unless
+			// they'd look like another value that way; e.g. "1"
+			label := t.value
+			var str string
+			switch {
+			case label == "":
+				label = AbsentValue
+			case label[0] == '"' && json.Unmarshal([]byte(label), &str) == nil:
+				if str != AbsentValue && !json.Valid([]byte(str)) {
+					label = str
+				}
+			}
+			fmt.Fprintf(w, "%s %d\n", label, t.count)
+		}
+
+		return ExitOK, nil
+	}
+}
+
+// Count is an action that tallies the statements the gron action would
+// write by the kind of value they assign (objects, arrays, strings,
+// numbers, bools and nulls) and writes a summary rather than the
+// statements; e.g.
+//
+//   objects 3
+//   arrays 1
+//   strings 2
+//   numbers 4
+//   bools 1
+//   nulls 0
+//   total 11
+//
+// Filters like WithGlob apply, but OptInlineScalarArrays is ignored so
+// that every leaf is counted. With OptJSON the summary is instead a JSON
+// object on one line.
+func Count(r io.Reader, w io.Writer, opts int, options ...Option) (int, error) {
+	return countStatements(Gron, r, w, opts, options)
+}
+
+// CountStream is like Count, but for the statements that GronStream
+// would write; the summary is a grand total for every line of input
+func CountStream(r io.Reader, w io.Writer, opts int, options ...Option) (int, error) {
+	return countStatements(GronStream, r, w, opts, options)
+}
+
+// countStatements runs a gron action with a statement
+// sink that counts the statements, and writes the summary
+func countStatements(action ActionFn, r io.Reader, w io.Writer, opts int, options []Option) (int, error) {
+	var counts statementCounts
+	options = append(options[:len(options):len(options)], WithStatementSink(counts.add))
+
+	code, err := action(r, ioutil.Discard, opts&^OptInlineScalarArrays, options...)
+	if err != nil {
+		return code, err
+	}
+	counts.write(w, opts)
+	return ExitOK, nil
+}
+
+// statementCounts is the number of statements
+// that assign each kind of value
+type statementCounts struct {
+	objects, arrays, strings, numbers, bools, nulls int
+}
+
+// add counts a statement by its value
+func (sc *statementCounts) add(s Statement) {
+	v, ok := s.tokens.value()
+	if !ok {
+		return
+	}
+	switch v.typ {
+	case typEmptyObject:
+		sc.objects++
+	case typEmptyArray:
+		sc.arrays++
+	case typString:
+		sc.strings++
+	case typNumber:
+		sc.numbers++
+	case typTrue, typFalse:
+		sc.bools++
+	case typNull:
+		sc.nulls++
+	}
+}
+
+// write writes the summary to w, as JSON if OptJSON is set
+func (sc statementCounts) write(w io.Writer, opts int) {
+	rows := []tally{
+		{"objects", sc.objects},
+		{"arrays", sc.arrays},
+		{"strings", sc.strings},
+		{"numbers", sc.numbers},
+		{"bools", sc.bools},
+		{"nulls", sc.nulls},
+		{"total", sc.objects + sc.arrays + sc.strings + sc.numbers + sc.bools + sc.nulls},
+	}
+
+	if opts&OptJSON > 0 {
+		fields := make([]string, 0, len(rows))
+		for _, t := range rows {
+			fields = append(fields, fmt.Sprintf("%s:%d", quoteString(t.value), t.count))
+		}
+		fmt.Fprintf(w, "{%s}\n", strings.Join(fields, ","))
+		return
+	}
+	for _, t := range rows {
+		fmt.Fprintf(w, "%s %d\n", t.value, t.count)
+	}
+}
+
+// a tally is the number of times a value has been seen
+type tally struct {
+	value string
+	count int
+}
+
+// tallies is a list of tallies, sorted most common first
+type tallies []tally
+
+// Len returns the number of tallies for sort.Sort
+func (ts tallies) Len() int {
+	return len(ts)
+}
+
+// Swap swaps two tallies for sort.Sort
+func (ts tallies) Swap(i, j int) {
+	ts[i], ts[j] = ts[j], ts[i]
+}
+
+// Less compares two tallies for sort.Sort; higher counts
+// come first, with ties broken by value
+func (ts tallies) Less(i, j int) bool {
+	if ts[i].count != ts[j].count {
+		return ts[i].count > ts[j].count
+	}
+	return ts[i].value < ts[j].value
+}
@@ -0,0 +1,96 @@
+package gron
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCountBy(t *testing.T) {
+	cases := []struct {
+		in   string
+		opts int
+		want string
+	}{
+		{
+			`[{"status": "active"}, {"status": "inactive"}, {"status": "active"}, {"name": "x"}, 4]`,
+			OptMonochrome,
+			"(absent) 2\nactive 2\ninactive 1\n",
+		},
+		{
+			"{\"status\": 1}\n{\"status\": 1}\n{\"status\": true}\n{\"status\": null}\n",
+			OptMonochrome,
+			"1 2\nnull 1\ntrue 1\n",
+		},
+		{
+			`[{"status": "a \"b\""}, {}]`,
+			OptMonochrome | OptJSON,
+			"[null,1,\"absent\"]\n[\"a \\\"b\\\"\",1]\n",
+		},
+		{
+			`[{"status": 1}, {"status": "1"}, {"status": "1"}, {"status": true}, {"status": "true"}, {"status": null}, {"status": "(absent)"}, {}]`,
+			OptMonochrome,
+			"\"1\" 2\n(absent) 1\n\"(absent)\" 1\n\"true\" 1\n1 1\nnull 1\ntrue 1\n",
+		},
+		{
+			`[{"status": 1}, {"status": "1"}, {"status": "1"}, {"status": true}, {"status": "true"}, {"status": null}, {"status": "(absent)"}, {}]`,
+			OptMonochrome | OptJSON,
+			"[\"1\",2]\n[null,1,\"absent\"]\n[\"(absent)\",1]\n[\"true\",1]\n[1,1]\n[null,1]\n[true,1]\n",
+		},
+		{
+			`[{"status": {"a": 1}}, {"status": {"b": 2}}, {"status": []}, {"status": [1]}]`,
+			OptMonochrome,
+			"[] 2\n{} 2\n",
+		},
+	}
+
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		code, err := CountBy("status")(strings.NewReader(c.in), out, c.opts)
+
+		if code != ExitOK {
+			t.Errorf("want ExitOK; have %d", code)
+		}
+		if err != nil {
+			t.Errorf("want nil error; have %s", err)
+		}
+		if out.String() != c.want {
+			t.Errorf("want %q for input %s; have %q", c.want, c.in, out.String())
+		}
+	}
+}
+
+func TestCount(t *testing.T) {
+	in := `{"users": [{"name": "Tom", "age": 40, "admin": true}, {"name": "Bob", "tags": [1, 2], "boss": null}]}`
+
+	out := &bytes.Buffer{}
+	code, err := Count(strings.NewReader(in), out, OptInlineScalarArrays)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	want := "objects 3\narrays 2\nstrings 2\nnumbers 3\nbools 1\nnulls 1\ntotal 12\n"
+	if out.String() != want {
+		t.Errorf("want %q; have %q", want, out.String())
+	}
+
+	out.Reset()
+	Count(strings.NewReader(in), out, OptJSON, WithGlob("json.users[*].name"))
+	want = `{"objects":0,"arrays":0,"strings":2,"numbers":0,"bools":0,"nulls":0,"total":2}` + "\n"
+	if out.String() != want {
+		t.Errorf("want %q; have %q", want, out.String())
+	}
+}
+
+func TestCountStream(t *testing.T) {
+	in := "{\"a\": 1}\n{\"a\": \"x\"}\n"
+
+	out := &bytes.Buffer{}
+	code, err := CountStream(strings.NewReader(in), out, 0)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	want := "objects 2\narrays 1\nstrings 1\nnumbers 1\nbools 0\nnulls 0\ntotal 5\n"
+	if out.String() != want {
+		t.Errorf("want %q; have %q", want, out.String())
+	}
+}
@@ -0,0 +1,120 @@
+package gron
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A Shell is a dialect of shell that environment
+// variable assignments can be written for
+type Shell string
+
+// Supported shells
+const (
+	ShellPOSIX      Shell = "posix"
+	ShellFish       Shell = "fish"
+	ShellPowerShell Shell = "powershell"
+)
+
+// Shells is the list of supported shells
+var Shells = []Shell{ShellPOSIX, ShellFish, ShellPowerShell}
+
+// WithShell sets the shell that environment variable
+// assignments are written for; the default is ShellPOSIX
+func WithShell(sh Shell) Option {
+	return func(c *config) {
+		c.shell = sh
+	}
+}
+
+// export returns a command that sets and exports an environment
+// variable in the shell, with the value quoted so that it's taken
+// literally; spaces, quotes, newlines and all
+func (sh Shell) export(name, value string) string {
+	switch sh {
+	case ShellFish:
+		// Only backslashes and single quotes are special
+		// inside single quotes in fish
+		r := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+		return "set -x " + name + " '" + r.Replace(value) + "'"
+
+	case ShellPowerShell:
+		// Single quotes in a single-quoted string are doubled up;
+		// including the 'smart' quotes PowerShell also accepts
+		r := strings.NewReplacer(`'`, `''`, "‘", "‘‘", "’", "’’", "‚", "‚‚", "‛", "‛‛")
+		return "$env:" + name + " = '" + r.Replace(value) + "'"
+
+	default:
+		// Nothing is special inside single quotes in POSIX shells, so
+		// a single quote has to end the quoting, be escaped, and then
+		// start the quoting again
+		return "export " + name + "='" + strings.Replace(value, `'`, `'\''`, -1) + "'"
+	}
+}
+
+// envVar returns the name of the environment variable for an
+// assignment statement when writing with OptEnv, along with its value;
+// or false if the statement's value is an object or array. The name is
+// the path in upper case, with an underscore before each key and index
+// and in place of anything that isn't a letter, digit or underscore;
+// e.g. json.database.hosts[0] becomes JSON_DATABASE_HOSTS_0. Strings are
+// unquoted, null is empty, and anything else is written as it is.
+func (s statement) envVar() (string, string, bool) {
+	v, ok := s.value()
+	if !ok || v.typ == typEmptyObject || v.typ == typEmptyArray {
+		return "", "", false
+	}
+
+	keys := []string{s[0].text}
+	for _, k := range s.pathKeys() {
+		if k.isIndex {
+			keys = append(keys, strconv.Itoa(k.index))
+			continue
+		}
+		keys = append(keys, k.key)
+	}
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		default:
+			return '_'
+		}
+	}, strings.Join(keys, "_"))
+
+	switch v.typ {
+	case typString:
+		var str string
+		if err := json.Unmarshal([]byte(v.text), &str); err == nil {
+			return name, str, true
+		}
+	case typNull:
+		return name, "", true
+	}
+	return name, v.text, true
+}
+
+// writeEnv writes an assignment statement as an environment variable
+// for the configured shell, warning if an earlier statement's path
+// became the same variable name
+func (c *config) writeEnv(w io.Writer, s statement) {
+	name, value, ok := s.envVar()
+	if !ok {
+		return
+	}
+
+	path := s.path().String()
+	if c.envNames == nil {
+		c.envNames = make(map[string]string)
+	}
+	if was, exists := c.envNames[name]; exists && was != path {
+		c.warnf("%s and %s both become the environment variable %s", was, path, name)
+	}
+	c.envNames[name] = path
+
+	c.writeLine(w, c.shell.export(name, value))
+}
@@ -0,0 +1,80 @@
+package gron
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestShellExport(t *testing.T) {
+	cases := []struct {
+		shell Shell
+		value string
+		want  string
+	}{
+		{ShellPOSIX, "plain", `export JSON_A='plain'`},
+		{ShellPOSIX, "with space", `export JSON_A='with space'`},
+		{ShellPOSIX, `it's "quoted"`, `export JSON_A='it'\''s "quoted"'`},
+		{ShellPOSIX, "two\nlines", "export JSON_A='two\nlines'"},
+		{ShellPOSIX, `$HOME \n`, `export JSON_A='$HOME \n'`},
+
+		{ShellFish, "plain", `set -x JSON_A 'plain'`},
+		{ShellFish, "with space", `set -x JSON_A 'with space'`},
+		{ShellFish, `it's "quoted"`, `set -x JSON_A 'it\'s "quoted"'`},
+		{ShellFish, "two\nlines", "set -x JSON_A 'two\nlines'"},
+		{ShellFish, `$HOME \n`, `set -x JSON_A '$HOME \\n'`},
+
+		{ShellPowerShell, "plain", `$env:JSON_A = 'plain'`},
+		{ShellPowerShell, "with space", `$env:JSON_A = 'with space'`},
+		{ShellPowerShell, `it's "quoted"`, `$env:JSON_A = 'it''s "quoted"'`},
+		{ShellPowerShell, "it’s", "$env:JSON_A = 'it’’s'"},
+		{ShellPowerShell, "two\nlines", "$env:JSON_A = 'two\nlines'"},
+		{ShellPowerShell, `$HOME \n`, `$env:JSON_A = '$HOME \n'`},
+	}
+
+	for _, c := range cases {
+		have := c.shell.export("JSON_A", c.value)
+		if have != c.want {
+			t.Errorf("want %s for %q in %s; have %s", c.want, c.value, c.shell, have)
+		}
+	}
+}
+
+func TestGronEnv(t *testing.T) {
+	in := `{"database": {"host": "localhost", "port": 5432, "it's": "a \"b\""}, "tags": ["x", null], "on": true, "a-b": 1, "a_b": 2}`
+
+	var warnings []string
+	warn := WithWarnings(func(msg string) {
+		warnings = append(warnings, msg)
+	})
+
+	out := &bytes.Buffer{}
+	code, err := Gron(strings.NewReader(in), out, OptMonochrome|OptEnv, warn)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+
+	want := `export JSON_A_B='2'
+export JSON_DATABASE_HOST='localhost'
+export JSON_DATABASE_PORT='5432'
+export JSON_DATABASE_IT_S='a "b"'
+export JSON_ON='true'
+export JSON_TAGS_0='x'
+export JSON_TAGS_1=''
+export JSON_A_B='1'
+`
+	if out.String() != want {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", out.String())
+		t.Errorf("env output does not match")
+	}
+	if len(warnings) != 1 || warnings[0] != `json.a_b and json["a-b"] both become the environment variable JSON_A_B` {
+		t.Errorf("want a warning about JSON_A_B; have %q", warnings)
+	}
+
+	out.Reset()
+	Gron(strings.NewReader(`{"a": "b"}`), out, OptMonochrome|OptEnv, WithShell(ShellFish))
+	if out.String() != "set -x JSON_A 'b'\n" {
+		t.Errorf("want fish output; have %q", out.String())
+	}
+}
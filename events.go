@@ -0,0 +1,88 @@
+package gron
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// EventsVersion is the version of the event schema written with OptEvents.
+// It will be incremented if the schema ever changes incompatibly.
+//
+// With OptEvents each statement is written as a single line of JSON:
+//
+//   {"v":1,"path":[{"key":"users","isIndex":false},{"key":"0","isIndex":true}],"type":"string","value":"Tom"}
+//
+// Where:
+//
+//   v     is EventsVersion
+//   path  is the list of object keys and array indexes leading to the
+//         value, not including the top-level 'json'. isIndex is true for
+//         array indexes, for which key is the index in decimal
+//   type  is one of object, array, string, number, bool or null
+//   value is the value; {} and [] for objects and arrays
+const EventsVersion = 1
+
+// A Segment is a single object key or array index in a statement's path
+type Segment struct {
+	Key     string `json:"key"`
+	IsIndex bool   `json:"isIndex"`
+}
+
+// Segments returns the object keys and array indexes in a Statement's
+// path, not including the top-level 'json'
+func (s Statement) Segments() []Segment {
+	path := s.tokens.path()
+	segments := make([]Segment, 0, len(path)/2)
+	for i, t := range path {
+		if i == 0 {
+			continue
+		}
+		switch t.typ {
+		case typBare, typQuotedKey:
+			segments = append(segments, Segment{Key: t.key()})
+		case typNumericKey:
+			segments = append(segments, Segment{Key: t.text, IsIndex: true})
+		}
+	}
+	return segments
+}
+
+// an event is the form a statement is written in with OptEvents
+type event struct {
+	Version int             `json:"v"`
+	Path    []Segment       `json:"path"`
+	Type    string          `json:"type"`
+	Value   json.RawMessage `json:"value"`
+}
+
+// eventTypes maps value token types to event types
+var eventTypes = map[tokenTyp]string{
+	typEmptyObject: "object",
+	typEmptyArray:  "array",
+	typInlineArray: "array",
+	typString:      "string",
+	typNumber:      "number",
+	typTrue:        "bool",
+	typFalse:       "bool",
+	typNull:        "null",
+}
+
+// statementToEvent converts a statement to a line of JSON
+// conforming to the event schema described for EventsVersion
+func statementToEvent(s statement) (string, error) {
+	v, _ := s.value()
+	e := event{
+		Version: EventsVersion,
+		Path:    Statement{tokens: s}.Segments(),
+		Type:    eventTypes[v.typ],
+		Value:   json.RawMessage(v.text),
+	}
+
+	out := &bytes.Buffer{}
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(e); err != nil {
+		return "", err
+	}
+	return string(bytes.TrimRight(out.Bytes(), "\n")), nil
+}
@@ -0,0 +1,33 @@
+package gron
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGronEvents(t *testing.T) {
+	in := `{"users": [{"name": "Tom", "<id>": 1}], "0": null}`
+
+	out := &bytes.Buffer{}
+	code, err := Gron(strings.NewReader(in), out, OptMonochrome|OptEvents)
+	if code != ExitOK {
+		t.Errorf("want ExitOK; have %d", code)
+	}
+	if err != nil {
+		t.Errorf("want nil error; have %s", err)
+	}
+
+	want := `{"v":1,"path":[],"type":"object","value":{}}
+{"v":1,"path":[{"key":"users","isIndex":false}],"type":"array","value":[]}
+{"v":1,"path":[{"key":"users","isIndex":false},{"key":"0","isIndex":true}],"type":"object","value":{}}
+{"v":1,"path":[{"key":"users","isIndex":false},{"key":"0","isIndex":true},{"key":"name","isIndex":false}],"type":"string","value":"Tom"}
+{"v":1,"path":[{"key":"users","isIndex":false},{"key":"0","isIndex":true},{"key":"<id>","isIndex":false}],"type":"number","value":1}
+{"v":1,"path":[{"key":"0","isIndex":false}],"type":"null","value":null}
+`
+	if out.String() != want {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", out.String())
+		t.Errorf("events output does not match")
+	}
+}
@@ -0,0 +1,321 @@
+package gron
+
+import (
+	"math/rand"
+	"regexp"
+	"strings"
+)
+
+// WithGlob limits output to statements whose path (e.g. json.users[0].name)
+// matches at least one of the provided shell-style patterns. Patterns can
+// contain:
+//
+//	?      any single character within a key or index
+//	*      any run of characters within a key or index; e.g. json.users[*]
+//	**     any run of characters at all; e.g. json.users.** for descendants
+//	{a,b}  either a or b; e.g. json.users[*].{name,email}
+//
+// Any other character, including '[', ']' and '.', matches itself.
+func WithGlob(patterns ...string) Option {
+	return func(c *config) {
+		c.includeGlobs = append(c.includeGlobs, patterns...)
+	}
+}
+
+// WithGlobExclude removes statements whose path matches at least one of
+// the provided patterns from the output, even if they match a pattern
+// given with WithGlob. The pattern syntax is the same as for WithGlob.
+func WithGlobExclude(patterns ...string) Option {
+	return func(c *config) {
+		c.excludeGlobs = append(c.excludeGlobs, patterns...)
+	}
+}
+
+// WithValueMatch limits output to statements whose value, as it's
+// written (e.g. "Tom" with the quotes, or 42), matches the provided
+// regexp; or with invert, to those whose value doesn't match it. Only
+// leaf values are matched: the statements for objects and arrays are
+// kept for anything inside them that matches, so the output ungrons.
+func WithValueMatch(re *regexp.Regexp, invert bool) Option {
+	return func(c *config) {
+		c.valueMatch = re
+		c.invertMatch = invert
+	}
+}
+
+// WithPathPrefix limits output to the statement with the provided path
+// (e.g. json.users[3]) and the statements for everything inside it; so
+// the output for a subtree still ungrons. The prefix is compared key by
+// key, so json.user doesn't match json.users and json["users"] matches
+// json.users. A prefix that isn't a valid path matches nothing.
+func WithPathPrefix(prefix string) Option {
+	p := statementFromString(prefix)
+	for _, t := range p {
+		if t.typ == typError || t.typ == typIgnored {
+			p = statement{{"", typError}}
+			break
+		}
+	}
+	if len(p) == 0 || p[0].typ != typBare {
+		p = statement{{"", typError}}
+	}
+
+	return func(c *config) {
+		c.pathPrefix = p
+		c.pathPrefixKeys = p.pathKeys()
+	}
+}
+
+// underPathPrefix returns true if a path is the path prefix
+// or the path to something inside it
+func (c *config) underPathPrefix(path statement) bool {
+	if len(path) == 0 || path[0] != c.pathPrefix[0] {
+		return false
+	}
+	keys := path.pathKeys()
+	if len(keys) < len(c.pathPrefixKeys) {
+		return false
+	}
+	for i, k := range c.pathPrefixKeys {
+		if keys[i] != k {
+			return false
+		}
+	}
+	return true
+}
+
+// A CaseMode decides whether glob patterns are case sensitive
+type CaseMode int
+
+// Case modes
+const (
+	// CaseSensitive patterns only match paths with the same case
+	CaseSensitive CaseMode = iota
+
+	// IgnoreCase patterns match paths regardless of case
+	IgnoreCase
+
+	// SmartCase patterns match regardless of case if they don't contain
+	// any uppercase letters, and are case sensitive if they do; decided
+	// separately for each pattern, as ripgrep does. E.g. json.users[*].id
+	// matches json.Users[0].ID but json.Users[*].id doesn't
+	SmartCase
+)
+
+// WithCaseMode sets whether the patterns given with WithGlob and
+// WithGlobExclude are case sensitive; the default is CaseSensitive
+func WithCaseMode(m CaseMode) Option {
+	return func(c *config) {
+		c.caseMode = m
+	}
+}
+
+// compileGlobs converts the glob patterns into regexps,
+// once all of the Options that affect them are known
+func (c *config) compileGlobs() {
+	compile := func(globs []string) []*regexp.Regexp {
+		res := make([]*regexp.Regexp, 0, len(globs))
+		for _, g := range globs {
+			ignoreCase := c.caseMode == IgnoreCase ||
+				(c.caseMode == SmartCase && strings.ToLower(g) == g)
+			res = append(res, globToRegexp(g, ignoreCase))
+		}
+		return res
+	}
+	c.includePaths = compile(c.includeGlobs)
+	c.excludePaths = compile(c.excludeGlobs)
+}
+
+// WithSample limits output to a random sample of statements, each of
+// which is kept with the provided probability (e.g. 0.01 for roughly 1%).
+// Using the same seed gives the same sample for the same input.
+//
+// Sampling is meant for getting a feel for a large document cheaply. It
+// is lossy: parent statements may be dropped while their children are
+// kept, so the output is not guaranteed to ungron.
+func WithSample(rate float64, seed int64) Option {
+	return func(c *config) {
+		c.sampleRate = rate
+		c.sampleRand = rand.New(rand.NewSource(seed))
+	}
+}
+
+// included returns true if a statement passes all of the filters
+func (c *config) included(s statement) bool {
+	if c.pathPrefix != nil && !c.underPathPrefix(s.path()) {
+		return false
+	}
+	if len(c.includePaths) > 0 || len(c.excludePaths) > 0 {
+		path := s.path().String()
+		if len(c.includePaths) > 0 && !matchAny(c.includePaths, path) {
+			return false
+		}
+		if matchAny(c.excludePaths, path) {
+			return false
+		}
+	}
+	if c.valueMatch != nil {
+		v, ok := s.value()
+		if !ok || v.typ == typEmptyObject || v.typ == typEmptyArray {
+			return false
+		}
+		if c.valueMatch.MatchString(v.text) == c.invertMatch {
+			return false
+		}
+	}
+
+	// Sampling comes last so that the rate applies
+	// to the statements that passed the other filters
+	if c.sampleRand != nil {
+		return c.sampleRand.Float64() < c.sampleRate
+	}
+	return true
+}
+
+// filter returns the statements that pass all of the filters, in the
+// same order; along with the container statements for their ancestors
+// if OptParents is set or values are being matched
+func (c *config) filter(ss statements) statements {
+	if len(c.includePaths) == 0 && len(c.excludePaths) == 0 && c.sampleRand == nil && c.pathPrefix == nil && c.valueMatch == nil {
+		return ss
+	}
+
+	parents := c.parents || c.valueMatch != nil
+	keep := make([]bool, len(ss))
+	var containers map[string]int
+	if parents {
+		containers = make(map[string]int)
+		for i, s := range ss {
+			if v, ok := s.value(); ok && (v.typ == typEmptyObject || v.typ == typEmptyArray) {
+				containers[s.path().String()] = i
+			}
+		}
+	}
+
+	for i, s := range ss {
+		if !c.included(s) {
+			continue
+		}
+		keep[i] = true
+		if !parents {
+			continue
+		}
+
+		// Every prefix of the path that ends with a
+		// key or an index is the path to an ancestor
+		path := s.path()
+		for j := 1; j < len(path); j++ {
+			if path[j-1].typ != typBare && path[j-1].typ != typRBrace {
+				continue
+			}
+			if k, ok := containers[path[:j].String()]; ok {
+				keep[k] = true
+			}
+		}
+	}
+
+	out := make(statements, 0, len(ss))
+	for i, s := range ss {
+		if keep[i] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// matchAny returns true if the string matches any of the regexps
+func matchAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// globToRegexp converts a glob pattern into an anchored regexp
+// that matches the same strings; see WithGlob for the syntax
+func globToRegexp(glob string, ignoreCase bool) *regexp.Regexp {
+	alts := expandBraces(glob)
+	out := make([]string, 0, len(alts))
+
+	for _, alt := range alts {
+		var re string
+		for i := 0; i < len(alt); i++ {
+			switch {
+			case strings.HasPrefix(alt[i:], "**"):
+				re += ".*"
+				i++
+			case alt[i] == '*':
+				re += `[^.\[\]]*`
+			case alt[i] == '?':
+				re += `[^.\[\]]`
+			default:
+				re += regexp.QuoteMeta(alt[i : i+1])
+			}
+		}
+		out = append(out, re)
+	}
+
+	flags := ""
+	if ignoreCase {
+		flags = "(?i)"
+	}
+	return regexp.MustCompile(flags + "^(?:" + strings.Join(out, "|") + ")$")
+}
+
+// expandBraces expands the first (and, recursively, every) brace
+// alternation in a pattern; e.g. a.{b,c}.{d,e} becomes a.b.d, a.b.e,
+// a.c.d and a.c.e. Braces can be nested. Unbalanced braces, and braces
+// without a comma in them, are left as they are.
+func expandBraces(p string) []string {
+	open := -1
+	depth := 0
+	var commas []int
+
+	for i, r := range p {
+		switch r {
+		case '{':
+			if depth == 0 {
+				open = i
+				commas = commas[:0]
+			}
+			depth++
+		case ',':
+			if depth == 1 {
+				commas = append(commas, i)
+			}
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth > 0 {
+				continue
+			}
+			if len(commas) == 0 {
+				// Nothing to expand here, but there might be
+				// alternations later in the pattern
+				rest := expandBraces(p[i+1:])
+				out := make([]string, 0, len(rest))
+				for _, r := range rest {
+					out = append(out, p[:i+1]+r)
+				}
+				return out
+			}
+
+			prefix, suffix := p[:open], p[i+1:]
+			var out []string
+			start := open + 1
+			for _, end := range append(commas, i) {
+				for _, alt := range expandBraces(prefix + p[start:end] + suffix) {
+					out = append(out, alt)
+				}
+				start = end + 1
+			}
+			return out
+		}
+	}
+
+	return []string{p}
+}
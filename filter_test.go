@@ -0,0 +1,272 @@
+package gron
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestExpandBraces(t *testing.T) {
+	cases := []struct {
+		in   string
+		want []string
+	}{
+		{`json.a`, []string{`json.a`}},
+		{`json.{a,b}`, []string{`json.a`, `json.b`}},
+		{`json.{a,b}.{c,d}`, []string{`json.a.c`, `json.a.d`, `json.b.c`, `json.b.d`}},
+		{`json.{a,b{c,d}}`, []string{`json.a`, `json.bc`, `json.bd`}},
+		{`json.{a}.{b,c}`, []string{`json.{a}.b`, `json.{a}.c`}},
+		{`json.{a,b`, []string{`json.{a,b`}},
+		{`json.{,s}`, []string{`json.`, `json.s`}},
+	}
+
+	for _, c := range cases {
+		have := expandBraces(c.in)
+		if !reflect.DeepEqual(have, c.want) {
+			t.Errorf("want expandBraces(%s) to be %#v; have %#v", c.in, c.want, have)
+		}
+	}
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	cases := []struct {
+		glob  string
+		path  string
+		match bool
+	}{
+		{`json.users[*].{name,email}`, `json.users[0].name`, true},
+		{`json.users[*].{name,email}`, `json.users[12].email`, true},
+		{`json.users[*].{name,email}`, `json.users[12].id`, false},
+		{`json.users[*].{name,email}`, `json.users[0].name.first`, false},
+		{`json.users[*]`, `json.users[3]`, true},
+		{`json.users[*]`, `json.users`, false},
+		{`json.*`, `json.a`, true},
+		{`json.*`, `json.a.b`, false},
+		{`json.**`, `json.a.b[0]`, true},
+		{`json.user?`, `json.users`, true},
+		{`json.user?`, `json.user`, false},
+		{`json["a key"]`, `json["a key"]`, true},
+		{`json.a+b`, `json.aab`, false},
+	}
+
+	for _, c := range cases {
+		have := globToRegexp(c.glob, false).MatchString(c.path)
+		if have != c.match {
+			t.Errorf("want match of %s against %s to be %t; have %t", c.glob, c.path, c.match, have)
+		}
+	}
+}
+
+func TestGronGlob(t *testing.T) {
+	in := `{"users": [{"name": "Tom", "email": "t@example.com", "id": 1}, {"name": "Bob", "id": 2}]}`
+
+	out := &bytes.Buffer{}
+	code, err := Gron(
+		strings.NewReader(in), out, OptMonochrome,
+		WithGlob(`json.users[*].{name,email,id}`),
+		WithGlobExclude(`json.users[1].*`),
+	)
+	if code != ExitOK {
+		t.Errorf("want ExitOK; have %d", code)
+	}
+	if err != nil {
+		t.Errorf("want nil error; have %s", err)
+	}
+
+	want := `json.users[0].email = "t@example.com";
+json.users[0].id = 1;
+json.users[0].name = "Tom";
+`
+	if out.String() != want {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", out.String())
+		t.Errorf("filtered output does not match")
+	}
+}
+
+func TestGronSample(t *testing.T) {
+	in := &bytes.Buffer{}
+	in.WriteString("[")
+	for i := 0; i < 1000; i++ {
+		if i > 0 {
+			in.WriteString(",")
+		}
+		fmt.Fprintf(in, "%d", i)
+	}
+	in.WriteString("]")
+
+	sample := func() string {
+		out := &bytes.Buffer{}
+		code, err := Gron(bytes.NewReader(in.Bytes()), out, OptMonochrome, WithSample(0.1, 42))
+		if code != ExitOK {
+			t.Errorf("want ExitOK; have %d", code)
+		}
+		if err != nil {
+			t.Errorf("want nil error; have %s", err)
+		}
+		return out.String()
+	}
+
+	first := sample()
+	n := strings.Count(first, "\n")
+	if n < 50 || n > 150 {
+		t.Errorf("want roughly 100 statements in a 10%% sample of 1001; have %d", n)
+	}
+
+	if second := sample(); second != first {
+		t.Errorf("want the same sample for the same seed")
+	}
+}
+
+func TestGronGlobParents(t *testing.T) {
+	in := `{"users": [{"name": "Tom", "tags": ["a"]}, {"name": "Bob"}], "total": 2}`
+
+	out := &bytes.Buffer{}
+	code, err := Gron(
+		strings.NewReader(in), out, OptMonochrome|OptParents,
+		WithGlob(`json.users[*].tags[*]`, `json.users[1].name`),
+	)
+	if code != ExitOK {
+		t.Errorf("want ExitOK; have %d", code)
+	}
+	if err != nil {
+		t.Errorf("want nil error; have %s", err)
+	}
+
+	want := `json = {};
+json.users = [];
+json.users[0] = {};
+json.users[0].tags = [];
+json.users[0].tags[0] = "a";
+json.users[1] = {};
+json.users[1].name = "Bob";
+`
+	if out.String() != want {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", out.String())
+		t.Fatalf("filtered output with parents does not match")
+	}
+
+	ungrond := &bytes.Buffer{}
+	code, err = Ungron(out, ungrond, OptMonochrome)
+	if code != ExitOK {
+		t.Errorf("want ExitOK; have %d", code)
+	}
+	if err != nil {
+		t.Errorf("want nil error; have %s", err)
+	}
+
+	var have, wantJSON interface{}
+	json.Unmarshal(ungrond.Bytes(), &have)
+	json.Unmarshal([]byte(`{"users": [{"tags": ["a"]}, {"name": "Bob"}]}`), &wantJSON)
+	if !reflect.DeepEqual(have, wantJSON) {
+		t.Errorf("want %#v; have %#v", wantJSON, have)
+	}
+}
+
+func TestGronGlobCase(t *testing.T) {
+	in := `{"Users": [{"ID": 1, "id": 2, "name": "Tom"}]}`
+
+	cases := []struct {
+		mode CaseMode
+		glob string
+		want string
+	}{
+		{CaseSensitive, `json.users[*].id`, ""},
+		{CaseSensitive, `json.Users[*].id`, "json.Users[0].id = 2;\n"},
+		{IgnoreCase, `json.users[*].id`, "json.Users[0].ID = 1;\njson.Users[0].id = 2;\n"},
+		{IgnoreCase, `json.Users[*].id`, "json.Users[0].ID = 1;\njson.Users[0].id = 2;\n"},
+		{SmartCase, `json.users[*].id`, "json.Users[0].ID = 1;\njson.Users[0].id = 2;\n"},
+		{SmartCase, `json.Users[*].id`, "json.Users[0].id = 2;\n"},
+		{SmartCase, `json.users[*].ID`, ""},
+	}
+
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		_, err := Gron(strings.NewReader(in), out, OptMonochrome, WithGlob(c.glob), WithCaseMode(c.mode))
+		if err != nil {
+			t.Errorf("want nil error; have %s", err)
+		}
+		if out.String() != c.want {
+			t.Errorf("want %q for %s in mode %d; have %q", c.want, c.glob, c.mode, out.String())
+		}
+	}
+}
+
+func TestGronPathPrefix(t *testing.T) {
+	in := `{"users": [{"name": "Tom"}, {"name": "Bob", "tags": ["a"]}], "usersTotal": 2}`
+
+	cases := []struct {
+		prefix string
+		want   string
+	}{
+		{`json.users[1]`, "json.users[1] = {};\njson.users[1].name = \"Bob\";\njson.users[1].tags = [];\njson.users[1].tags[0] = \"a\";\n"},
+		{`json["users"][1].tags`, "json.users[1].tags = [];\njson.users[1].tags[0] = \"a\";\n"},
+		{`json.usersTotal`, "json.usersTotal = 2;\n"},
+		{`json.user`, ""},
+		{`json.users[2]`, ""},
+		{`other.users`, ""},
+		{`json.`, ""},
+	}
+
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		_, err := Gron(strings.NewReader(in), out, OptMonochrome, WithPathPrefix(c.prefix))
+		if err != nil {
+			t.Errorf("want nil error; have %s", err)
+		}
+		if out.String() != c.want {
+			t.Errorf("want %q for prefix %s; have %q", c.want, c.prefix, out.String())
+		}
+	}
+}
+
+func TestGronValueMatch(t *testing.T) {
+	in := `{"users": [{"name": "Tom", "site": "https://tom.example"}, {"name": "Bob", "age": 40}], "total": 2}`
+
+	tests := []struct {
+		invert bool
+		want   string
+	}{
+		{false, `json = {};
+json.users = [];
+json.users[0] = {};
+json.users[0].site = "https://tom.example";
+`},
+		{true, `json = {};
+json.total = 2;
+json.users = [];
+json.users[0] = {};
+json.users[0].name = "Tom";
+json.users[1] = {};
+json.users[1].age = 40;
+json.users[1].name = "Bob";
+`},
+	}
+
+	for _, test := range tests {
+		out := &bytes.Buffer{}
+		code, err := Gron(
+			strings.NewReader(in), out, OptMonochrome,
+			WithValueMatch(regexp.MustCompile(`^"https?:`), test.invert),
+		)
+		if code != ExitOK || err != nil {
+			t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+		}
+		if out.String() != test.want {
+			t.Logf("want: %s", test.want)
+			t.Logf("have: %s", out.String())
+			t.Errorf("output matched with invert %t does not match", test.invert)
+		}
+
+		// The containers are kept, so it ungrons
+		code, err = Ungron(out, &bytes.Buffer{}, OptMonochrome)
+		if code != ExitOK || err != nil {
+			t.Errorf("want ExitOK and nil error from ungron; have %d and %v", code, err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package gron
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// statementsFromForm reads application/x-www-form-urlencoded input, like
+// a URL's query string, and returns the statements it represents. Keys
+// and values are percent-decoded, and brackets in keys nest the values
+// as they do for PHP and Rails:
+//
+//   a=1&b[0]=x&b[1]=y&c[d]=z&e[]=p&e[]=q
+//
+// gives json.a = "1"; json.b[0] = "x"; json.b[1] = "y"; json.c.d = "z";
+// json.e[0] = "p"; and json.e[1] = "q"; Values are always strings, as
+// the format has no types. Newlines separate pairs as & does.
+func statementsFromForm(r io.Reader) (statements, error) {
+	in, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each [] appends to the array at its path,
+	// so the next index for each one is kept
+	next := make(map[string]int)
+
+	var ss statements
+	pairs := strings.FieldsFunc(string(in), func(r rune) bool {
+		return r == '&' || r == '\n' || r == '\r'
+	})
+	for _, pair := range pairs {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		key, err := url.QueryUnescape(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid key `%s`: %s", parts[0], err)
+		}
+		value := ""
+		if len(parts) == 2 {
+			value, err = url.QueryUnescape(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid value `%s`: %s", parts[1], err)
+			}
+		}
+
+		path, err := formPath(key, next)
+		if err != nil {
+			return nil, err
+		}
+		ss.addWithValue(path, token{quoteString(value), typString})
+	}
+	return ss, nil
+}
+
+// formPath returns the path for a form key like a[b][0], with each
+// [] given the next index in next for the array at its path
+func formPath(key string, next map[string]int) (statement, error) {
+	name := key
+	rest := ""
+	if i := strings.IndexByte(key, '['); i >= 0 {
+		name, rest = key[:i], key[i:]
+	}
+	if name == "" {
+		return nil, fmt.Errorf("invalid key `%s`: no name before the brackets", key)
+	}
+
+	path := statement{{DefaultRoot, typBare}}.withKey(name)
+	for rest != "" {
+		end := strings.IndexByte(rest, ']')
+		if rest[0] != '[' || end < 0 {
+			return nil, fmt.Errorf("invalid key `%s`: unmatched brackets", key)
+		}
+		k := rest[1:end]
+		rest = rest[end+1:]
+
+		if k == "" {
+			p := path.String()
+			path = path.withNumericKey(next[p])
+			next[p]++
+			continue
+		}
+		if n, err := strconv.Atoi(k); err == nil && n >= 0 && strconv.Itoa(n) == k {
+			if n >= next[path.String()] {
+				next[path.String()] = n + 1
+			}
+			path = path.withNumericKey(n)
+			continue
+		}
+		path = path.withKey(k)
+	}
+	return path, nil
+}
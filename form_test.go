@@ -0,0 +1,38 @@
+package gron
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestUngronForm(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"a=1&b[0]=x&b[1]=y\n", `{"a":"1","b":["x","y"]}`},
+		{"c[d]=z&c[e][f]=w", `{"c":{"d":"z","e":{"f":"w"}}}`},
+		{"e[]=p&e[]=q&e[5]=r&e[]=s", `{"e":["p","q",null,null,null,"r","s"]}`},
+		{"q=hello+world&x%5By%5D=a%26b&empty=&bare", `{"bare":"","empty":"","q":"hello world","x":{"y":"a&b"}}`},
+		{"a[0][]=1&a[0][]=2\na[1][k]=v", `{"a":[["1","2"],{"k":"v"}]}`},
+	}
+
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		code, err := Ungron(strings.NewReader(c.in), out, OptMonochrome|OptFromForm, WithIndent(""))
+		if code != ExitOK || err != nil {
+			t.Fatalf("want ExitOK and nil error for %q; have %d and %v", c.in, code, err)
+		}
+		if out.String() != c.want+"\n" {
+			t.Errorf("want %s for %q; have %s", c.want, c.in, out.String())
+		}
+	}
+
+	for _, in := range []string{"[a]=1", "a[b=1", "a]=1&a[x", "a=%zz"} {
+		code, err := Ungron(strings.NewReader(in), &bytes.Buffer{}, OptMonochrome|OptFromForm)
+		if code != ExitParseStatements || err == nil {
+			t.Errorf("want ExitParseStatements and an error for %q; have %d and %v", in, code, err)
+		}
+	}
+}
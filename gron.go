@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"sort"
 
 	"github.com/fatih/color"
@@ -20,6 +21,8 @@ var (
 	BareColor  = color.New(color.FgBlue, color.Bold)
 	NumColor   = color.New(color.FgRed)
 	BoolColor  = color.New(color.FgCyan)
+	NullColor  = color.New(color.FgCyan)
+	PunctColor = color.New(color.Faint)
 )
 
 // Option bitfields
@@ -27,6 +30,151 @@ const (
 	OptMonochrome = 1 << iota
 	OptNoSort
 	OptJSON
+
+	// OptDeterministic makes the output for a given input identical
+	// byte-for-byte regardless of Go version or map ordering. It implies
+	// OptMonochrome, overrides OptNoSort so that statements are always
+	// sorted (with array indexes in numeric order), normalizes numbers
+	// (see normalizeNumber) and ends every statement with a single '\n'.
+	OptDeterministic
+
+	// OptInferTypes makes Ungron read relaxed 'key.path = value' lines,
+	// like those found in INI files, inferring the type of bare values
+	OptInferTypes
+
+	// OptNDJSON makes Ungron write each element of a top-level
+	// array as a separate line of compact JSON
+	OptNDJSON
+
+	// OptPreorder sorts statements into a pre-order traversal, with
+	// siblings ordered by key rather than by their rendered form
+	OptPreorder
+
+	// OptFromTSV makes Ungron read path<TAB>value lines,
+	// inferring the type of values as for OptInferTypes
+	OptFromTSV
+
+	// OptFromCSV makes Ungron read path,value CSV records,
+	// inferring the type of values as for OptInferTypes
+	OptFromCSV
+
+	// OptEvents writes each statement as a line of JSON with
+	// a structured path; see EventsVersion for the schema
+	OptEvents
+
+	// OptInlineScalarArrays writes arrays that contain only strings,
+	// numbers, bools and nulls as a single statement; e.g.
+	// json.tags = ["a","b"]; Ungron reads them back in either form
+	OptInlineScalarArrays
+
+	// OptArrayAsSet treats arrays as unordered sets when gronning: the
+	// elements of each array are ordered by their JSON encoding, so that
+	// documents differing only in the order of array elements give the
+	// same statements. It's meant for comparing documents; array indexes
+	// in the output don't correspond to those in the input
+	OptArrayAsSet
+
+	// OptLenient ignores anything after the JSON value in the input,
+	// rather than treating it as an error
+	OptLenient
+
+	// OptParents writes the container statements for the ancestors of
+	// every statement that passes the filters (e.g. WithGlob), so that
+	// filtered output can still be ungronned into the right shape
+	OptParents
+
+	// OptCanonical writes the canonical form of the input, which is the
+	// same for any two documents that are equal as JSON; regardless of
+	// the order of their keys, how their numbers and strings are written,
+	// or the whitespace in them. See Hash for the rules.
+	OptCanonical
+
+	// OptYAML makes Gron read YAML rather than JSON. Input with several
+	// documents is treated as an array of them, like GronStream's input
+	OptYAML
+
+	// OptValues writes only the value of each statement, without the
+	// path or the trailing semicolon; e.g. "Tom" for json.name = "Tom";
+	// Statements for objects and arrays are left out, as they carry no
+	// value. The output can't be ungronned
+	OptValues
+
+	// OptSkipErrors makes GronStream skip lines that aren't valid JSON,
+	// or that have a key already used with WithStreamKey, passing a
+	// warning about each to the function set with WithWarnings, rather
+	// than stopping at the first one. Array indexes still match line
+	// numbers, so skipped lines leave gaps
+	OptSkipErrors
+
+	// OptStrict makes Ungron fail if two statements assign different
+	// values to the same path, rather than the last one winning; e.g.
+	// json.a = 1; and json.a = 2; or json.a = 1; and json.a.b = 2;
+	// Declaring a container and then assigning to its members is fine.
+	// With WithBase it applies to the values in the base too
+	OptStrict
+
+	// OptSortByValue sorts statements by their values rather than their
+	// paths, with numbers in numeric order; see byValue for the details
+	OptSortByValue
+
+	// OptEnv writes each statement with a scalar value as a command that
+	// sets an environment variable, for the shell set with WithShell; e.g.
+	// export JSON_DATABASE_HOST='localhost' (see envVar for the naming).
+	// Objects and arrays are left out. The output can't be ungronned
+	OptEnv
+
+	// OptUnwrapStrings makes Gron treat strings that contain a JSON object
+	// or array, like "{\"a\":1}", as that object or array; writing its
+	// contents as if they were part of the input. Other strings are left
+	// as they are. Ungronning the output gives the unwrapped JSON, as
+	// there's no way to know which strings were unwrapped
+	OptUnwrapStrings
+
+	// OptSkipNulls leaves out statements that assign null, so that nulls
+	// are treated as if they were absent; e.g. when diffing configs. The
+	// statement for the top-level value (or each line's, for GronStream)
+	// is always written, as are nulls in arrays written inline. Ungronning
+	// the output gives the JSON without the nulls, other than in arrays
+	OptSkipNulls
+
+	// OptTOML makes Gron read TOML rather than JSON. The document is a
+	// table, so it's an object; see tomlToJSON for how values are written
+	OptTOML
+
+	// OptSplitKeys makes Ungron write the value of each key of the
+	// top-level object as a separate document rather than the object
+	// itself; e.g. to carve the concatenated output for several roots,
+	// like config.* and secrets.*, back into its parts. See splitKeys
+	OptSplitKeys
+
+	// OptKeepOrder writes statements in the order they appear in the
+	// document: the keys of each object in the order they were in the
+	// input, rather than sorted, with each object or array followed by
+	// its contents. Unlike OptNoSort the order is the same on every run.
+	// It applies to JSON input; OptDeterministic overrides it
+	OptKeepOrder
+
+	// OptFromForm makes Ungron read application/x-www-form-urlencoded
+	// input, like a=1&b[0]=x; see statementsFromForm for the details
+	OptFromForm
+
+	// OptXML makes Gron read XML rather than JSON. The root element is
+	// the only key of the top-level object; see xmlElement for the rest
+	OptXML
+
+	// OptMsgpack makes Gron read MessagePack rather than JSON. Input with
+	// several values is treated as an array of them, like OptYAML's
+	OptMsgpack
+
+	// OptBSON makes Gron read BSON rather than JSON; e.g. the output of
+	// mongodump. Input with several documents is treated as an array of
+	// them. See bsonDecoder for how types JSON doesn't have are written
+	OptBSON
+
+	// OptAvro makes Gron read an Avro object container file rather than
+	// JSON, decoding its records with the schema that's embedded in it.
+	// The records are always an array, like GronStream's lines
+	OptAvro
 )
 
 // Exit codes
@@ -38,44 +186,96 @@ const (
 	ExitFetchURL
 	ExitParseStatements
 	ExitJSONEncode
+	ExitUsage
+	ExitInputTooLarge
+	ExitNoValidLines
+	ExitDifferences
+	ExitEmptyInput
 )
 
+// ErrEmptyInput is returned, with ExitEmptyInput, by actions given
+// input that's empty or has nothing but whitespace in it; so that
+// having nothing to do can be told apart from invalid input
+var ErrEmptyInput = errors.New("empty input: there's nothing but whitespace to read")
+
+// checkEmpty reads r up to the first byte that isn't whitespace, and
+// returns a reader for all of the input, including the whitespace that
+// was read. It's an ErrEmptyInput if there's nothing but whitespace
+func checkEmpty(r io.Reader) (io.Reader, int, error) {
+	br := bufio.NewReader(r)
+	var space []byte
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			return nil, ExitEmptyInput, ErrEmptyInput
+		}
+		if err != nil {
+			return nil, ExitReadInput, fmt.Errorf("failed to read input: %s", err)
+		}
+		if b != ' ' && b != '\t' && b != '\n' && b != '\r' {
+			br.UnreadByte()
+			return io.MultiReader(bytes.NewReader(space), br), ExitOK, nil
+		}
+		space = append(space, b)
+	}
+}
+
 // an actionFn represents a main action of the program, it accepts
-// an input, output and a bitfield of options; returning an exit
-// code and any error that occurred
-type ActionFn func(io.Reader, io.Writer, int) (int, error)
+// an input, output, a bitfield of options and any further Options;
+// returning an exit code and any error that occurred
+type ActionFn func(io.Reader, io.Writer, int, ...Option) (int, error)
 
 // gron is the default action. Given JSON as the input it returns a list
-// of assignment statements. Possible options are OptNoSort and OptMonochrome
-func Gron(r io.Reader, w io.Writer, opts int) (int, error) {
-	var err error
+// of assignment statements. Possible options are OptNoSort, OptMonochrome,
+// OptJSON and OptDeterministic
+func Gron(r io.Reader, w io.Writer, opts int, options ...Option) (code int, err error) {
+	c := newConfig(options)
+	opts = resolveOpts(opts)
+	c.setOpts(opts)
+	r = c.limitInput(r)
+	defer c.checkInputSize(&code, &err)
+	if r, code, err = checkEmpty(r); err != nil {
+		return code, err
+	}
 
-	var conv statementconv
-	if opts&OptMonochrome > 0 {
-		conv = statementToString
-	} else {
-		conv = statementToColorString
+	// Input that's too big to hold in memory can only be streamed
+	if c.maxMemory > 0 {
+		var tooBig bool
+		r, tooBig, err = c.limitMemory(r)
+		if err != nil {
+			return ExitReadInput, fmt.Errorf("failed to read input: %s", err)
+		}
+		if tooBig && opts&(OptYAML|OptTOML|OptXML|OptMsgpack|OptBSON|OptAvro) > 0 {
+			return ExitFormStatements, fmt.Errorf(
+				"input needs more than the maximum memory of %d bytes and only JSON input can be streamed",
+				c.maxMemory,
+			)
+		}
+		if tooBig {
+			return gronArray(r, w, opts, c)
+		}
 	}
 
-	ss, err := statementsFromJSON(r, statement{{"json", typBare}})
+	var ss statements
+	ss, err = decoderFor(opts)(r, c.prefix(), c)
 	if err != nil {
 		goto out
 	}
+	ss = append(c.namespaceStatements(), ss...)
 
 	// Go's maps do not have well-defined ordering, but we want a consistent
 	// output for a given input, so we must sort the statements
-	if opts&OptNoSort == 0 {
-		sort.Sort(ss)
+	sortStatements(ss, opts)
+	ss = c.filter(ss)
+	if err = c.checkFloatFormat(ss); err != nil {
+		goto out
 	}
 
 	for _, s := range ss {
-		if opts&OptJSON > 0 {
-			s, err = s.jsonify()
-			if err != nil {
-				goto out
-			}
+		err = writeStatement(w, s, opts, c)
+		if err != nil {
+			goto out
 		}
-		fmt.Fprintln(w, conv(s))
 	}
 
 out:
@@ -85,151 +285,489 @@ out:
 	return ExitOK, nil
 }
 
+// GronEach is like Gron, but rather than writing the statements it
+// calls fn with the path and value of each; e.g. json.city and "Leeds".
+// They're passed in the order Gron would write them; with OptNoSort
+// that's the order they're formed in, which saves sorting them first.
+// If fn returns an error, GronEach stops and returns that error.
+func GronEach(r io.Reader, opts int, fn func(path, value string) error, options ...Option) error {
+	var fnErr error
+	each := func(c *config) {
+		c.sink = func(s Statement) error {
+			fnErr = fn(s.Path(), s.Value())
+			return fnErr
+		}
+	}
+
+	_, err := Gron(r, ioutil.Discard, opts, append(options[:len(options):len(options)], each)...)
+	if fnErr != nil {
+		return fnErr
+	}
+	return err
+}
+
 // gronStream is like the gron action, but it treats the input as one
 // JSON object per line. There's a bit of code duplication from the
 // gron action, but it'd be fairly messy to combine the two actions
-func GronStream(r io.Reader, w io.Writer, opts int) (int, error) {
-	var err error
+//
+// WithStreamKey keys each line by one of its fields rather than by
+// its line number, so the top-level value is an object
+func GronStream(r io.Reader, w io.Writer, opts int, options ...Option) (code int, err error) {
+	c := newConfig(options)
+	opts = resolveOpts(opts)
+	c.setOpts(opts)
+	r = c.limitInput(r)
+	defer c.checkInputSize(&code, &err)
+	if r, code, err = checkEmpty(r); err != nil {
+		return code, err
+	}
 	errstr := "failed to form statements"
-	var i int
+	var i, parsed int
 	var sc *bufio.Scanner
-	var buf []byte
-
-	var conv func(s statement) string
-	if opts&OptMonochrome > 0 {
-		conv = statementToString
-	} else {
-		conv = statementToColorString
-	}
 
-	// Helper function to make the prefix statements for each line
-	makePrefix := func(index int) statement {
-		return statement{
-			{"json", typBare},
-			{"[", typLBrace},
-			{fmt.Sprintf("%d", index), typNumericKey},
-			{"]", typRBrace},
-		}
-	}
+	prefix := c.prefix()
 
 	// The first line of output needs to establish that the top-level
-	// thing is actually an array...
-	top := statement{
-		{"json", typBare},
-		{"=", typEquals},
-		{"[]", typEmptyArray},
-		{";", typSemi},
+	// thing is actually an array, or an object if lines are keyed...
+	top := c.namespaceStatements()
+	if c.streamKey != "" {
+		top.addWithValue(prefix, token{"{}", typEmptyObject})
+	} else {
+		top.addWithValue(prefix, token{"[]", typEmptyArray})
 	}
 
-	if opts&OptJSON > 0 {
-		top, err = top.jsonify()
+	// With OptParents the top-level statements are the
+	// ancestors of everything, so they're always written
+	if !c.parents {
+		top = c.filter(top)
+	}
+	for _, s := range top {
+		err = writeStatement(w, s, opts, c)
 		if err != nil {
 			goto out
 		}
 	}
 
-	fmt.Fprintln(w, conv(top))
-
 	// Read the input line by line
-	sc = bufio.NewScanner(r)
-	buf = make([]byte, 0, 64*1024)
-	sc.Buffer(buf, 1024*1024)
+	sc = c.newScanner(r)
 	i = 0
 	for sc.Scan() {
 
 		line := bytes.NewBuffer(sc.Bytes())
 
-		var ss statements
-		ss, err = statementsFromJSON(line, makePrefix(i))
+		var v interface{}
+		c.keyOrder.reset()
+		v, err = decodeJSON(line, c.lenient, c.tokenDecoder(c.lineIndexPrefix(prefix, i)))
 		i++
+		if err != nil && opts&OptSkipErrors > 0 {
+			c.warnf("skipping line %d (%s): %s", i, c.lineIndexPrefix(prefix, i-1), err)
+			err = nil
+			continue
+		}
 		if err != nil {
 			goto out
 		}
 
-		// Go's maps do not have well-defined ordering, but we want a consistent
-		// output for a given input, so we must sort the statements
-		if opts&OptNoSort == 0 {
-			sort.Sort(ss)
+		var linePrefix statement
+		linePrefix, err = c.linePrefix(prefix, i-1, v)
+		if err != nil && opts&OptSkipErrors > 0 {
+			c.warnf("%s; skipping it", err)
+			err = nil
+			continue
 		}
+		if err != nil {
+			goto out
+		}
+		parsed++
 
-		for _, s := range ss {
-			if opts&OptJSON > 0 {
-				s, err = s.jsonify()
-				if err != nil {
-					goto out
-				}
+		ss := make(statements, 0, 32)
+		ss.fill(linePrefix, v, 0, c)
+
+		// Go's maps do not have well-defined ordering, but we want a consistent
+		// output for a given input, so we must sort the statements
+		sortStatements(ss, opts)
 
+		for _, s := range c.filter(ss) {
+			err = writeStatement(w, s, opts, c)
+			if err != nil {
+				goto out
 			}
-			fmt.Fprintln(w, conv(s))
 		}
 	}
 	if err = sc.Err(); err != nil {
-		errstr = "error reading multiline input: %s"
+		return ExitFormStatements, c.scanError(err, i+1, "error reading multiline input")
 	}
 
 out:
 	if err != nil {
 		return ExitFormStatements, fmt.Errorf(errstr+": %s", err)
 	}
+	if i > 0 && parsed == 0 {
+		return ExitNoValidLines, fmt.Errorf("%s: none of the %d lines of input were valid JSON", errstr, i)
+	}
 	return ExitOK, nil
 
 }
 
-// ungron is the reverse of gron. Given assignment statements as input,
-// it returns JSON. The only option is OptMonochrome
-func Ungron(r io.Reader, w io.Writer, opts int) (int, error) {
-	scanner := bufio.NewScanner(r)
-	var maker statementmaker
+// sortStatements sorts statements in the order chosen by opts
+func sortStatements(ss statements, opts int) {
+	switch {
+	case opts&(OptNoSort|OptKeepOrder) > 0:
+		return
+	case opts&OptSortByValue > 0:
+		sort.Sort(byValue{ss})
+	case opts&OptPreorder > 0:
+		sort.Sort(newPreorder(ss))
+	default:
+		sort.Sort(ss)
+	}
+}
+
+// resolveOpts expands any preset options into the options they imply
+func resolveOpts(opts int) int {
+	if opts&OptCanonical > 0 {
+		opts = (opts | OptDeterministic) &^ (OptJSON | OptEvents | OptEnv | OptPreorder | OptSortByValue | OptInlineScalarArrays | OptValues)
+	}
+	if opts&OptDeterministic > 0 {
+		opts = (opts | OptMonochrome) &^ (OptNoSort | OptKeepOrder)
+	}
+	return opts
+}
+
+// checkFloatFormat returns the error that writeStatement would return
+// for the first statement with a number too big to format as set with
+// WithFloatFormat; so that it can be returned before anything is written
+func (c *config) checkFloatFormat(ss statements) error {
+	if c.floatFormat == "" {
+		return nil
+	}
+	for _, s := range ss {
+		if _, err := s.withFormattedNumbers(c.floatFormat); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	if opts&OptJSON > 0 {
-		maker = statementFromJSONSpec
+// writeStatement writes a single statement to w in the form chosen
+// by opts, or passes it to the statement sink if there is one
+func writeStatement(w io.Writer, s statement, opts int, c *config) error {
+	if opts&OptDeterministic > 0 {
+		s = s.withNormalizedNumbers()
+	}
+	if c.floatFormat != "" {
+		var err error
+		s, err = s.withFormattedNumbers(c.floatFormat)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.sink != nil {
+		return c.sink(Statement{tokens: s})
+	}
+
+	truncated := 0
+	if c.truncate > 0 {
+		s, truncated = s.withTruncatedString(c.truncate)
+	}
+
+	if opts&OptEvents > 0 {
+		e, err := statementToEvent(s)
+		if err != nil {
+			return err
+		}
+		c.writeLine(w, e)
+		return nil
+	}
+
+	if opts&OptEnv > 0 {
+		c.writeEnv(w, s)
+		return nil
+	}
+
+	if opts&OptValues > 0 {
+		v, ok := s.value()
+		if !ok || v.typ == typEmptyObject || v.typ == typEmptyArray {
+			return nil
+		}
+		s = statement{v}
+	} else if opts&OptJSON > 0 {
+		var err error
+		s, err = s.jsonify()
+		if err != nil {
+			return err
+		}
 	} else {
-		maker = statementFromStringMaker
+		s = c.pathDialect.convert(s)
 	}
 
-	// Make a list of statements from the input
-	var ss statements
-	for scanner.Scan() {
-		s, err := maker(scanner.Text())
+	var conv statementconv
+	switch {
+	case opts&OptMonochrome > 0:
+		conv = statementToString
+	case len(c.highlights) > 0:
+		conv = c.highlightedColorString
+	default:
+		conv = statementToColorString
+	}
+	line := conv(s)
+	if truncated > 0 {
+		line += fmt.Sprintf(" // truncated %d bytes", truncated)
+	}
+	c.writeLine(w, line)
+	return nil
+}
+
+// ungron is the reverse of gron. Given assignment statements as input,
+// it returns JSON. OptMonochrome turns off color, and OptJSON,
+// OptInferTypes, OptFromTSV, OptFromCSV and OptFromForm set how the
+// statements are read; OptNDJSON writes one line per element of the
+// top-level array and OptSplitKeys one document per top-level key.
+// WithIndent sets how the JSON is indented, or writes it on one line;
+// WithSplitOn treats the input as several documents, written as a JSON
+// array or with OptNDJSON as one line each, and takes precedence over
+// OptSplitKeys. OptStrict makes conflicting statements an error, and
+// WithBase applies the statements to a document as a patch; while
+// WithMaxArrayIndex, WithNamespace, WithMaxLineSize and
+// WithMaxInputBytes apply as they do for the other actions.
+func Ungron(r io.Reader, w io.Writer, opts int, options ...Option) (code int, err error) {
+	c := newConfig(options)
+	c.setOpts(opts)
+	r = c.limitInput(r)
+	defer c.checkInputSize(&code, &err)
+
+	// Empty input is fine for a patch; it means there are no changes
+	if c.base == nil && c.baseErr == nil {
+		if r, code, err = checkEmpty(r); err != nil {
+			return code, err
+		}
+	}
+	scanner := c.newScanner(r)
+	maker := ungronMaker(opts)
+
+	// Make lists of statements from the input; there's only one
+	// unless the input is split into several documents
+	docs := []statements{nil}
+	switch {
+	case opts&OptFromCSV > 0:
+		var err error
+		docs[0], err = statementsFromCSV(r)
+		if err != nil {
+			return ExitParseStatements, fmt.Errorf("failed to read CSV input: %s", err)
+		}
+	case opts&OptFromForm > 0:
+		var err error
+		docs[0], err = statementsFromForm(r)
 		if err != nil {
-			return ExitParseStatements, err
+			return ExitParseStatements, fmt.Errorf("failed to read form input: %s", err)
+		}
+	default:
+		n := 1
+		for ; scanner.Scan(); n++ {
+			if c.splitOn != nil && c.splitOn.MatchString(scanner.Text()) {
+				if !docs[len(docs)-1].blank() {
+					docs = append(docs, nil)
+				}
+				continue
+			}
+			s, err := parseLine(maker, n, scanner.Text(), c)
+			if err != nil {
+				return ExitParseStatements, err
+			}
+			docs[len(docs)-1].add(s)
+		}
+		if err := scanner.Err(); err != nil {
+			return ExitReadInput, c.scanError(err, n, "failed to read input statements")
 		}
-		ss.add(s)
 	}
-	if err := scanner.Err(); err != nil {
-		return ExitReadInput, fmt.Errorf("failed to read input statements")
+
+	// Split documents are written as an array, or as one line each
+	if c.splitOn != nil {
+		values := make([]interface{}, 0, len(docs))
+		for _, ss := range docs {
+			if ss.blank() {
+				continue
+			}
+			v, _, err := ungronStatements(ss, c)
+			if err != nil {
+				return ExitParseStatements, err
+			}
+			values = append(values, v)
+		}
+
+		if opts&OptNDJSON > 0 {
+			err := writeNDJSON(w, values, nil)
+			if err != nil {
+				return ExitJSONEncode, errors.Wrap(err, "failed to convert statements to JSON")
+			}
+			return ExitOK, nil
+		}
+		return writeJSON(w, values, opts, c.indent)
 	}
 
-	// turn the statements into a single merged interface{} type
-	merged, err := ss.toInterface()
+	return writeUngronned(w, docs[0], opts, c)
+}
+
+// ungronMaker returns the function that makes
+// statements from lines of input for Ungron
+func ungronMaker(opts int) statementmaker {
+	switch {
+	case opts&OptJSON > 0:
+		return statementFromJSONSpec
+	case opts&OptInferTypes > 0:
+		return statementFromInferredString
+	case opts&OptFromTSV > 0:
+		return statementFromTSV
+	default:
+		return statementFromStringMaker
+	}
+}
+
+// A LineError is returned by Ungron and UngronStream when a line of
+// input can't be made into a statement that can be ungronned
+type LineError struct {
+	Line int    // The number of the line, counting from one
+	Text string // The line itself
+	Err  error  // What's wrong with it
+}
+
+func (e LineError) Error() string {
+	return fmt.Sprintf("line %d: %s in `%s`", e.Line, e.Err, e.Text)
+}
+
+// parseLine makes a statement from the n'th line of input (counting
+// from one), returning a LineError if it can't be ungronned
+func parseLine(maker statementmaker, n int, line string, c *config) (statement, error) {
+	s, err := maker(line)
+	if err == nil {
+		err = s.parseError(c.maxIndex)
+	}
+	if err != nil {
+		return nil, LineError{n, line, err}
+	}
+	return s, nil
+}
+
+// writeUngronned ungrons a single document's statements
+// and writes the JSON, or NDJSON if OptNDJSON is set
+func writeUngronned(w io.Writer, ss statements, opts int, c *config) (int, error) {
+	merged, root, err := ungronStatements(ss, c)
 	if err != nil {
 		return ExitParseStatements, err
 	}
 
-	// If there's only one top level key and it's "json", make that the top level thing
+	if opts&OptSplitKeys > 0 {
+		return writeSplitKeys(w, merged, opts, c.indent)
+	}
+
+	if opts&OptNDJSON > 0 {
+		prefix := c.prefix()
+		if root != "" {
+			prefix[0] = token{root, typBare}
+		}
+		err = writeNDJSON(w, merged, ss.indexes(prefix))
+		if err != nil {
+			return ExitJSONEncode, errors.Wrap(err, "failed to convert statements to JSON")
+		}
+		return ExitOK, nil
+	}
+
+	return writeJSON(w, merged, opts, c.indent)
+}
+
+// ungronStatements turns statements into a single merged value,
+// without the root or any namespace keys; also returning the root
+func ungronStatements(ss statements, c *config) (interface{}, string, error) {
+	// Patches are applied to the base in order rather than merged
+	var merged interface{}
+	var root string
+	var err error
+	switch {
+	case c.patching(ss):
+		merged, root, err = c.applyPatch(ss)
+	case c.strict:
+		err = ss.conflict()
+		if err == nil {
+			merged, root, err = ss.merge(c.maxIndex)
+		}
+	default:
+		merged, root, err = ss.merge(c.maxIndex)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Strip any namespace keys from the top level thing
+	for _, k := range c.namespace {
+		m, ok := merged.(map[string]interface{})
+		if !ok {
+			return nil, "", fmt.Errorf("namespace key %s not found in statements", quoteString(k))
+		}
+		v, exists := m[k]
+		if !exists {
+			return nil, "", fmt.Errorf("namespace key %s not found in statements", quoteString(k))
+		}
+		merged = v
+	}
+
+	return merged, root, nil
+}
+
+// merge turns statements into a single merged value
+// without the root, also returning the root
+func (ss statements) merge(maxIndex int) (interface{}, string, error) {
+	// turn the statements into a single merged interface{} type
+	merged, err := ss.toInterface(maxIndex)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// If every statement starts with the same bare word (usually "json",
+	// but not always; e.g. when the statements were edited by hand) then
+	// that's the root rather than a key, so make its value the top level thing
+	root := ss.root()
 	mergedMap, ok := merged.(map[string]interface{})
-	if ok {
+	if ok && root != "" {
 		if len(mergedMap) == 1 {
-			if _, exists := mergedMap["json"]; exists {
-				merged = mergedMap["json"]
+			if _, exists := mergedMap[root]; exists {
+				merged = mergedMap[root]
 			}
 		}
 	}
+	return merged, root, nil
+}
+
+// writeJSON writes a value to w as JSON indented with the provided
+// string, colorized unless OptMonochrome is set. An empty indent
+// writes it on one line, without color.
+func writeJSON(w io.Writer, v interface{}, opts int, indent string) (int, error) {
+	j, err := encodeJSON(v, opts, indent)
+	if err != nil {
+		return ExitJSONEncode, errors.Wrap(err, "failed to convert statements to JSON")
+	}
+	fmt.Fprintf(w, "%s\n", j)
 
+	return ExitOK, nil
+}
+
+// encodeJSON returns a value as JSON in the form that writeJSON
+// writes it, but without the trailing newline
+func encodeJSON(v interface{}, opts int, indent string) ([]byte, error) {
 	// Marshal the output into JSON to display to the user
 	out := &bytes.Buffer{}
 	enc := json.NewEncoder(out)
-	enc.SetIndent("", "  ")
+	enc.SetIndent("", indent)
 	enc.SetEscapeHTML(false)
-	err = enc.Encode(merged)
+	err := enc.Encode(v)
 	if err != nil {
-		return ExitJSONEncode, errors.Wrap(err, "failed to convert statements to JSON")
+		return nil, err
 	}
 	j := out.Bytes()
 
 	// If the output isn't monochrome, add color to the JSON
-	if opts&OptMonochrome == 0 {
-		c, err := colorizeJSON(j)
+	if opts&OptMonochrome == 0 && indent != "" {
+		c, err := colorizeJSON(j, indent)
 
 		// If we failed to colorize the JSON for whatever reason,
 		// we'll just fall back to monochrome output, otherwise
@@ -239,21 +777,79 @@ func Ungron(r io.Reader, w io.Writer, opts int) (int, error) {
 		}
 	}
 
-	// For whatever reason, the monochrome version of the JSON
-	// has a trailing newline character, but the colorized version
-	// does not. Strip the whitespace so that neither has the newline
-	// character on the end, and then we'll add a newline in the
-	// Fprintf below
-	j = bytes.TrimSpace(j)
+	// The encoder ends the monochrome version of the JSON with a
+	// newline, but the colorized version doesn't have one. Only that
+	// newline is removed; there's never anything else after the value
+	return bytes.TrimSuffix(j, []byte("\n")), nil
+}
+
+// writeSplitKeys writes the value of each key of an object as a separate
+// document, in key order: as a line of JSON each with OptNDJSON, and
+// otherwise as JSON after a line like '--- # config' that names the key;
+// so the output is also a stream of YAML documents
+func writeSplitKeys(w io.Writer, v interface{}, opts int, indent string) (int, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return ExitParseStatements, fmt.Errorf("the top-level value isn't an object, so it can't be split into its keys")
+	}
 
-	fmt.Fprintf(w, "%s\n", j)
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if opts&OptNDJSON > 0 {
+			j, err := encodeJSON(m[k], OptMonochrome, "")
+			if err != nil {
+				return ExitJSONEncode, errors.Wrap(err, "failed to convert statements to JSON")
+			}
+			fmt.Fprintf(w, "%s\n", j)
+			continue
+		}
 
+		name := k
+		if !ValidIdentifier(k) {
+			name = quoteString(k)
+		}
+		fmt.Fprintf(w, "--- # %s\n", name)
+		if code, err := writeJSON(w, m[k], opts, indent); err != nil {
+			return code, err
+		}
+	}
 	return ExitOK, nil
 }
 
-func colorizeJSON(src []byte) ([]byte, error) {
+// writeNDJSON writes each element of v, if it's an array, to w as a
+// line of compact JSON. Only the elements at the provided indexes are
+// written, so that gaps left by filtering statements don't turn into
+// nulls; unless indexes is nil, in which case all of them are. Anything
+// other than an array is written as a single line.
+func writeNDJSON(w io.Writer, v interface{}, indexes map[int]bool) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	elems, ok := v.([]interface{})
+	if !ok {
+		return enc.Encode(v)
+	}
+
+	for i, e := range elems {
+		if indexes != nil && !indexes[i] {
+			continue
+		}
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func colorizeJSON(src []byte, indent string) ([]byte, error) {
 	out := &bytes.Buffer{}
 	f := jsoncolor.NewFormatter()
+	f.Indent = indent
 
 	f.StringColor = StrColor
 	f.ObjectColor = BraceColor
@@ -262,7 +858,7 @@ func colorizeJSON(src []byte) ([]byte, error) {
 	f.NumberColor = NumColor
 	f.TrueColor = BoolColor
 	f.FalseColor = BoolColor
-	f.NullColor = BoolColor
+	f.NullColor = NullColor
 
 	err := f.Format(out, src)
 	if err != nil {
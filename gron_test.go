@@ -3,10 +3,17 @@ package gron
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 	"testing"
+
+	"github.com/fatih/color"
 )
 
 func TestGron(t *testing.T) {
@@ -181,6 +188,37 @@ func TestUngron(t *testing.T) {
 	}
 }
 
+func TestRoundTripTopLevelValues(t *testing.T) {
+	cases := []string{
+		`[1,2,3]`,
+		`42`,
+		`"str"`,
+		`null`,
+		`false`,
+		`[]`,
+		`[[1],{"json":[]}]`,
+	}
+
+	for _, in := range cases {
+		for _, opts := range []int{0, OptJSON} {
+			gronned := &bytes.Buffer{}
+			code, err := Gron(strings.NewReader(in), gronned, OptMonochrome|opts)
+			if code != ExitOK || err != nil {
+				t.Fatalf("want ExitOK and nil error from Gron for %s; have %d and %v", in, code, err)
+			}
+
+			out := &bytes.Buffer{}
+			code, err = Ungron(gronned, out, OptMonochrome|opts, WithIndent(""))
+			if code != ExitOK || err != nil {
+				t.Fatalf("want ExitOK and nil error from Ungron for %s; have %d and %v", in, code, err)
+			}
+			if out.String() != in+"\n" {
+				t.Errorf("want %s to round trip; have %s", in, out.String())
+			}
+		}
+	}
+}
+
 func TestGronJ(t *testing.T) {
 	cases := []struct {
 		inFile  string
@@ -333,3 +371,1502 @@ func BenchmarkBigJSON(b *testing.B) {
 		}
 	}
 }
+
+func TestGronValueTransform(t *testing.T) {
+	in := bytes.NewBufferString(`{"user": {"name": "Tom", "password": "hunter2"}, "ids": [1, 2]}`)
+
+	var paths []string
+	transform := func(path []string, v interface{}) interface{} {
+		paths = append(paths, strings.Join(path, "/"))
+		if len(path) > 0 && path[len(path)-1] == "password" {
+			return "***"
+		}
+		return v
+	}
+
+	out := &bytes.Buffer{}
+	code, err := Gron(in, out, OptMonochrome, WithValueTransform(transform))
+
+	if code != ExitOK {
+		t.Errorf("want ExitOK; have %d", code)
+	}
+	if err != nil {
+		t.Errorf("want nil error; have %s", err)
+	}
+
+	want := []byte(`json = {};
+json.ids = [];
+json.ids[0] = 1;
+json.ids[1] = 2;
+json.user = {};
+json.user.name = "Tom";
+json.user.password = "***";
+`)
+
+	if !reflect.DeepEqual(want, out.Bytes()) {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", out.Bytes())
+		t.Errorf("transformed output does not match")
+	}
+
+	sort.Strings(paths)
+	wantPaths := []string{"ids/0", "ids/1", "user/name", "user/password"}
+	if !reflect.DeepEqual(wantPaths, paths) {
+		t.Errorf("want transform paths %v; have %v", wantPaths, paths)
+	}
+}
+
+func TestGronRedact(t *testing.T) {
+	in := bytes.NewBufferString(`{
+		"user": {"name": "Tom", "password": "hunter2", "auth": {"db_password": 1234}},
+		"passwords": ["a", "b"],
+		"title": "password reset"
+	}`)
+
+	out := &bytes.Buffer{}
+	code, err := Gron(in, out, OptMonochrome, WithRedact(regexp.MustCompile(`password`)))
+
+	if code != ExitOK {
+		t.Errorf("want ExitOK; have %d", code)
+	}
+	if err != nil {
+		t.Errorf("want nil error; have %s", err)
+	}
+
+	want := []byte(`json = {};
+json.passwords = [];
+json.passwords[0] = "***";
+json.passwords[1] = "***";
+json.title = "password reset";
+json.user = {};
+json.user.auth = {};
+json.user.auth.db_password = "***";
+json.user.name = "Tom";
+json.user.password = "***";
+`)
+
+	if !reflect.DeepEqual(want, out.Bytes()) {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", out.Bytes())
+		t.Errorf("redacted output does not match")
+	}
+
+	// The redacted output should still be valid gron
+	code, err = Ungron(bytes.NewReader(out.Bytes()), &bytes.Buffer{}, OptMonochrome)
+	if code != ExitOK || err != nil {
+		t.Errorf("want redacted output to ungron; have %d, %s", code, err)
+	}
+}
+
+func TestGronStatementSink(t *testing.T) {
+	in := bytes.NewBufferString(`{"b": [true], "a": "foo"}`)
+
+	var have []string
+	sink := func(s Statement) {
+		have = append(have, s.Path()+"|"+s.Value()+"|"+s.String())
+	}
+
+	out := &bytes.Buffer{}
+	code, err := Gron(in, out, OptMonochrome|OptJSON, WithStatementSink(sink))
+
+	if code != ExitOK {
+		t.Errorf("want ExitOK; have %d", code)
+	}
+	if err != nil {
+		t.Errorf("want nil error; have %s", err)
+	}
+
+	if out.Len() != 0 {
+		t.Errorf("want nothing written to the writer; have %q", out.String())
+	}
+
+	want := []string{
+		`json|{}|json = {};`,
+		`json.a|"foo"|json.a = "foo";`,
+		`json.b|[]|json.b = [];`,
+		`json.b[0]|true|json.b[0] = true;`,
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("want sink to receive %#v; have %#v", want, have)
+	}
+}
+
+func TestGronEach(t *testing.T) {
+	in := `{"b": [true], "a": "foo", "c": null}`
+
+	var have []string
+	err := GronEach(strings.NewReader(in), OptMonochrome, func(path, value string) error {
+		have = append(have, path+" "+value)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("want nil error; have %s", err)
+	}
+	want := []string{`json {}`, `json.a "foo"`, `json.b []`, `json.b[0] true`, `json.c null`}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("want %#v; have %#v", want, have)
+	}
+
+	// An error from the callback stops it, and is returned as it is
+	stop := fmt.Errorf("stop")
+	calls := 0
+	err = GronEach(strings.NewReader(in), OptMonochrome|OptNoSort, func(path, value string) error {
+		calls++
+		if calls == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || calls != 2 {
+		t.Errorf("want the callback's error after 2 calls; have %v after %d", err, calls)
+	}
+
+	// Other errors are returned too
+	err = GronEach(strings.NewReader(`{"a":`), OptMonochrome, func(path, value string) error {
+		return nil
+	})
+	if err == nil {
+		t.Errorf("want an error for invalid JSON; have nil")
+	}
+}
+
+func TestUngronTrailingNewline(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+
+	// colored is the value as it's colorized; for a scalar
+	// that's the whole of the colorized output but the newline
+	cases := []struct {
+		in      string
+		want    string
+		colored string
+	}{
+		{"json = null;\n", "null\n", NullColor.Sprint("null")},
+		{"json = 1;\n", "1\n", NumColor.Sprint("1")},
+		{"json = \"a \";\n", "\"a \"\n", StrColor.Sprint("\"a \"")},
+		{"json = {};\njson.a = \"b \";\n", "{\n  \"a\": \"b \"\n}\n", StrColor.Sprint("\"b \"")},
+	}
+
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		code, err := Ungron(strings.NewReader(c.in), out, OptMonochrome)
+		if code != ExitOK || err != nil {
+			t.Fatalf("want ExitOK and nil error for %q; have %d and %v", c.in, code, err)
+		}
+		if out.String() != c.want {
+			t.Errorf("want %q for %q; have %q", c.want, c.in, out.String())
+		}
+
+		// Colorized JSON ends with exactly one newline too,
+		// and keeps the space at the end of the string
+		out.Reset()
+		Ungron(strings.NewReader(c.in), out, 0)
+		have := out.String()
+		if !strings.HasSuffix(have, "\n") || strings.HasSuffix(have, "\n\n") {
+			t.Errorf("want colorized output for %q to end with one newline; have %q", c.in, have)
+		}
+		if strings.Count(c.want, "\n") == 1 && have != c.colored+"\n" {
+			t.Errorf("want colorized output %q for %q; have %q", c.colored+"\n", c.in, have)
+		}
+		if !strings.Contains(have, c.colored) {
+			t.Errorf("want %q in the colorized output for %q; have %q", c.colored, c.in, have)
+		}
+	}
+
+	// An empty document is still an error rather than null
+	code, err := Ungron(strings.NewReader(""), &bytes.Buffer{}, OptMonochrome)
+	if code != ExitEmptyInput || err != ErrEmptyInput {
+		t.Errorf("want ExitEmptyInput and ErrEmptyInput for empty input; have %d and %v", code, err)
+	}
+}
+
+func TestUngronLineNumbers(t *testing.T) {
+	cases := []struct {
+		in   string
+		opts int
+		want string
+	}{
+		{"json = {};\njson.a = ;\njson.b = 2;\n", 0, "line 2: invalid value `` in `json.a = ;`"},
+		{"json = {};\njson..a = 1;\njson.b = 2;\n", 0, "line 2: invalid statement in `json..a = 1;`"},
+		{"[[],{}]\n[[\"a\"\n[[\"b\"],2]\n", OptJSON, "line 2: unexpected end of JSON input in `[[\"a\"`"},
+
+		// The line is only quoted once, whichever way it's read
+		{"a = 1\nfoo\n", OptInferTypes, "line 2: no '=' found in `foo`"},
+		{"a = 1\n= 2\n", OptInferTypes, "line 2: no key found in `= 2`"},
+		{"json.a\t1\nfoo\n", OptFromTSV, "line 2: no tab found in `foo`"},
+	}
+
+	for _, c := range cases {
+		for _, fn := range []ActionFn{Ungron, UngronStream} {
+			code, err := fn(strings.NewReader(c.in), &bytes.Buffer{}, OptMonochrome|c.opts)
+			if code != ExitParseStatements || err == nil {
+				t.Fatalf("want ExitParseStatements and an error for %q; have %d and %v", c.in, code, err)
+			}
+			if err.Error() != c.want {
+				t.Errorf("want error %q; have %q", c.want, err)
+			}
+			if le, ok := err.(LineError); !ok || le.Line != 2 {
+				t.Errorf("want a LineError for line 2; have %#v", err)
+			}
+		}
+	}
+}
+
+func TestUngronLongLine(t *testing.T) {
+	long := strings.Repeat("a", 100*1024)
+	in := fmt.Sprintf("json = {};\njson.blob = %q;\n", long)
+
+	out := &bytes.Buffer{}
+	code, err := Ungron(strings.NewReader(in), out, OptMonochrome)
+	if code != ExitOK {
+		t.Errorf("want ExitOK; have %d", code)
+	}
+	if err != nil {
+		t.Errorf("want nil error; have %s", err)
+	}
+
+	var have map[string]string
+	err = json.Unmarshal(out.Bytes(), &have)
+	if err != nil {
+		t.Fatalf("failed to unmarshal JSON from ungron output: %s", err)
+	}
+	if have["blob"] != long {
+		t.Errorf("want long value to survive ungron; have %d bytes", len(have["blob"]))
+	}
+
+	// Lowering the limit should make the same input fail
+	code, err = Ungron(strings.NewReader(in), &bytes.Buffer{}, OptMonochrome, WithMaxLineSize(64*1024))
+	if code != ExitReadInput {
+		t.Errorf("want ExitReadInput; have %d", code)
+	}
+	if err == nil {
+		t.Errorf("want non-nil error; have nil")
+	}
+}
+
+func TestUngronSparseArrays(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{`json[5] = "x";`, `[null,null,null,null,null,"x"]`},
+		{"json[2] = 3;\njson[1] = 2;\njson[0] = 1;\n", `[1,2,3]`},
+		{"json[2].a = 1;\njson[0] = [];\njson[2].b = 2;\njson[0][1] = true;\n", `[[null,true],null,{"a":1,"b":2}]`},
+		{"json.a[3] = 1;\njson.a[1] = null;\njson.a[0] = 0;\n", `{"a":[0,null,null,1]}`},
+	}
+
+	for _, c := range cases {
+		for _, fn := range []ActionFn{Ungron, UngronStream} {
+			out := &bytes.Buffer{}
+			code, err := fn(strings.NewReader(c.in), out, OptMonochrome, WithIndent(""))
+			if code != ExitOK || err != nil {
+				t.Fatalf("want ExitOK and nil error for %q; have %d and %v", c.in, code, err)
+			}
+			if have := strings.TrimSpace(out.String()); have != c.want {
+				t.Errorf("want %s for %q; have %s", c.want, c.in, have)
+			}
+		}
+	}
+}
+
+func TestUngronMaxArrayIndex(t *testing.T) {
+	in := "json[0] = 1;\njson[1000] = 2;\n"
+	want := "line 2: array index 1000 is more than the maximum of 999 in `json[1000] = 2;`"
+	for _, fn := range []ActionFn{Ungron, UngronStream} {
+		code, err := fn(strings.NewReader(in), &bytes.Buffer{}, OptMonochrome, WithMaxArrayIndex(999))
+		if code != ExitParseStatements || err == nil {
+			t.Fatalf("want ExitParseStatements and an error; have %d and %v", code, err)
+		}
+		if err.Error() != want {
+			t.Errorf("want error %q; have %q", want, err)
+		}
+	}
+
+	// The default limit stops an absurd index from allocating anything
+	code, err := Ungron(strings.NewReader("json[99999999999] = 1;\n"), &bytes.Buffer{}, OptMonochrome)
+	if code != ExitParseStatements || err == nil {
+		t.Errorf("want ExitParseStatements and an error; have %d and %v", code, err)
+	}
+
+	// The limit itself is fine
+	out := &bytes.Buffer{}
+	code, err = Ungron(strings.NewReader(in), out, OptMonochrome, WithIndent(""), WithMaxArrayIndex(1000))
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+}
+
+func TestGronStreamLongLine(t *testing.T) {
+	// The second line is one byte longer than the default limit
+	long := strings.Repeat("a", DefaultMaxLineSize-len(`{"a":""}`)+1)
+	in := fmt.Sprintf("{\"a\":1}\n{\"a\":%q}\n", long)
+
+	code, err := GronStream(strings.NewReader(in), &bytes.Buffer{}, OptMonochrome)
+	if code != ExitFormStatements {
+		t.Errorf("want ExitFormStatements; have %d", code)
+	}
+	want := LineTooLongError{Line: 2, Max: DefaultMaxLineSize}
+	if err != want {
+		t.Errorf("want %#v; have %#v", want, err)
+	}
+	if err != nil && err.Error() != "line 2 is longer than the maximum line size of 1048576 bytes" {
+		t.Errorf("want an error naming the line and limit; have %q", err)
+	}
+
+	// Raising the limit lets it through
+	out := &bytes.Buffer{}
+	code, err = GronStream(strings.NewReader(in), out, OptMonochrome, WithMaxLineSize(2*DefaultMaxLineSize))
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	if !strings.Contains(out.String(), long) {
+		t.Errorf("want the long value in the output")
+	}
+}
+
+func TestGronNamespace(t *testing.T) {
+	out := &bytes.Buffer{}
+	code, err := Gron(strings.NewReader(`{"users": ["Tom"]}`), out, OptMonochrome, WithNamespace("svcA", "my svc"))
+	if code != ExitOK {
+		t.Errorf("want ExitOK; have %d", code)
+	}
+	if err != nil {
+		t.Errorf("want nil error; have %s", err)
+	}
+
+	want := `json = {};
+json.svcA = {};
+json.svcA["my svc"] = {};
+json.svcA["my svc"].users = [];
+json.svcA["my svc"].users[0] = "Tom";
+`
+	if out.String() != want {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", out.String())
+		t.Errorf("namespaced output does not match")
+	}
+
+	// Ungronning with the same namespace should strip it
+	ungronned := &bytes.Buffer{}
+	code, err = Ungron(bytes.NewReader(out.Bytes()), ungronned, OptMonochrome, WithNamespace("svcA", "my svc"))
+	if code != ExitOK {
+		t.Errorf("want ExitOK; have %d", code)
+	}
+	if err != nil {
+		t.Errorf("want nil error; have %s", err)
+	}
+	if ungronned.String() != "{\n  \"users\": [\n    \"Tom\"\n  ]\n}\n" {
+		t.Errorf("want namespace stripped by ungron; have %s", ungronned.String())
+	}
+
+	// A namespace that isn't there is an error
+	code, err = Ungron(bytes.NewReader(out.Bytes()), &bytes.Buffer{}, OptMonochrome, WithNamespace("svcB"))
+	if code != ExitParseStatements {
+		t.Errorf("want ExitParseStatements; have %d", code)
+	}
+	if err == nil {
+		t.Errorf("want non-nil error; have nil")
+	}
+}
+
+func TestGronStreamNamespace(t *testing.T) {
+	out := &bytes.Buffer{}
+	code, err := GronStream(strings.NewReader("1\n2\n"), out, OptMonochrome, WithNamespace("svcA"))
+	if code != ExitOK {
+		t.Errorf("want ExitOK; have %d", code)
+	}
+	if err != nil {
+		t.Errorf("want nil error; have %s", err)
+	}
+
+	want := "json = {};\njson.svcA = [];\njson.svcA[0] = 1;\njson.svcA[1] = 2;\n"
+	if out.String() != want {
+		t.Errorf("want %q; have %q", want, out.String())
+	}
+}
+
+func TestGronDeterministic(t *testing.T) {
+	in := `{"b": 1.0, "a": [1e2, -0, 2.50], "c": {"z": 1, "y": 2}}`
+
+	want := `json = {};
+json.a = [];
+json.a[0] = 100;
+json.a[1] = 0;
+json.a[2] = 2.5;
+json.b = 1;
+json.c = {};
+json.c.y = 2;
+json.c.z = 1;
+`
+
+	// The output should be the same whatever other options are used
+	for _, opts := range []int{0, OptNoSort, OptMonochrome} {
+		out := &bytes.Buffer{}
+		code, err := Gron(strings.NewReader(in), out, opts|OptDeterministic)
+		if code != ExitOK {
+			t.Errorf("want ExitOK; have %d", code)
+		}
+		if err != nil {
+			t.Errorf("want nil error; have %s", err)
+		}
+		if out.String() != want {
+			t.Logf("want: %s", want)
+			t.Logf("have: %s", out.String())
+			t.Errorf("deterministic output does not match for opts %d", opts)
+		}
+	}
+}
+
+func TestUngronInferTypes(t *testing.T) {
+	in := `; database settings
+database.host = localhost
+database.port = 5432
+database.ssl = true
+
+# feature flags
+features[0] = 1.5
+features[1] = 007
+["a key"].name = "quoted"
+my-key = dashes
+my key.sub-key[0] = spaces
+`
+
+	out := &bytes.Buffer{}
+	code, err := Ungron(strings.NewReader(in), out, OptMonochrome|OptInferTypes)
+	if code != ExitOK {
+		t.Errorf("want ExitOK; have %d", code)
+	}
+	if err != nil {
+		t.Errorf("want nil error; have %s", err)
+	}
+
+	var have interface{}
+	err = json.Unmarshal(out.Bytes(), &have)
+	if err != nil {
+		t.Fatalf("failed to unmarshal JSON from ungron output: %s", err)
+	}
+
+	want := map[string]interface{}{
+		"database": map[string]interface{}{
+			"host": "localhost",
+			"port": float64(5432),
+			"ssl":  true,
+		},
+		"features": []interface{}{1.5, "007"},
+		"a key":    map[string]interface{}{"name": "quoted"},
+		"my-key":   "dashes",
+		"my key":   map[string]interface{}{"sub-key": []interface{}{"spaces"}},
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Logf("want: %#v", want)
+		t.Logf("have: %#v", have)
+		t.Errorf("ungronned INI does not match")
+	}
+}
+
+func TestUngronNDJSON(t *testing.T) {
+	in := "{\"id\": 1, \"ok\": true}\n{\"id\": 2, \"ok\": false}\nnull\n{\"id\": 4, \"ok\": true}\n"
+
+	gronned := &bytes.Buffer{}
+	code, err := GronStream(strings.NewReader(in), gronned, OptMonochrome)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want gron to succeed; have %d, %s", code, err)
+	}
+
+	// Keep only the lines for records that are ok, or null; like grep would
+	filtered := &bytes.Buffer{}
+	for _, l := range strings.Split(gronned.String(), "\n") {
+		if strings.HasPrefix(l, "json[1]") {
+			continue
+		}
+		filtered.WriteString(l + "\n")
+	}
+
+	out := &bytes.Buffer{}
+	code, err = Ungron(filtered, out, OptMonochrome|OptNDJSON)
+	if code != ExitOK {
+		t.Errorf("want ExitOK; have %d", code)
+	}
+	if err != nil {
+		t.Errorf("want nil error; have %s", err)
+	}
+
+	want := "{\"id\":1,\"ok\":true}\nnull\n{\"id\":4,\"ok\":true}\n"
+	if out.String() != want {
+		t.Errorf("want %q; have %q", want, out.String())
+	}
+}
+
+func TestUngronFromColumns(t *testing.T) {
+	want := map[string]interface{}{
+		"name":   "Tom",
+		"id":     float64(1),
+		"admin":  false,
+		"tags":   []interface{}{"a, b", "c"},
+		"a key":  "\"quoted\"",
+		"height": nil,
+	}
+
+	cases := []struct {
+		in   string
+		opts int
+	}{
+		{
+			"json.name\tTom\njson.id\t1\njson.admin\tfalse\n" +
+				"json.tags[0]\ta, b\njson.tags[1]\t\"c\"\n" +
+				"json[\"a key\"]\t\"\\\"quoted\\\"\"\njson.height\tnull\n",
+			OptFromTSV,
+		},
+		{
+			"path,value\njson.name,Tom\njson.id,1\njson.admin,false\n" +
+				"json.tags[0],\"a, b\"\njson.tags[1],\"\"\"c\"\"\"\n" +
+				"\"json[\"\"a key\"\"]\",\"\"\"\\\"\"quoted\\\"\"\"\"\"\njson.height,null\n",
+			OptFromCSV,
+		},
+	}
+
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		code, err := Ungron(strings.NewReader(c.in), out, OptMonochrome|c.opts)
+		if code != ExitOK {
+			t.Errorf("want ExitOK; have %d", code)
+		}
+		if err != nil {
+			t.Errorf("want nil error; have %s", err)
+		}
+
+		var have interface{}
+		err = json.Unmarshal(out.Bytes(), &have)
+		if err != nil {
+			t.Fatalf("failed to unmarshal JSON from ungron output: %s", err)
+		}
+		if !reflect.DeepEqual(want, have) {
+			t.Logf("want: %#v", want)
+			t.Logf("have: %#v", have)
+			t.Errorf("ungronned columns do not match for opts %d", c.opts)
+		}
+	}
+}
+
+func TestGronInlineScalarArrays(t *testing.T) {
+	in := `{"tags": ["a", "b]\"", 1.50, true, null], "empty": [], "nested": [1, [2]]}`
+
+	out := &bytes.Buffer{}
+	code, err := Gron(strings.NewReader(in), out, OptMonochrome|OptInlineScalarArrays)
+	if code != ExitOK {
+		t.Errorf("want ExitOK; have %d", code)
+	}
+	if err != nil {
+		t.Errorf("want nil error; have %s", err)
+	}
+
+	want := `json = {};
+json.empty = [];
+json.nested = [];
+json.nested[0] = 1;
+json.nested[1] = [2];
+json.tags = ["a","b]\"",1.50,true,null];
+`
+	if out.String() != want {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", out.String())
+		t.Fatalf("inline array output does not match")
+	}
+
+	var wantJSON interface{}
+	err = json.Unmarshal([]byte(in), &wantJSON)
+	if err != nil {
+		t.Fatalf("failed to unmarshal want JSON: %s", err)
+	}
+
+	for _, opts := range []int{0, OptJSON} {
+		grond := &bytes.Buffer{}
+		Gron(strings.NewReader(in), grond, OptMonochrome|OptInlineScalarArrays|opts)
+
+		ungrond := &bytes.Buffer{}
+		code, err = Ungron(grond, ungrond, OptMonochrome|opts)
+		if code != ExitOK {
+			t.Errorf("want ExitOK; have %d", code)
+		}
+		if err != nil {
+			t.Errorf("want nil error; have %s", err)
+		}
+
+		var have interface{}
+		err = json.Unmarshal(ungrond.Bytes(), &have)
+		if err != nil {
+			t.Fatalf("failed to unmarshal ungronned JSON: %s", err)
+		}
+		if !reflect.DeepEqual(have, wantJSON) {
+			t.Errorf("want %#v; have %#v", wantJSON, have)
+		}
+	}
+}
+
+func TestUngronCustomRoot(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"data = {};\ndata.users = [];\ndata.users[0] = \"Tom\";\n", `{"users":["Tom"]}`},
+		{"data.id = 1;\n", `{"id":1}`},
+		{"data.id = 1;\nother.id = 2;\n", `{"data":{"id":1},"other":{"id":2}}`},
+		{"json.id = 1;\n", `{"id":1}`},
+
+		// Lines that aren't assignments are skipped, so they don't stop
+		// the root being unwrapped
+		{"json.a = 1;\nfoo\n", `{"a":1}`},
+		{"json.a = 1;\njunk line;\n", `{"a":1}`},
+	}
+
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		code, err := Ungron(strings.NewReader(c.in), out, OptMonochrome)
+		if code != ExitOK {
+			t.Errorf("want ExitOK; have %d", code)
+		}
+		if err != nil {
+			t.Errorf("want nil error; have %s", err)
+		}
+
+		var have, want interface{}
+		json.Unmarshal(out.Bytes(), &have)
+		json.Unmarshal([]byte(c.want), &want)
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("want %#v for %q; have %#v", want, c.in, have)
+		}
+	}
+}
+
+func TestGronArrayAsSet(t *testing.T) {
+	a := `{"tags": ["b", "a", 2], "users": [{"name": "Tom", "id": 1}, {"id": 0}]}`
+	b := `{"users": [{"id": 0}, {"id": 1, "name": "Tom"}], "tags": [2, "a", "b"]}`
+
+	outA := &bytes.Buffer{}
+	outB := &bytes.Buffer{}
+	Gron(strings.NewReader(a), outA, OptMonochrome|OptArrayAsSet)
+	Gron(strings.NewReader(b), outB, OptMonochrome|OptArrayAsSet)
+
+	if outA.String() != outB.String() {
+		t.Logf("a: %s", outA)
+		t.Logf("b: %s", outB)
+		t.Errorf("want reordered arrays to give the same statements")
+	}
+
+	// Arrays are still ordered normally without the option
+	outA.Reset()
+	outB.Reset()
+	Gron(strings.NewReader(a), outA, OptMonochrome)
+	Gron(strings.NewReader(b), outB, OptMonochrome)
+	if outA.String() == outB.String() {
+		t.Errorf("want reordered arrays to give different statements without OptArrayAsSet")
+	}
+}
+
+func TestGronTrailingData(t *testing.T) {
+	cases := []struct {
+		in      string
+		opts    int
+		code    int
+		wantErr string
+	}{
+		{`{"a":1}`, 0, ExitOK, ""},
+		{"{\"a\":1}\n \t", 0, ExitOK, ""},
+		{`{"a":1}garbage`, 0, ExitFormStatements, "trailing data after JSON value at offset 7"},
+		{`{"a":1} {"b":2}`, 0, ExitFormStatements, "trailing data after JSON value at offset 8"},
+		{`{"a":1}garbage`, OptLenient, ExitOK, ""},
+	}
+
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		code, err := Gron(strings.NewReader(c.in), out, OptMonochrome|c.opts)
+		if code != c.code {
+			t.Errorf("want exit code %d for %q; have %d", c.code, c.in, code)
+		}
+		if c.wantErr == "" {
+			if err != nil {
+				t.Errorf("want nil error for %q; have %s", c.in, err)
+			}
+			if out.String() != "json = {};\njson.a = 1;\n" {
+				t.Errorf("want statements for %q; have %q", c.in, out.String())
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+			t.Errorf("want error containing %q for %q; have %v", c.wantErr, c.in, err)
+		}
+	}
+}
+
+func TestUngronSplitOn(t *testing.T) {
+	in := "json = {};\njson.id = 1;\n\n\njson = {};\njson.id = 2;\njson.tags = [];\njson.tags[0] = \"a\";\n\n"
+
+	cases := []struct {
+		opts int
+		want string
+	}{
+		{OptMonochrome, "[\n  {\n    \"id\": 1\n  },\n  {\n    \"id\": 2,\n    \"tags\": [\n      \"a\"\n    ]\n  }\n]\n"},
+		{OptMonochrome | OptNDJSON, "{\"id\":1}\n{\"id\":2,\"tags\":[\"a\"]}\n"},
+	}
+
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		code, err := Ungron(strings.NewReader(in), out, c.opts, WithSplitOn(regexp.MustCompile(`^\s*$`)))
+		if code != ExitOK {
+			t.Errorf("want ExitOK; have %d", code)
+		}
+		if err != nil {
+			t.Errorf("want nil error; have %s", err)
+		}
+		if out.String() != c.want {
+			t.Errorf("want %q; have %q", c.want, out.String())
+		}
+	}
+}
+
+func TestGronFloatFormat(t *testing.T) {
+	in := `{"price": 1.005, "count": 3, "big": 1e21, "name": "1.5"}`
+
+	out := &bytes.Buffer{}
+	code, err := Gron(strings.NewReader(in), out, OptMonochrome, WithFloatFormat("%.2f"))
+	if code != ExitOK {
+		t.Errorf("want ExitOK; have %d", code)
+	}
+	if err != nil {
+		t.Errorf("want nil error; have %s", err)
+	}
+
+	want := `json = {};
+json.big = 1000000000000000000000.00;
+json.count = 3.00;
+json.name = "1.5";
+json.price = 1.00;
+`
+	if out.String() != want {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", out.String())
+		t.Errorf("formatted output does not match")
+	}
+}
+
+func TestNumericStringsStayStrings(t *testing.T) {
+	in := `{"zip":"01234","id":"1234","neg":"-0","exp":"1e3","arr":["007","1.50"]}`
+
+	var want interface{}
+	json.Unmarshal([]byte(in), &want)
+
+	cases := []struct {
+		opts    int
+		options []Option
+	}{
+		{OptMonochrome, nil},
+		{OptMonochrome | OptJSON, nil},
+		{OptMonochrome | OptDeterministic, nil},
+		{OptMonochrome | OptInlineScalarArrays, nil},
+		{OptMonochrome | OptArrayAsSet, nil},
+		{OptMonochrome, []Option{WithFloatFormat("%.2f")}},
+	}
+
+	for _, c := range cases {
+		grond := &bytes.Buffer{}
+		code, err := Gron(strings.NewReader(in), grond, c.opts, c.options...)
+		if code != ExitOK || err != nil {
+			t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+		}
+		if !strings.Contains(grond.String(), `"01234"`) {
+			t.Errorf("want quoted \"01234\" in output with opts %d; have %s", c.opts, grond)
+		}
+
+		ungrond := &bytes.Buffer{}
+		code, err = Ungron(grond, ungrond, c.opts&(OptMonochrome|OptJSON))
+		if code != ExitOK || err != nil {
+			t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+		}
+
+		var have interface{}
+		json.Unmarshal(ungrond.Bytes(), &have)
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("want %#v with opts %d; have %#v", want, c.opts, have)
+		}
+	}
+}
+
+func TestLargeIntegersKeepPrecision(t *testing.T) {
+	in := `{"id": 10000000000000001, "neg": -9007199254740993, "f": 1.5}`
+
+	for _, action := range []ActionFn{Gron, GronStream} {
+		grond := &bytes.Buffer{}
+		code, err := action(strings.NewReader(in), grond, OptMonochrome)
+		if code != ExitOK || err != nil {
+			t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+		}
+		for _, want := range []string{"= 10000000000000001;", "= -9007199254740993;", "= 1.5;"} {
+			if !strings.Contains(grond.String(), want) {
+				t.Errorf("want %q in output; have %s", want, grond)
+			}
+		}
+	}
+
+	grond := &bytes.Buffer{}
+	Gron(strings.NewReader(in), grond, OptMonochrome)
+	ungrond := &bytes.Buffer{}
+	code, err := Ungron(grond, ungrond, OptMonochrome)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	for _, want := range []string{`"id": 10000000000000001`, `"neg": -9007199254740993`, `"f": 1.5`} {
+		if !strings.Contains(ungrond.String(), want) {
+			t.Errorf("want %q in ungronned output; have %s", want, ungrond)
+		}
+	}
+}
+
+func TestNumbersOutOfFloatRange(t *testing.T) {
+	in := `{"big": 1e400, "small": -1e400, "long": 1234567890123456789012345678901234567890}`
+	want := `json = {};
+json.big = 1e400;
+json.long = 1234567890123456789012345678901234567890;
+json.small = -1e400;
+`
+
+	// They're written as they are, even when normalized, and ungron back
+	for _, opts := range []int{OptMonochrome, OptDeterministic} {
+		grond := &bytes.Buffer{}
+		code, err := Gron(strings.NewReader(in), grond, opts)
+		if code != ExitOK || err != nil {
+			t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+		}
+		if grond.String() != want {
+			t.Errorf("want %s; have %s", want, grond)
+		}
+
+		ungrond := &bytes.Buffer{}
+		code, err = Ungron(grond, ungrond, OptMonochrome, WithIndent(""))
+		if code != ExitOK || err != nil {
+			t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+		}
+		wantJSON := `{"big":1e400,"long":1234567890123456789012345678901234567890,"small":-1e400}` + "\n"
+		if ungrond.String() != wantJSON {
+			t.Errorf("want %s; have %s", wantJSON, ungrond)
+		}
+	}
+
+	// Formatting them as a float64 would make them +Inf or -Inf
+	for _, n := range []string{"1e400", "-1e400"} {
+		code, err := Gron(strings.NewReader(`{"n": `+n+`}`), &bytes.Buffer{}, OptMonochrome, WithFloatFormat("%.2f"))
+		if code != ExitFormStatements || err == nil {
+			t.Fatalf("want ExitFormStatements and an error for %s; have %d and %v", n, code, err)
+		}
+		if !strings.Contains(err.Error(), "number "+n+" at json.n ") {
+			t.Errorf("want an error naming the number and path; have %s", err)
+		}
+	}
+
+	// Nothing is written before the error, whichever way
+	// the document is gronned
+	ok := `{"a": 1, "z": 1e400}`
+	for _, fn := range []ActionFn{Gron, Diff, GronDiff} {
+		out := &bytes.Buffer{}
+		code, err := fn(strings.NewReader(ok), out, OptMonochrome, WithFloatFormat("%.2f"), WithBase(strings.NewReader(`{}`)))
+		if code != ExitFormStatements || err == nil {
+			t.Errorf("want ExitFormStatements and an error; have %d and %v", code, err)
+		}
+		if out.Len() != 0 {
+			t.Errorf("want no output before the error; have %q", out.String())
+		}
+	}
+	inputsOut := &bytes.Buffer{}
+	code, err := GronInputs([]io.Reader{strings.NewReader(ok)}, inputsOut, OptMonochrome, WithFloatFormat("%.2f"))
+	if code != ExitFormStatements || err == nil || inputsOut.Len() != 0 {
+		t.Errorf("want ExitFormStatements, an error and no output from GronInputs; have %d, %v and %q", code, err, inputsOut.String())
+	}
+
+	out := &bytes.Buffer{}
+	Gron(strings.NewReader(`{"n": 1234567890123456789012345678901234567890}`), out, OptMonochrome, WithFloatFormat("%.0f"))
+	if !strings.Contains(out.String(), "json.n = 1234567890123456846996462118072609669120;") {
+		t.Errorf("want the long integer formatted as a float64; have %s", out)
+	}
+}
+
+func TestNumericKeysKeepType(t *testing.T) {
+	cases := []struct {
+		in   string
+		want interface{}
+	}{
+		{`{"0": "x"}`, map[string]interface{}{"0": "x"}},
+		{`["x"]`, []interface{}{"x"}},
+		{`{"0": ["x"], "1": {"0": null}}`, map[string]interface{}{"0": []interface{}{"x"}, "1": map[string]interface{}{"0": nil}}},
+		{`[{"0": "x"}]`, []interface{}{map[string]interface{}{"0": "x"}}},
+	}
+
+	for _, c := range cases {
+		for _, opts := range []int{OptMonochrome, OptMonochrome | OptJSON} {
+			grond := &bytes.Buffer{}
+			if _, err := Gron(strings.NewReader(c.in), grond, opts); err != nil {
+				t.Fatalf("want nil error; have %s", err)
+			}
+			ungrond := &bytes.Buffer{}
+			if _, err := Ungron(grond, ungrond, opts); err != nil {
+				t.Fatalf("want nil error; have %s", err)
+			}
+
+			var have interface{}
+			json.Unmarshal(ungrond.Bytes(), &have)
+			if !reflect.DeepEqual(have, c.want) {
+				t.Errorf("want %#v for %s with opts %d; have %#v", c.want, c.in, opts, have)
+			}
+		}
+	}
+
+	// Without the statements for the containers,
+	// the notation alone decides what they are
+	for in, want := range map[string]string{
+		`json["0"] = "x";`: `{"0":"x"}`,
+		`json[0] = "x";`:   `["x"]`,
+	} {
+		out := &bytes.Buffer{}
+		Ungron(strings.NewReader(in), out, OptMonochrome, WithIndent(""))
+		if strings.TrimSpace(out.String()) != want {
+			t.Errorf("want %s for %s; have %s", want, in, out)
+		}
+	}
+}
+
+func TestGronRoot(t *testing.T) {
+	in := `{"name": "Tom", "tags": ["a"]}`
+
+	out := &bytes.Buffer{}
+	code, err := Gron(strings.NewReader(in), out, OptMonochrome, WithRoot("users"), WithNamespace("prod"))
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+
+	want := `users = {};
+users.prod = {};
+users.prod.name = "Tom";
+users.prod.tags = [];
+users.prod.tags[0] = "a";
+`
+	if out.String() != want {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", out.String())
+		t.Fatalf("output with a custom root does not match")
+	}
+
+	ungrond := &bytes.Buffer{}
+	code, err = Ungron(out, ungrond, OptMonochrome, WithNamespace("prod"))
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+
+	var have, wantJSON interface{}
+	json.Unmarshal(ungrond.Bytes(), &have)
+	json.Unmarshal([]byte(in), &wantJSON)
+	if !reflect.DeepEqual(have, wantJSON) {
+		t.Errorf("want %#v; have %#v", wantJSON, have)
+	}
+
+	stream := &bytes.Buffer{}
+	_, err = GronStream(strings.NewReader("1\n2\n"), stream, OptMonochrome, WithRoot("lines"))
+	if err != nil {
+		t.Fatalf("want nil error; have %s", err)
+	}
+	wantStream := "lines = [];\nlines[0] = 1;\nlines[1] = 2;\n"
+	if stream.String() != wantStream {
+		t.Errorf("want %q; have %q", wantStream, stream.String())
+	}
+}
+
+func TestGronValues(t *testing.T) {
+	in := `{"users": [{"name": "Tom", "admin": true}, {"name": "Bob", "age": 30.5, "tags": []}], "next": null}`
+
+	out := &bytes.Buffer{}
+	code, err := Gron(strings.NewReader(in), out, OptMonochrome|OptValues)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+
+	want := `null
+true
+"Tom"
+30.5
+"Bob"
+`
+	if out.String() != want {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", out.String())
+		t.Errorf("values output does not match")
+	}
+
+	// Values keep their color, and take precedence over OptJSON
+	out.Reset()
+	_, err = Gron(strings.NewReader(`{"name": "Tom"}`), out, OptValues|OptJSON)
+	if err != nil {
+		t.Fatalf("want nil error; have %s", err)
+	}
+	wantColor := token{`"Tom"`, typString}.formatColor() + "\n"
+	if out.String() != wantColor {
+		t.Errorf("want %q; have %q", wantColor, out.String())
+	}
+}
+
+func TestGronToStatements(t *testing.T) {
+	in := `{"name": "Tom", "tags": ["a", "b"]}`
+
+	ss, err := GronToStatements(strings.NewReader(in), OptMonochrome)
+	if err != nil {
+		t.Fatalf("want nil error; have %s", err)
+	}
+	want := []string{
+		`json = {};`,
+		`json.name = "Tom";`,
+		`json.tags = [];`,
+		`json.tags[0] = "a";`,
+		`json.tags[1] = "b";`,
+	}
+	if !reflect.DeepEqual(ss, want) {
+		t.Errorf("want %#v; have %#v", want, ss)
+	}
+
+	// The statements round trip
+	out, err := UngronFromStatements(ss, OptMonochrome)
+	if err != nil {
+		t.Fatalf("want nil error; have %s", err)
+	}
+	wantJSON := "{\n  \"name\": \"Tom\",\n  \"tags\": [\n    \"a\",\n    \"b\"\n  ]\n}\n"
+	if string(out) != wantJSON {
+		t.Errorf("want %q; have %q", wantJSON, out)
+	}
+
+	_, err = GronToStatements(strings.NewReader(`{"name": `), OptMonochrome)
+	if err == nil {
+		t.Errorf("want error for invalid JSON; have nil")
+	}
+	_, err = UngronFromStatements([]string{`json.name = `}, OptMonochrome)
+	if err == nil {
+		t.Errorf("want error for invalid statements; have nil")
+	}
+}
+
+func TestUngronIndent(t *testing.T) {
+	in := "json = {};\njson.tags = [];\njson.tags[0] = \"a\";\n"
+
+	tests := []struct {
+		opts   int
+		indent string
+		want   string
+	}{
+		{OptMonochrome, "\t", "{\n\t\"tags\": [\n\t\t\"a\"\n\t]\n}\n"},
+		{OptMonochrome, "    ", "{\n    \"tags\": [\n        \"a\"\n    ]\n}\n"},
+		{OptMonochrome, "", "{\"tags\":[\"a\"]}\n"},
+
+		// Compact output isn't colorized
+		{0, "", "{\"tags\":[\"a\"]}\n"},
+	}
+
+	for _, test := range tests {
+		out := &bytes.Buffer{}
+		code, err := Ungron(strings.NewReader(in), out, test.opts, WithIndent(test.indent))
+		if code != ExitOK || err != nil {
+			t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+		}
+		if out.String() != test.want {
+			t.Errorf("want %q for indent %q; have %q", test.want, test.indent, out.String())
+		}
+	}
+}
+
+func TestGronStreamSkipErrors(t *testing.T) {
+	in := "{\"a\": 1}\n{\"a\": \n{\"a\": 3}\n"
+
+	// Without OptSkipErrors the first bad line stops everything
+	out := &bytes.Buffer{}
+	code, err := GronStream(strings.NewReader(in), out, OptMonochrome)
+	if code != ExitFormStatements || err == nil {
+		t.Errorf("want ExitFormStatements and an error; have %d and %v", code, err)
+	}
+
+	var warnings []string
+	warn := WithWarnings(func(msg string) {
+		warnings = append(warnings, msg)
+	})
+
+	out.Reset()
+	code, err = GronStream(strings.NewReader(in), out, OptMonochrome|OptSkipErrors, warn)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	want := "json = [];\njson[0] = {};\njson[0].a = 1;\njson[2] = {};\njson[2].a = 3;\n"
+	if out.String() != want {
+		t.Errorf("want %q; have %q", want, out.String())
+	}
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "skipping line 2 (json[1]): ") {
+		t.Errorf("want one warning about line 2; have %q", warnings)
+	}
+
+	// Input with no valid lines at all is still a failure
+	out.Reset()
+	code, err = GronStream(strings.NewReader("nope\n{\n"), out, OptMonochrome|OptSkipErrors)
+	if code != ExitNoValidLines || err == nil {
+		t.Errorf("want ExitNoValidLines and an error; have %d and %v", code, err)
+	}
+}
+
+func TestGronStreamKey(t *testing.T) {
+	in := "{\"id\": \"abc\", \"n\": 1}\n{\"id\": 42}\n{\"n\": 3}\n[1]\n"
+
+	var warnings []string
+	warn := WithWarnings(func(msg string) {
+		warnings = append(warnings, msg)
+	})
+
+	out := &bytes.Buffer{}
+	code, err := GronStream(strings.NewReader(in), out, OptMonochrome, WithStreamKey("id"), warn)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	want := `json = {};
+json.abc = {};
+json.abc.id = "abc";
+json.abc.n = 1;
+json["42"] = {};
+json["42"].id = 42;
+json["#2"] = {};
+json["#2"].n = 3;
+json["#3"] = [];
+json["#3"][0] = 1;
+`
+	if out.String() != want {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", out.String())
+		t.Errorf("keyed stream output does not match")
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[0], `using json["#2"]`) {
+		t.Errorf("want warnings about lines 3 and 4; have %q", warnings)
+	}
+
+	code, err = Ungron(out, &bytes.Buffer{}, OptMonochrome)
+	if code != ExitOK || err != nil {
+		t.Errorf("want ExitOK and nil error from ungron; have %d and %v", code, err)
+	}
+
+	// Lines without the field don't take the key of a line with it
+	in = "{\"id\": 2, \"n\": 2}\n{\"n\": 3}\n{\"n\": 4}\n"
+	out.Reset()
+	code, err = GronStream(strings.NewReader(in), out, OptMonochrome, WithStreamKey("id"))
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	js := &bytes.Buffer{}
+	Ungron(out, js, OptMonochrome|OptNoSort, WithIndent(""))
+	if want := `{"#1":{"n":3},"#2":{"n":4},"2":{"id":2,"n":2}}` + "\n"; js.String() != want {
+		t.Errorf("want %s; have %s", want, js.String())
+	}
+}
+
+func TestGronStreamKeyRepeated(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"{\"id\": \"a\", \"n\": 1}\n{\"id\": \"a\", \"n\": 2}\n", `line 2 has the same key as line 1: json.a`},
+		{"{\"id\": 1}\n{\"id\": \"1\"}\n", `line 2 has the same key as line 1: json["1"]`},
+		{"{\"n\": 1}\n{\"id\": \"#0\"}\n", `line 2 has the same key as line 1: json["#0"]`},
+	}
+
+	for _, test := range tests {
+		code, err := GronStream(strings.NewReader(test.in), &bytes.Buffer{}, OptMonochrome, WithStreamKey("id"))
+		if code != ExitFormStatements || err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("want ExitFormStatements and an error containing %q for %q; have %d and %v", test.want, test.in, code, err)
+		}
+	}
+
+	// With OptSkipErrors the later line is skipped with a warning
+	var warnings []string
+	warn := WithWarnings(func(msg string) {
+		warnings = append(warnings, msg)
+	})
+	out := &bytes.Buffer{}
+	code, err := GronStream(strings.NewReader(tests[0].in), out, OptMonochrome|OptSkipErrors, WithStreamKey("id"), warn)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	want := "json = {};\njson.a = {};\njson.a.id = \"a\";\njson.a.n = 1;\n"
+	if out.String() != want {
+		t.Errorf("want %q; have %q", want, out.String())
+	}
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], tests[0].want) {
+		t.Errorf("want a warning about line 2; have %q", warnings)
+	}
+}
+
+func TestGronStreamArrayStart(t *testing.T) {
+	in := "{\"a\": 1}\n[2]\n"
+
+	out := &bytes.Buffer{}
+	code, err := GronStream(strings.NewReader(in), out, OptMonochrome, WithArrayStart(1000))
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	want := `json = [];
+json[1000] = {};
+json[1000].a = 1;
+json[1001] = [];
+json[1001][0] = 2;
+`
+	if out.String() != want {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", out.String())
+		t.Errorf("stream output does not start at the index given")
+	}
+}
+
+func TestGronMaxDepth(t *testing.T) {
+	in := `{"a": {"b": {"c": 1}, "tags": ["x"]}, "n": 2}`
+
+	tests := []struct {
+		depth int
+		want  string
+	}{
+		{0, "json = {};\n"},
+		{1, "json = {};\njson.a = {};\njson.n = 2;\n"},
+		{2, "json = {};\njson.a = {};\njson.a.b = {};\njson.a.tags = [];\njson.n = 2;\n"},
+	}
+
+	for _, test := range tests {
+		out := &bytes.Buffer{}
+		code, err := Gron(strings.NewReader(in), out, OptMonochrome, WithMaxDepth(test.depth))
+		if code != ExitOK || err != nil {
+			t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+		}
+		if out.String() != test.want {
+			t.Errorf("want %q for depth %d; have %q", test.want, test.depth, out.String())
+		}
+	}
+
+	// Arrays at the cutoff aren't written inline
+	out := &bytes.Buffer{}
+	Gron(strings.NewReader(in), out, OptMonochrome|OptInlineScalarArrays, WithMaxDepth(2))
+	if !strings.Contains(out.String(), "json.a.tags = [];\n") {
+		t.Errorf("want json.a.tags = []; in output; have %q", out.String())
+	}
+
+	// The depth of each line in a stream is counted from the line
+	out.Reset()
+	code, err := GronStream(strings.NewReader(in+"\n"), out, OptMonochrome, WithMaxDepth(1))
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	want := "json = [];\njson[0] = {};\njson[0].a = {};\njson[0].n = 2;\n"
+	if out.String() != want {
+		t.Errorf("want %q; have %q", want, out.String())
+	}
+}
+
+func TestUngronStrict(t *testing.T) {
+	in := "json.foo = 1;\njson.foo = 2;\n"
+
+	out := &bytes.Buffer{}
+	code, err := Ungron(strings.NewReader(in), out, OptMonochrome|OptStrict)
+	if code != ExitParseStatements || err == nil {
+		t.Fatalf("want ExitParseStatements and an error; have %d and %v", code, err)
+	}
+	if !strings.Contains(err.Error(), "json.foo: 1 and 2") {
+		t.Errorf("want the path and both values in the error; have %s", err)
+	}
+
+	// The same value twice is fine, even for numbers
+	out.Reset()
+	code, err = Ungron(strings.NewReader("json.foo = 1;\njson.foo = 1;\n"), out, OptMonochrome|OptStrict)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	want := "{\n  \"foo\": 1\n}\n"
+	if out.String() != want {
+		t.Errorf("want %q; have %q", want, out.String())
+	}
+}
+
+func TestGronSortsIndexesNumerically(t *testing.T) {
+	in := `{"a": [0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11], "b": [[0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10]]}`
+
+	for _, opts := range []int{OptMonochrome, OptMonochrome | OptPreorder, OptMonochrome | OptDeterministic} {
+		out := &bytes.Buffer{}
+		code, err := Gron(strings.NewReader(in), out, opts)
+		if code != ExitOK || err != nil {
+			t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+		}
+
+		have := out.String()
+		for _, pair := range [][2]string{
+			{"json.a[2] = 2;", "json.a[10] = 10;"},
+			{"json.a[9] = 9;", "json.a[11] = 11;"},
+			{"json.b[0][2] = 2;", "json.b[0][10] = 10;"},
+		} {
+			i, j := strings.Index(have, pair[0]), strings.Index(have, pair[1])
+			if i == -1 || j == -1 || i > j {
+				t.Errorf("want %s before %s with opts %d; have %s", pair[0], pair[1], opts, have)
+			}
+		}
+	}
+}
+
+func TestGronUnwrapStrings(t *testing.T) {
+	in := `{"payload": "{\"a\": 1, \"b\": \"[2]\"}", "list": " [true] ", "text": "{not json", "num": "1"}`
+
+	out := &bytes.Buffer{}
+	code, err := Gron(strings.NewReader(in), out, OptMonochrome|OptUnwrapStrings)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+
+	want := `json = {};
+json.list = [];
+json.list[0] = true;
+json.num = "1";
+json.payload = {};
+json.payload.a = 1;
+json.payload.b = [];
+json.payload.b[0] = 2;
+json.text = "{not json";
+`
+	if out.String() != want {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", out.String())
+		t.Errorf("unwrapped output does not match")
+	}
+}
+
+func TestGronTruncate(t *testing.T) {
+	in := `{"short": "abcde", "long": "abcdefgh", "accents": "ééééé\"é", "n": 1234567}`
+
+	out := &bytes.Buffer{}
+	code, err := Gron(strings.NewReader(in), out, OptMonochrome, WithTruncate(5))
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+
+	want := `json = {};
+json.accents = "ééééé…"; // truncated 3 bytes
+json.long = "abcde…"; // truncated 3 bytes
+json.n = 1234567;
+json.short = "abcde";
+`
+	if out.String() != want {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", out.String())
+		t.Errorf("truncated output does not match")
+	}
+
+	// The sink gets whole strings
+	var sunk []string
+	_, err = Gron(strings.NewReader(in), &bytes.Buffer{}, OptMonochrome, WithTruncate(5), WithStatementSink(func(s Statement) {
+		sunk = append(sunk, s.String())
+	}))
+	if err != nil {
+		t.Fatalf("want nil error; have %s", err)
+	}
+	if sunk[2] != `json.long = "abcdefgh";` {
+		t.Errorf("want the sink to get the whole string; have %s", sunk[2])
+	}
+}
+
+func TestGronSkipNulls(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{`{"a": null, "b": null}`, "json = {};\n"},
+		{`{"a": {"b": null, "c": 1}, "d": [null, 2]}`, "json = {};\njson.a = {};\njson.a.c = 1;\njson.d = [];\njson.d[1] = 2;\n"},
+		{`null`, "json = null;\n"},
+	}
+
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		code, err := Gron(strings.NewReader(c.in), out, OptMonochrome|OptSkipNulls)
+		if code != ExitOK || err != nil {
+			t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+		}
+		if out.String() != c.want {
+			t.Errorf("want %q for %s; have %q", c.want, c.in, out.String())
+		}
+	}
+}
+
+func TestEmptyInput(t *testing.T) {
+	actions := []struct {
+		name string
+		fn   ActionFn
+		opts int
+	}{
+		{"Gron", Gron, OptMonochrome},
+		{"Gron YAML", Gron, OptMonochrome | OptYAML},
+		{"GronStream", GronStream, OptMonochrome},
+		{"Ungron", Ungron, OptMonochrome},
+		{"UngronStream", UngronStream, OptMonochrome},
+	}
+
+	for _, a := range actions {
+		for _, in := range []string{"", " \n\t\r\n"} {
+			out := &bytes.Buffer{}
+			code, err := a.fn(strings.NewReader(in), out, a.opts)
+			if code != ExitEmptyInput || err != ErrEmptyInput {
+				t.Errorf("want ExitEmptyInput and ErrEmptyInput from %s for %q; have %d and %v", a.name, in, code, err)
+			}
+			if out.Len() != 0 {
+				t.Errorf("want no output from %s for %q; have %q", a.name, in, out)
+			}
+		}
+	}
+
+	// Leading whitespace is still there for input that isn't empty
+	out := &bytes.Buffer{}
+	code, err := GronStream(strings.NewReader("\n{\"a\": 1}\n"), out, OptMonochrome|OptSkipErrors, WithWarnings(func(msg string) {
+		if !strings.HasPrefix(msg, "skipping line 1 ") {
+			t.Errorf("want a warning about line 1; have %s", msg)
+		}
+	}))
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	if !strings.Contains(out.String(), "json[1].a = 1;") {
+		t.Errorf("want the second line at index 1; have %s", out)
+	}
+}
+
+func TestUngronSplitKeys(t *testing.T) {
+	in := `config = {};
+config.port = 80;
+secrets = {};
+secrets.key = "abc";
+secrets.list = [];
+`
+
+	out := &bytes.Buffer{}
+	code, err := Ungron(strings.NewReader(in), out, OptMonochrome|OptSplitKeys)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	want := "--- # config\n{\n  \"port\": 80\n}\n--- # secrets\n{\n  \"key\": \"abc\",\n  \"list\": []\n}\n"
+	if out.String() != want {
+		t.Errorf("want %q; have %q", want, out.String())
+	}
+
+	// With a single root, it's that root's keys that are split
+	out.Reset()
+	in = "json = {};\njson[\"odd key\"] = [1];\njson.b = true;\n"
+	code, err = Ungron(strings.NewReader(in), out, OptMonochrome|OptSplitKeys|OptNDJSON)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	want = "true\n[1]\n"
+	if out.String() != want {
+		t.Errorf("want %q; have %q", want, out.String())
+	}
+
+	code, err = Ungron(strings.NewReader("json = [];\n"), &bytes.Buffer{}, OptMonochrome|OptSplitKeys)
+	if code != ExitParseStatements || err == nil {
+		t.Errorf("want ExitParseStatements and an error for an array; have %d and %v", code, err)
+	}
+}
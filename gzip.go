@@ -0,0 +1,21 @@
+package gron
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+)
+
+// Decompress returns a reader for the decompressed contents of r
+// if it's gzipped, as detected from its first two bytes; otherwise
+// it returns a reader for r unchanged. An error is returned if r
+// looks gzipped but the gzip header can't be read.
+func Decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+		// Input too short to be gzipped is left for the action to read
+		return br, nil
+	}
+	return gzip.NewReader(br)
+}
@@ -0,0 +1,51 @@
+package gron
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func gzipped(t *testing.T, s string) []byte {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	gz.Write([]byte(s))
+	if err := gz.Close(); err != nil {
+		t.Fatalf("want nil error gzipping; have %s", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	tests := []struct {
+		in   []byte
+		want string
+	}{
+		{gzipped(t, `{"name": "Tom"}`), `{"name": "Tom"}`},
+		{[]byte(`{"name": "Tom"}`), `{"name": "Tom"}`},
+		{[]byte("{"), "{"},
+		{[]byte{0x1f}, "\x1f"},
+		{[]byte{}, ""},
+	}
+
+	for _, test := range tests {
+		r, err := Decompress(bytes.NewReader(test.in))
+		if err != nil {
+			t.Fatalf("want nil error for %q; have %s", test.in, err)
+		}
+		have, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("want nil error reading %q; have %s", test.in, err)
+		}
+		if string(have) != test.want {
+			t.Errorf("want %q; have %q", test.want, have)
+		}
+	}
+
+	_, err := Decompress(strings.NewReader("\x1f\x8bnot really gzip"))
+	if err == nil {
+		t.Errorf("want error for a broken gzip header; have nil")
+	}
+}
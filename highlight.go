@@ -0,0 +1,64 @@
+package gron
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// a highlight is a color for values matching a pattern
+type highlight struct {
+	pattern *regexp.Regexp
+	sprint  sprintFn
+}
+
+// WithHighlight colors values that match a pattern with the provided
+// color rather than the usual color for their type; e.g. to make
+// strings like "error" stand out in red. Patterns are matched against
+// the value as it would be in the JSON, with strings unquoted, and only
+// scalar values are highlighted. Where several patterns match a value
+// the first one to be added wins. Monochrome output isn't affected.
+func WithHighlight(pattern *regexp.Regexp, col *color.Color) Option {
+	return func(c *config) {
+		c.highlights = append(c.highlights, highlight{pattern, col.SprintFunc()})
+	}
+}
+
+// highlight returns the sprintFn for the first highlight
+// that matches the value of a token, if there is one
+func (c *config) highlight(t token) (sprintFn, bool) {
+	var v string
+	switch t.typ {
+	case typString:
+		if err := json.Unmarshal([]byte(t.text), &v); err != nil {
+			return nil, false
+		}
+	case typNumber, typTrue, typFalse, typNull:
+		v = t.text
+	default:
+		return nil, false
+	}
+
+	for _, h := range c.highlights {
+		if h.pattern.MatchString(v) {
+			return h.sprint, true
+		}
+	}
+	return nil, false
+}
+
+// highlightedColorString is a statementconv like statementToColorString,
+// except that values matching a highlight use its color instead
+func (c *config) highlightedColorString(s statement) string {
+	out := make([]string, 0, len(s)+2)
+	for _, t := range s {
+		if fn, ok := c.highlight(t); ok {
+			out = append(out, fn(t.text))
+			continue
+		}
+		out = append(out, t.formatColor())
+	}
+	return strings.Join(out, "")
+}
@@ -0,0 +1,79 @@
+package gron
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestGronHighlight(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+
+	red := color.New(color.FgRed)
+	green := color.New(color.FgGreen)
+
+	in := `{"level": "error", "ok": true, "msg": "error: disk full", "code": 500}`
+	out := &bytes.Buffer{}
+	code, err := Gron(
+		strings.NewReader(in), out, 0,
+		WithHighlight(regexp.MustCompile(`^(error|fatal)$`), red),
+		WithHighlight(regexp.MustCompile(`^(ok|true)$`), green),
+		WithHighlight(regexp.MustCompile(`error`), green),
+	)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+
+	have := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := []string{
+		statement{{"json", typBare}, {"=", typEquals}, {"{}", typEmptyObject}, {";", typSemi}}.colorString(),
+	}
+
+	// Highlights take precedence over the default colors for each
+	// type, and the first highlight to match a value wins
+	values := []struct {
+		key   string
+		value token
+		fn    sprintFn
+	}{
+		{"code", token{"500", typNumber}, sprintFns[typNumber]},
+		{"level", token{`"error"`, typString}, red.SprintFunc()},
+		{"msg", token{`"error: disk full"`, typString}, green.SprintFunc()},
+		{"ok", token{"true", typTrue}, green.SprintFunc()},
+	}
+	for _, v := range values {
+		path := statement{{"json", typBare}, {".", typDot}, {v.key, typBare}, {"=", typEquals}}
+		want = append(want, path.colorString()+v.fn(v.value.text)+sprintFns[typSemi](";"))
+	}
+
+	if len(have) != len(want) {
+		t.Fatalf("want %d statements; have %d: %q", len(want), len(have), have)
+	}
+	for i := range want {
+		if have[i] != want[i] {
+			t.Errorf("want statement %d to be %q; have %q", i, want[i], have[i])
+		}
+	}
+}
+
+func TestGronHighlightMonochrome(t *testing.T) {
+	in := `{"level": "error"}`
+	out := &bytes.Buffer{}
+	_, err := Gron(
+		strings.NewReader(in), out, OptMonochrome,
+		WithHighlight(regexp.MustCompile(`error`), color.New(color.FgRed)),
+	)
+	if err != nil {
+		t.Fatalf("want nil error; have %s", err)
+	}
+
+	want := "json = {};\njson.level = \"error\";\n"
+	if out.String() != want {
+		t.Errorf("want %q; have %q", want, out.String())
+	}
+}
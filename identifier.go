@@ -42,6 +42,13 @@ var reservedWords = map[string]bool{
 	"yield":      true,
 }
 
+// ValidIdentifier returns true if a string can be written as a bare
+// word in a statement rather than quoted; which is a requirement for
+// the name given to WithRoot
+func ValidIdentifier(s string) bool {
+	return validIdentifier(s)
+}
+
 // validIdentifier checks to see if a string is a valid
 // JavaScript identifier
 // E.g:
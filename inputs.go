@@ -0,0 +1,126 @@
+package gron
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// WithInputNames makes GronInputs write each input under a key of a
+// top-level object, like json.users, rather than under an index of a
+// top-level array. There must be exactly one name for each input.
+func WithInputNames(names ...string) Option {
+	return func(c *config) {
+		c.inputNames = append(c.inputNames, names...)
+	}
+}
+
+// GronInputs is like the gron action, but it grons several inputs
+// at once as the elements of a top-level array; e.g. json[0].name and
+// json[1].name for the name in each of two inputs. So ungronning the
+// output gives an array of the inputs. With WithInputNames they're the
+// values of a top-level object instead; e.g. json.users.name.
+//
+// The inputs are JSON unless one of the options for another format,
+// like OptYAML, is set; in which case they all have to be in that format.
+//
+// The statements for all of the inputs are sorted together. Any limit
+// set with WithMaxInputBytes applies to each input separately.
+func GronInputs(rs []io.Reader, w io.Writer, opts int, options ...Option) (code int, err error) {
+	c := newConfig(options)
+	opts = resolveOpts(opts)
+	c.setOpts(opts)
+	defer c.checkInputSize(&code, &err)
+
+	if c.inputNames != nil && len(c.inputNames) != len(rs) {
+		return ExitUsage, fmt.Errorf("%d input names given for %d inputs", len(c.inputNames), len(rs))
+	}
+
+	prefix := c.prefix()
+	ss := c.namespaceStatements()
+	if c.inputNames != nil {
+		ss.addWithValue(prefix, token{"{}", typEmptyObject})
+	} else {
+		ss.addWithValue(prefix, token{"[]", typEmptyArray})
+	}
+
+	decode := decoderFor(opts)
+	for i, r := range rs {
+		sub, err := decode(c.limitInput(r), elementPrefix(prefix, i, c.inputNames), c)
+		if err != nil {
+			return ExitFormStatements, fmt.Errorf("failed to form statements for input %d: %s", i, err)
+		}
+		ss = append(ss, sub...)
+	}
+
+	sortStatements(ss, opts)
+	ss = c.filter(ss)
+	if err := c.checkFloatFormat(ss); err != nil {
+		return ExitFormStatements, fmt.Errorf("failed to form statements: %s", err)
+	}
+
+	for _, s := range ss {
+		err = writeStatement(w, s, opts, c)
+		if err != nil {
+			return ExitFormStatements, fmt.Errorf("failed to form statements: %s", err)
+		}
+	}
+	return ExitOK, nil
+}
+
+// linePrefix returns the prefix for the statements made from the i'th
+// line of input for GronStream, which has the value v; that's the value
+// of the stream key field if there is one, and otherwise the line's index.
+// Two lines can't have the same key, or one would be merged into the other
+func (c *config) linePrefix(prefix statement, i int, v interface{}) (statement, error) {
+	if c.streamKey == "" {
+		return c.lineIndexPrefix(prefix, i), nil
+	}
+
+	var key string
+	obj, _ := v.(map[string]interface{})
+	switch k := obj[c.streamKey].(type) {
+	case string:
+		key = k
+	case json.Number:
+		key = k.String()
+	case bool:
+		key = strconv.FormatBool(k)
+	default:
+		key = fmt.Sprintf("#%d", i+c.arrayStart)
+		c.warnf("line %d has no string, number or bool %q field to key it by; using %s", i+1, c.streamKey, prefix.withKey(key))
+	}
+
+	if c.streamKeys == nil {
+		c.streamKeys = make(map[string]int)
+	}
+	if first, used := c.streamKeys[key]; used {
+		return nil, fmt.Errorf("line %d has the same key as line %d: %s", i+1, first, prefix.withKey(key))
+	}
+	c.streamKeys[key] = i + 1
+	return prefix.withKey(key), nil
+}
+
+// lineIndexPrefix returns the prefix for the statements made from the
+// i'th line of input for GronStream by its index in an array; or, if
+// lines are keyed by a field, for messages about the line before its
+// key is known. Indexes start at the one set with WithArrayStart
+func (c *config) lineIndexPrefix(prefix statement, i int) statement {
+	i += c.arrayStart
+	if c.streamKey != "" {
+		return prefix.withKey(fmt.Sprintf("#%d", i))
+	}
+	return elementPrefix(prefix, i, nil)
+}
+
+// elementPrefix returns the prefix for the statements made from the
+// i'th of several values under a prefix; e.g. the lines of input for
+// GronStream. That's the i'th name if there are names, and otherwise
+// the i'th index of an array
+func elementPrefix(prefix statement, i int, names []string) statement {
+	if names != nil {
+		return prefix.withKey(names[i])
+	}
+	return prefix.withNumericKey(i)
+}
@@ -0,0 +1,73 @@
+package gron
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestGronInputs(t *testing.T) {
+	inputs := func() []io.Reader {
+		return []io.Reader{
+			strings.NewReader(`{"name": "Tom"}`),
+			strings.NewReader(`[1]`),
+		}
+	}
+
+	out := &bytes.Buffer{}
+	code, err := GronInputs(inputs(), out, OptMonochrome)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	want := "json = [];\njson[0] = {};\njson[0].name = \"Tom\";\njson[1] = [];\njson[1][0] = 1;\n"
+	if out.String() != want {
+		t.Errorf("want %q; have %q", want, out.String())
+	}
+
+	// The indexed form ungrons to an array of the inputs
+	ungrond := &bytes.Buffer{}
+	code, err = Ungron(out, ungrond, OptMonochrome, WithIndent(""))
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	if ungrond.String() != "[{\"name\":\"Tom\"},[1]]\n" {
+		t.Errorf("want an array of the inputs; have %q", ungrond.String())
+	}
+
+	out.Reset()
+	code, err = GronInputs(inputs(), out, OptMonochrome, WithInputNames("users", "first ids"))
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	want = "json = {};\njson.users = {};\njson.users.name = \"Tom\";\njson[\"first ids\"] = [];\njson[\"first ids\"][0] = 1;\n"
+	if out.String() != want {
+		t.Errorf("want %q; have %q", want, out.String())
+	}
+
+	_, err = GronInputs(inputs(), out, OptMonochrome, WithInputNames("users"))
+	if err == nil {
+		t.Errorf("want error for too few names; have nil")
+	}
+	code, err = GronInputs([]io.Reader{strings.NewReader(`{}`), strings.NewReader(`{`)}, out, OptMonochrome)
+	if code != ExitFormStatements || err == nil || !strings.Contains(err.Error(), "input 1") {
+		t.Errorf("want ExitFormStatements and an error for input 1; have %d and %v", code, err)
+	}
+}
+
+func TestGronInputsYAML(t *testing.T) {
+	inputs := []io.Reader{
+		strings.NewReader("name: Tom\n"),
+		strings.NewReader("name: Bob\n"),
+	}
+
+	out := &bytes.Buffer{}
+	code, err := GronInputs(inputs, out, OptMonochrome|OptYAML)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	want := "json = [];\njson[0] = {};\njson[0].name = \"Tom\";\njson[1] = {};\njson[1].name = \"Bob\";\n"
+	if out.String() != want {
+		t.Errorf("want %q; have %q", want, out.String())
+	}
+}
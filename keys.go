@@ -0,0 +1,147 @@
+package gron
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// A KeysCase is a style that object keys can be converted to
+type KeysCase string
+
+// Supported key cases
+const (
+	// KeysSnake splits keys into words and joins them with underscores
+	// in lowercase; e.g. userID, user-id and UserId become user_id
+	KeysSnake KeysCase = "snake"
+
+	// KeysCamel splits keys into words and joins them with the first
+	// letter of each but the first capitalized; e.g. user_id becomes userId
+	KeysCamel KeysCase = "camel"
+
+	// KeysLower converts keys to lowercase, without splitting them
+	KeysLower KeysCase = "lower"
+
+	// KeysUpper converts keys to uppercase, without splitting them
+	KeysUpper KeysCase = "upper"
+)
+
+// KeysCases is the list of supported key cases
+var KeysCases = []KeysCase{KeysSnake, KeysCamel, KeysLower, KeysUpper}
+
+// WithKeysCase converts every object key in the input to the provided
+// case, so that keys from systems with different conventions can be
+// grepped for the same way. Values are left as they are.
+//
+// The original keys are lost, so ungronning the output gives keys in
+// the new case. Where two keys in the same object become the same key
+// a warning is given with WithWarnings, and their values are merged.
+// Words are split at anything other than a letter or digit, and at
+// changes from lowercase to uppercase; e.g. HTTPServerID is split into
+// HTTP, Server and ID.
+func WithKeysCase(kc KeysCase) Option {
+	return func(c *config) {
+		c.keysCase = kc
+	}
+}
+
+// convert returns a key in the case
+func (kc KeysCase) convert(k string) string {
+	switch kc {
+	case KeysLower:
+		return strings.ToLower(k)
+	case KeysUpper:
+		return strings.ToUpper(k)
+	}
+
+	words := splitWords(k)
+	if len(words) == 0 {
+		return k
+	}
+
+	switch kc {
+	case KeysSnake:
+		for i, w := range words {
+			words[i] = strings.ToLower(w)
+		}
+		return strings.Join(words, "_")
+
+	case KeysCamel:
+		for i, w := range words {
+			w = strings.ToLower(w)
+			if i > 0 {
+				r := []rune(w)
+				r[0] = unicode.ToUpper(r[0])
+				w = string(r)
+			}
+			words[i] = w
+		}
+		return strings.Join(words, "")
+	}
+
+	return k
+}
+
+// splitWords splits a key into words, at anything other than a letter
+// or digit and at changes from lowercase (or a digit) to uppercase; the
+// last of a run of uppercase letters followed by a lowercase letter
+// starts a new word, so that HTTPServer becomes HTTP and Server
+func splitWords(s string) []string {
+	var words []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			words = append(words, string(cur))
+			cur = nil
+		}
+	}
+
+	runes := []rune(s)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(cur) > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		cur = append(cur, r)
+	}
+	flush()
+
+	return words
+}
+
+// convertKeys returns the keys of an object converted to the
+// configured case, in sorted order so that any warnings about
+// keys that collide are given in a consistent order; or with
+// OptKeepOrder, in the order they were in the input
+func (c *config) convertKeys(path statement, obj map[string]interface{}) ([]string, map[string]string) {
+	keys, ok := c.keyOrder.keys(obj)
+	if !ok {
+		keys = make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+	}
+
+	converted := make(map[string]string, len(keys))
+	from := make(map[string]string, len(keys))
+	for _, k := range keys {
+		ck := c.keysCase.convert(k)
+		if prev, exists := from[ck]; exists {
+			c.warnf(
+				"keys %s and %s in %s both become %s",
+				quoteString(prev), quoteString(k), path, quoteString(ck),
+			)
+		}
+		from[ck] = k
+		converted[k] = ck
+	}
+	return keys, converted
+}
@@ -0,0 +1,80 @@
+package gron
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKeysCaseConvert(t *testing.T) {
+	cases := []struct {
+		kc   KeysCase
+		in   string
+		want string
+	}{
+		{KeysSnake, "userID", "user_id"},
+		{KeysSnake, "UserId", "user_id"},
+		{KeysSnake, "user-id", "user_id"},
+		{KeysSnake, "HTTPServerID", "http_server_id"},
+		{KeysSnake, "version2Name", "version2_name"},
+		{KeysSnake, "already_snake", "already_snake"},
+		{KeysSnake, "--", "--"},
+
+		{KeysCamel, "user_id", "userId"},
+		{KeysCamel, "User ID", "userId"},
+		{KeysCamel, "HTTPServer", "httpServer"},
+		{KeysCamel, "alreadyCamel", "alreadyCamel"},
+		{KeysCamel, "", ""},
+
+		{KeysLower, "User_ID", "user_id"},
+		{KeysLower, "Ünïcode", "ünïcode"},
+
+		{KeysUpper, "user-id", "USER-ID"},
+		{KeysUpper, "userId", "USERID"},
+	}
+
+	for _, c := range cases {
+		have := c.kc.convert(c.in)
+		if have != c.want {
+			t.Errorf("want %s case of %q to be %q; have %q", c.kc, c.in, c.want, have)
+		}
+	}
+}
+
+func TestGronKeysCase(t *testing.T) {
+	in := `{"userID": 1, "user_id": 2, "Tags": {"FirstTag": "Leave Values Alone"}}`
+
+	var warnings []string
+	out := &bytes.Buffer{}
+	code, err := Gron(
+		strings.NewReader(in), out, OptMonochrome,
+		WithKeysCase(KeysSnake),
+		WithWarnings(func(msg string) {
+			warnings = append(warnings, msg)
+		}),
+	)
+	if code != ExitOK {
+		t.Errorf("want ExitOK; have %d", code)
+	}
+	if err != nil {
+		t.Errorf("want nil error; have %s", err)
+	}
+
+	want := `json = {};
+json.tags = {};
+json.tags.first_tag = "Leave Values Alone";
+json.user_id = 1;
+json.user_id = 2;
+`
+	if out.String() != want {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", out.String())
+		t.Errorf("converted output does not match")
+	}
+
+	wantWarnings := []string{`keys "userID" and "user_id" in json both become "user_id"`}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("want warnings %q; have %q", wantWarnings, warnings)
+	}
+}
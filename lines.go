@@ -0,0 +1,57 @@
+package gron
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// GronToStatements is like the gron action, but rather than writing
+// the statements to an io.Writer it returns them, one string per line
+// of output and without the newlines; so that they can be filtered or
+// re-sorted before they're used. The options are the same as Gron's.
+func GronToStatements(r io.Reader, opts int, options ...Option) ([]string, error) {
+	var lines []string
+	options = append(options[:len(options):len(options)], withOutput(func(line string) {
+		lines = append(lines, line)
+	}))
+
+	_, err := Gron(r, ioutil.Discard, opts, options...)
+	if err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// UngronFromStatements is like the ungron action, but it takes the
+// statements as a slice, one per element, and returns the JSON rather
+// than writing it to an io.Writer. The JSON is formatted just as Ungron
+// writes it, so OptMonochrome should usually be set.
+func UngronFromStatements(ss []string, opts int, options ...Option) ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := Ungron(strings.NewReader(strings.Join(ss, "\n")), &buf, opts, options...)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// withOutput passes each line of output to the provided function
+// instead of writing it to the action's io.Writer
+func withOutput(fn func(line string)) Option {
+	return func(c *config) {
+		c.output = fn
+	}
+}
+
+// writeLine writes a line of output to w, or passes it to the
+// output function if there is one
+func (c *config) writeLine(w io.Writer, line string) {
+	if c.output != nil {
+		c.output(line)
+		return
+	}
+	fmt.Fprintln(w, line)
+}
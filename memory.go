@@ -0,0 +1,199 @@
+package gron
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// MemoryPerInputByte is the number of bytes of memory that Gron is
+// estimated to need for each byte of input; covering the decoded
+// document, the statements made from it and sorting them. It's
+// deliberately pessimistic: deeply nested documents with short keys
+// and values need the most memory relative to their size.
+const MemoryPerInputByte = 16
+
+// WithMaxMemory limits the memory that Gron is allowed to use, in bytes,
+// as estimated from the size of the input with MemoryPerInputByte.
+//
+// Input that's estimated to need more than that is still accepted if
+// it's an array: its elements are decoded and written one at a time, so
+// only the largest element needs to fit. Anything else is refused with
+// an error before it's decoded. Only as much of the input as the limit
+// allows is read before deciding.
+func WithMaxMemory(n int64) Option {
+	return func(c *config) {
+		c.maxMemory = n
+	}
+}
+
+// limitMemory reads as much of r as the memory limit allows, returning
+// a reader for the whole input and true if the input exceeded the limit
+func (c *config) limitMemory(r io.Reader) (io.Reader, bool, error) {
+	buf := &bytes.Buffer{}
+	_, err := io.CopyN(buf, r, c.maxMemory/MemoryPerInputByte+1)
+	if err == io.EOF {
+		return buf, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return io.MultiReader(buf, r), true, nil
+}
+
+// gronArray is like the gron action, but it decodes and writes
+// the elements of a top-level array one at a time so that the
+// whole document never needs to be in memory at once
+func gronArray(r io.Reader, w io.Writer, opts int, c *config) (int, error) {
+	cr := &countingReader{r: r}
+	d := json.NewDecoder(cr)
+	d.UseNumber()
+
+	t, err := d.Token()
+	if err != nil {
+		return ExitFormStatements, fmt.Errorf("failed to form statements: %s", err)
+	}
+	if t != json.Delim('[') {
+		return ExitFormStatements, fmt.Errorf(
+			"input needs more than the maximum memory of %d bytes and isn't an array, so can't be streamed",
+			c.maxMemory,
+		)
+	}
+
+	// The first line of output needs to establish that the top-level
+	// thing is actually an array, as for GronStream
+	prefix := c.prefix()
+	top := c.namespaceStatements()
+	top.addWithValue(prefix, token{"[]", typEmptyArray})
+	if !c.parents {
+		top = c.filter(top)
+	}
+	for _, s := range top {
+		if err := writeStatement(w, s, opts, c); err != nil {
+			return ExitFormStatements, fmt.Errorf("failed to form statements: %s", err)
+		}
+	}
+
+	if c.tooDeep(0) {
+		return ExitOK, nil
+	}
+
+	// Array indexes are sorted numerically, so sorting the statements
+	// for each element in turn gives the same order as sorting them all
+	i := 0
+	for ; d.More(); i++ {
+		var v interface{}
+		if td := c.tokenDecoder(prefix.withNumericKey(i)); td != nil {
+			c.keyOrder.reset()
+			v, err = td.decode(d)
+		} else {
+			err = d.Decode(&v)
+		}
+		if err != nil {
+			return ExitFormStatements, fmt.Errorf("failed to form statements: %s", err)
+		}
+
+		k := i
+		if c.selectIndexes != nil {
+			var ok bool
+			if k, ok = c.selected(i); !ok {
+				continue
+			}
+		}
+
+		ss := make(statements, 0, 32)
+		ss.fill(prefix.withNumericKey(k), v, 1, c)
+		sortStatements(ss, opts)
+
+		for _, s := range c.filter(ss) {
+			if err := writeStatement(w, s, opts, c); err != nil {
+				return ExitFormStatements, fmt.Errorf("failed to form statements: %s", err)
+			}
+		}
+	}
+
+	if _, err := d.Token(); err != nil {
+		return ExitFormStatements, fmt.Errorf("failed to form statements: %s", err)
+	}
+	if c.selectIndexes != nil {
+		c.warnUnselected(i)
+	}
+	if !c.lenient {
+		if err := expectEOF(d, cr); err != nil {
+			return ExitFormStatements, fmt.Errorf("failed to form statements: %s", err)
+		}
+	}
+	return ExitOK, nil
+}
+
+// WithMaxInputBytes limits the input that Gron, GronStream and Ungron
+// will read to n bytes, so that input from an untrusted source can't
+// make them read without end. Input that's any longer fails with
+// ExitInputTooLarge as soon as the limit is passed; unlike with
+// WithMaxMemory, nothing beyond the limit is ever read.
+func WithMaxInputBytes(n int64) Option {
+	return func(c *config) {
+		c.maxInput = n
+	}
+}
+
+// limitInput wraps r so that no more than the maximum input size
+// can be read from it, if there is one
+func (c *config) limitInput(r io.Reader) io.Reader {
+	if c.maxInput <= 0 {
+		return r
+	}
+	c.input = &maxBytesReader{r: r, max: c.maxInput}
+	return c.input
+}
+
+// checkInputSize replaces the exit code and error returned by an action
+// with ExitInputTooLarge if it tried to read more than the maximum input
+// size; the error it got is only a symptom of the input being cut off
+func (c *config) checkInputSize(code *int, err *error) {
+	if c.input == nil || !c.input.exceeded {
+		return
+	}
+	*code = ExitInputTooLarge
+	*err = errInputTooLarge{c.maxInput}
+}
+
+// an errInputTooLarge is returned when the
+// input is longer than the maximum input size
+type errInputTooLarge struct {
+	max int64
+}
+
+func (e errInputTooLarge) Error() string {
+	return fmt.Sprintf("input too large: more than %d bytes", e.max)
+}
+
+// a maxBytesReader reads up to max bytes from r, and returns an
+// errInputTooLarge rather than reading any further
+type maxBytesReader struct {
+	r        io.Reader
+	max      int64
+	read     int64
+	exceeded bool
+}
+
+func (m *maxBytesReader) Read(p []byte) (int, error) {
+	if m.exceeded {
+		return 0, errInputTooLarge{m.max}
+	}
+
+	// Reading one more byte than is allowed tells
+	// the difference between input that's exactly
+	// the maximum size and input that's too large
+	if left := m.max - m.read + 1; int64(len(p)) > left {
+		p = p[:left]
+	}
+	n, err := m.r.Read(p)
+	m.read += int64(n)
+	if m.read > m.max {
+		m.exceeded = true
+		return n - 1, errInputTooLarge{m.max}
+	}
+	return n, err
+}
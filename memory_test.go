@@ -0,0 +1,88 @@
+package gron
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGronMaxMemory(t *testing.T) {
+	in := `[{"id": 2, "tags": ["a", "b"]}, {"id": 10}, 3, [], {}]`
+
+	want := &bytes.Buffer{}
+	_, err := Gron(strings.NewReader(in), want, OptMonochrome)
+	if err != nil {
+		t.Fatalf("want nil error; have %s", err)
+	}
+
+	// Big enough to hold the input, and too small to
+	for _, limit := range []int64{1 << 20, 16} {
+		out := &bytes.Buffer{}
+		code, err := Gron(strings.NewReader(in), out, OptMonochrome, WithMaxMemory(limit))
+		if code != ExitOK {
+			t.Errorf("want ExitOK; have %d", code)
+		}
+		if err != nil {
+			t.Errorf("want nil error; have %s", err)
+		}
+		if out.String() != want.String() {
+			t.Logf("want: %s", want)
+			t.Logf("have: %s", out)
+			t.Errorf("output with a memory limit of %d does not match", limit)
+		}
+	}
+
+	// Objects can't be streamed
+	out := &bytes.Buffer{}
+	code, err := Gron(strings.NewReader(`{"id": 1}`), out, OptMonochrome, WithMaxMemory(16))
+	if code != ExitFormStatements {
+		t.Errorf("want ExitFormStatements; have %d", code)
+	}
+	if err == nil {
+		t.Errorf("want non-nil error for an object that's too big")
+	}
+	if out.Len() != 0 {
+		t.Errorf("want no output; have %s", out)
+	}
+}
+
+func TestMaxInputBytes(t *testing.T) {
+	in := `{"a": [1, 2, 3], "b": "some text"}`
+	lines := "{\"a\": 1}\n{\"a\": 2}\n"
+	statements := "json.a = 1;\njson.b = 2;\n"
+
+	cases := []struct {
+		action ActionFn
+		in     string
+		max    int64
+		want   int
+	}{
+		{Gron, in, int64(len(in)), ExitOK},
+		{Gron, in, int64(len(in)) - 1, ExitInputTooLarge},
+		{Gron, in, 1, ExitInputTooLarge},
+		{GronStream, lines, int64(len(lines)), ExitOK},
+		{GronStream, lines, int64(len(lines)) - 1, ExitInputTooLarge},
+		{Ungron, statements, int64(len(statements)), ExitOK},
+		{Ungron, statements, int64(len(statements)) - 1, ExitInputTooLarge},
+		{Check, in, int64(len(in)), ExitOK},
+		{Check, in, int64(len(in)) - 1, ExitInputTooLarge},
+		{PrecisionCheck, in, int64(len(in)), ExitOK},
+		{PrecisionCheck, in, int64(len(in)) - 1, ExitInputTooLarge},
+		{Schema, in, int64(len(in)), ExitOK},
+		{Schema, in, int64(len(in)) - 1, ExitInputTooLarge},
+		{CountBy("a"), lines, int64(len(lines)), ExitOK},
+		{CountBy("a"), lines, int64(len(lines)) - 1, ExitInputTooLarge},
+	}
+
+	for i, c := range cases {
+		code, err := c.action(strings.NewReader(c.in), &bytes.Buffer{}, OptMonochrome, WithMaxInputBytes(c.max))
+		if code != c.want {
+			t.Errorf("case %d: want exit code %d; have %d (%v)", i, c.want, code, err)
+		}
+		if c.want == ExitInputTooLarge {
+			if _, ok := err.(errInputTooLarge); !ok {
+				t.Errorf("case %d: want an errInputTooLarge; have %v", i, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package gron
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"time"
+)
+
+// statementsFromMsgpack takes an io.Reader containing MessagePack and
+// returns statements or an error on failure. A single value is treated
+// like a JSON document; several, one after the other, are treated as
+// elements of a top-level array, as GronStream treats lines of JSON
+func statementsFromMsgpack(r io.Reader, prefix statement, c *config) (statements, error) {
+	d := &msgpackDecoder{r: bufio.NewReader(r)}
+	var docs []interface{}
+	for {
+		// The end of the input is only fine between values
+		if _, err := d.r.Peek(1); err == io.EOF {
+			break
+		}
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, v)
+	}
+
+	ss := make(statements, 0, 32)
+	switch len(docs) {
+	case 0:
+		return nil, fmt.Errorf("no MessagePack values in input")
+	case 1:
+		ss.fill(prefix, docs[0], 0, c)
+	default:
+		ss.addWithValue(prefix, token{"[]", typEmptyArray})
+		for i, doc := range docs {
+			ss.fill(prefix.withNumericKey(i), doc, 0, c)
+		}
+	}
+	return ss, nil
+}
+
+// msgpackTimestamp is the extension type for timestamps
+const msgpackTimestamp = -1
+
+// A msgpackDecoder decodes MessagePack values into the types used for
+// values decoded from JSON. Map keys that aren't strings are converted
+// to strings, as they are for YAML; e.g. the key 1 becomes "1" and nil
+// becomes "null". Binary data is written as a base64 string, as
+// encoding/json writes a []byte, and timestamps as RFC 3339 strings.
+// Other extension types are objects with their type and base64 data;
+// e.g. {"type": 1, "data": "AQI="}. Numbers that JSON can't represent
+// (infinities and NaN) are an error
+type msgpackDecoder struct {
+	r     *bufio.Reader
+	depth int
+}
+
+// maxMsgpackDepth limits how deeply values can be nested, so a few
+// bytes of input can't make the decoder recurse until it runs out of stack
+const maxMsgpackDepth = 10000
+
+// value decodes the next value in the input
+func (d *msgpackDecoder) value() (interface{}, error) {
+	d.depth++
+	defer func() { d.depth-- }()
+	if d.depth > maxMsgpackDepth {
+		return nil, fmt.Errorf("values are nested more than %d deep", maxMsgpackDepth)
+	}
+
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	switch {
+	case b <= 0x7f:
+		return json.Number(strconv.Itoa(int(b))), nil
+	case b >= 0xe0:
+		return json.Number(strconv.Itoa(int(int8(b)))), nil
+	case b >= 0x80 && b <= 0x8f:
+		return d.mapOf(int(b & 0x0f))
+	case b >= 0x90 && b <= 0x9f:
+		return d.arrayOf(int(b & 0x0f))
+	case b >= 0xa0 && b <= 0xbf:
+		return d.str(int(b & 0x1f))
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.length(b - 0xc4)
+		if err != nil {
+			return nil, err
+		}
+		data, err := d.bytes(n)
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(data), nil
+
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.length(b - 0xc7)
+		if err != nil {
+			return nil, err
+		}
+		return d.ext(n)
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.ext(1 << (b - 0xd4))
+
+	case 0xca:
+		data, err := d.bytes(4)
+		if err != nil {
+			return nil, err
+		}
+		return msgpackFloat(float64(math.Float32frombits(binary.BigEndian.Uint32(data))), 32)
+	case 0xcb:
+		data, err := d.bytes(8)
+		if err != nil {
+			return nil, err
+		}
+		return msgpackFloat(math.Float64frombits(binary.BigEndian.Uint64(data)), 64)
+
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := d.uint(1 << (b - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(strconv.FormatUint(n, 10)), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (b - 0xd0)
+		n, err := d.uint(size)
+		if err != nil {
+			return nil, err
+		}
+		// Sign extend from however many bytes there were
+		shift := uint(64 - 8*size)
+		return json.Number(strconv.FormatInt(int64(n<<shift)>>shift, 10)), nil
+
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.length(b - 0xd9)
+		if err != nil {
+			return nil, err
+		}
+		return d.str(n)
+	case 0xdc, 0xdd:
+		n, err := d.length(b - 0xdc + 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.arrayOf(n)
+	case 0xde, 0xdf:
+		n, err := d.length(b - 0xde + 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.mapOf(n)
+	}
+
+	return nil, fmt.Errorf("invalid MessagePack type byte 0x%02x", b)
+}
+
+// length reads a length of 1, 2 or 4 bytes for a size of 0, 1 or 2
+func (d *msgpackDecoder) length(size byte) (int, error) {
+	n, err := d.uint(1 << size)
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxInt32 {
+		return 0, fmt.Errorf("MessagePack length %d is too big", n)
+	}
+	return int(n), nil
+}
+
+// uint reads a big-endian unsigned integer of n bytes
+func (d *msgpackDecoder) uint(n int) (uint64, error) {
+	data, err := d.bytes(n)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, b := range data {
+		v = v<<8 | uint64(b)
+	}
+	return v, nil
+}
+
+// bytes reads exactly n bytes. The slice is only as big as what's been
+// read, so that a huge length in a short input doesn't allocate much
+func (d *msgpackDecoder) bytes(n int) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(d.r, int64(n)))
+	if err == nil && len(data) < n {
+		err = io.ErrUnexpectedEOF
+	}
+	return data, err
+}
+
+// str reads a string of n bytes
+func (d *msgpackDecoder) str(n int) (string, error) {
+	data, err := d.bytes(n)
+	return string(data), err
+}
+
+// arrayOf reads an array of n values
+func (d *msgpackDecoder) arrayOf(n int) ([]interface{}, error) {
+	out := make([]interface{}, 0, minInt(n, 1024))
+	for i := 0; i < n; i++ {
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// mapOf reads a map of n keys and values
+func (d *msgpackDecoder) mapOf(n int) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, minInt(n, 1024))
+	for i := 0; i < n; i++ {
+		k, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+
+		switch kk := k.(type) {
+		case nil:
+			out["null"] = v
+		case string, json.Number, bool:
+			out[fmt.Sprint(kk)] = v
+		default:
+			return nil, fmt.Errorf("MessagePack map keys that are arrays or maps can't be represented in JSON")
+		}
+	}
+	return out, nil
+}
+
+// ext reads the type and n bytes of data of an extension
+func (d *msgpackDecoder) ext(n int) (interface{}, error) {
+	typ, err := d.r.ReadByte()
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	data, err := d.bytes(n)
+	if err != nil {
+		return nil, err
+	}
+
+	if int8(typ) != msgpackTimestamp {
+		return map[string]interface{}{
+			"type": json.Number(strconv.Itoa(int(int8(typ)))),
+			"data": base64.StdEncoding.EncodeToString(data),
+		}, nil
+	}
+
+	var t time.Time
+	switch n {
+	case 4:
+		t = time.Unix(int64(binary.BigEndian.Uint32(data)), 0)
+	case 8:
+		v := binary.BigEndian.Uint64(data)
+		t = time.Unix(int64(v&0x3ffffffff), int64(v>>34))
+	case 12:
+		t = time.Unix(int64(binary.BigEndian.Uint64(data[4:])), int64(binary.BigEndian.Uint32(data)))
+	default:
+		return nil, fmt.Errorf("invalid MessagePack timestamp of %d bytes", n)
+	}
+	return t.UTC().Format(time.RFC3339Nano), nil
+}
+
+// msgpackFloat returns a float of the provided bit size as a json.Number
+func msgpackFloat(f float64, bitSize int) (interface{}, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, fmt.Errorf("MessagePack number %v can't be represented in JSON", f)
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, bitSize)), nil
+}
+
+// unexpectedEOF turns io.EOF into io.ErrUnexpectedEOF, for
+// when the input ends part of the way through a value
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// minInt returns the smaller of two ints
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
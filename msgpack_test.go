@@ -0,0 +1,97 @@
+package gron
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGronMsgpack(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{
+			"\x88" +
+				"\xa4name\xa3Tom" +
+				"\xa3age\x1e" +
+				"\xa4tags\x93\xa1a\xc3\xc0" +
+				"\xa5ratio\xcb\x3f\xe0\x00\x00\x00\x00\x00\x00" +
+				"\xa3neg\xfb" +
+				"\xa3big\xcf\xff\xff\xff\xff\xff\xff\xff\xff" +
+				"\xa3i16\xd1\xfe\xd4" +
+				"\xa3bin\xc4\x02\x01\x02",
+			`json = {};
+json.age = 30;
+json.big = 18446744073709551615;
+json.bin = "AQI=";
+json.i16 = -300;
+json.name = "Tom";
+json.neg = -5;
+json.ratio = 0.5;
+json.tags = [];
+json.tags[0] = "a";
+json.tags[1] = true;
+json.tags[2] = null;
+`,
+		},
+		{
+			// Several values one after the other
+			"\x01\xa1x",
+			`json = [];
+json[0] = 1;
+json[1] = "x";
+`,
+		},
+		{
+			// Non-string keys, a timestamp and another extension type
+			"\x84" +
+				"\xa2at\xd6\xff\x00\x00\x00\x00" +
+				"\x01\xa3one" +
+				"\xc0\xa1n" +
+				"\xa3ext\xd4\x05\x07",
+			`json = {};
+json.at = "1970-01-01T00:00:00Z";
+json.ext = {};
+json.ext.data = "Bw==";
+json.ext.type = 5;
+json["1"] = "one";
+json["null"] = "n";
+`,
+		},
+	}
+
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		code, err := Gron(strings.NewReader(c.in), out, OptMonochrome|OptMsgpack)
+		if code != ExitOK || err != nil {
+			t.Errorf("want ExitOK and nil error for %q; have %d and %v", c.in, code, err)
+			continue
+		}
+		if out.String() != c.want {
+			t.Logf("want: %s", c.want)
+			t.Logf("have: %s", out.String())
+			t.Errorf("output for %q does not match", c.in)
+		}
+	}
+}
+
+func TestGronMsgpackInvalid(t *testing.T) {
+	cases := []string{
+		"\xc1",
+		"\x92\x01",
+		"\xa5ab",
+		"\xdb\xff\xff\xff\xffab",
+		"\xcb\x7f\xf0\x00\x00\x00\x00\x00\x00",
+		"\x81\x90\x01",
+		"\xd6\xff\x00",
+		strings.Repeat("\x91", 20000) + "\x01",
+	}
+
+	for _, c := range cases {
+		code, err := Gron(strings.NewReader(c), &bytes.Buffer{}, OptMonochrome|OptMsgpack)
+		if code != ExitFormStatements || err == nil {
+			t.Errorf("want ExitFormStatements and an error for %q; have %d and %v", c, code, err)
+		}
+	}
+}
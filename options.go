@@ -0,0 +1,424 @@
+package gron
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// An Option configures an action in ways that don't fit in the
+// option bitfield; usually because they need a value
+type Option func(*config)
+
+// config holds the settings applied by a list of Options
+type config struct {
+	transforms  []transformFn
+	sink        func(Statement) error
+	output      func(string)
+	maxLineSize int
+	maxIndex    int
+	streamKey   string
+	streamKeys  map[string]int
+	arrayStart  int
+	namespace   []string
+	inputNames  []string
+	root        string
+
+	includeGlobs []string
+	excludeGlobs []string
+	caseMode     CaseMode
+	includePaths []*regexp.Regexp
+	excludePaths []*regexp.Regexp
+	valueMatch   *regexp.Regexp
+	invertMatch  bool
+
+	pathPrefix     statement
+	pathPrefixKeys []pathKey
+
+	tlsServerName string
+	timeout       time.Duration
+	headers       http.Header
+	retries       int
+	retryDelay    time.Duration
+
+	inlineArrays  bool
+	arraysAsSets  bool
+	lenient       bool
+	parents       bool
+	strict        bool
+	unwrapStrings bool
+	skipNulls     bool
+	keyOrder      keyOrder
+	dupKeys       DupKeys
+	maxMemory     int64
+	maxInput      int64
+	maxDepth      int
+	depthLimited  bool
+	input         *maxBytesReader
+
+	shell    Shell
+	envNames map[string]string
+	splitOn  *regexp.Regexp
+
+	floatFormat string
+	truncate    int
+	indent      string
+	pathDialect PathDialect
+	highlights  []highlight
+	keysCase    KeysCase
+
+	base        []byte
+	baseErr     error
+	diffContext int
+
+	selectIndexes []int
+	reindex       bool
+
+	warn func(string)
+
+	sampleRate float64
+	sampleRand *rand.Rand
+}
+
+// DefaultMaxLineSize is the default limit, in bytes, on the length
+// of a single line of input read by GronStream and Ungron
+const DefaultMaxLineSize = 1024 * 1024
+
+// newConfig returns a config with the provided Options applied
+func newConfig(options []Option) *config {
+	c := &config{
+		maxLineSize: DefaultMaxLineSize,
+		maxIndex:    DefaultMaxArrayIndex,
+		shell:       ShellPOSIX,
+		root:        DefaultRoot,
+		timeout:     DefaultTimeout,
+		indent:      DefaultIndent,
+	}
+	for _, o := range options {
+		o(c)
+	}
+	c.compileGlobs()
+	return c
+}
+
+// setOpts copies the options from the bitfield that
+// affect how statements are made into the config
+func (c *config) setOpts(opts int) {
+	c.inlineArrays = opts&OptInlineScalarArrays > 0
+	c.arraysAsSets = opts&OptArrayAsSet > 0
+	c.lenient = opts&OptLenient > 0
+	c.parents = opts&OptParents > 0
+	c.strict = opts&OptStrict > 0
+	c.unwrapStrings = opts&OptUnwrapStrings > 0
+	c.skipNulls = opts&OptSkipNulls > 0
+	if opts&OptKeepOrder > 0 {
+		c.keyOrder = make(keyOrder)
+	}
+
+	// Formatted numbers and truncated strings aren't canonical
+	if opts&OptCanonical > 0 {
+		c.floatFormat = ""
+		c.truncate = 0
+	}
+}
+
+// a transformFn accepts the path to a leaf value (as a statement
+// without the assignment) and the value itself, and returns the
+// value that should be used in its place
+type transformFn func(path statement, v interface{}) interface{}
+
+// WithValueTransform registers a function that is called with the path
+// to, and the value of, every leaf (string, number, bool or null) in the
+// input. The returned value is used in place of the original one.
+//
+// Path segments are the object keys and array indexes leading to the
+// value, not including the top-level 'json'. Numbers are passed as
+// json.Number, and any value returned should be one of the types
+// produced by encoding/json (with json.Number for numbers).
+//
+// Transforms run as the statements are made; that is, before any
+// sorting or rendering takes place. Multiple transforms are applied
+// in the order they were given.
+func WithValueTransform(fn func(path []string, v interface{}) interface{}) Option {
+	return func(c *config) {
+		c.transforms = append(c.transforms, func(path statement, v interface{}) interface{} {
+			return fn(path.keys(), v)
+		})
+	}
+}
+
+// WithStatementSink passes each statement to the provided function
+// instead of writing it to the action's io.Writer, so that embedders
+// can route statements elsewhere without parsing rendered text.
+//
+// Statements are passed in the same order they would have been
+// written: sorted unless OptNoSort is set, in which case the order
+// is undefined. The sink always receives the assignment form of the
+// statement, regardless of OptJSON.
+func WithStatementSink(fn func(Statement)) Option {
+	return func(c *config) {
+		c.sink = func(s Statement) error {
+			fn(s)
+			return nil
+		}
+	}
+}
+
+// WithMaxLineSize sets the maximum length, in bytes, of a single line
+// of input for the line-oriented actions: GronStream and Ungron.
+func WithMaxLineSize(n int) Option {
+	return func(c *config) {
+		c.maxLineSize = n
+	}
+}
+
+// DefaultMaxArrayIndex is the default limit on the array indexes
+// that Ungron and UngronStream accept in their input
+const DefaultMaxArrayIndex = 16 * 1024 * 1024
+
+// WithMaxArrayIndex sets the largest array index that Ungron and
+// UngronStream accept; e.g. json[n]. Arrays are made big enough for
+// their largest index, with null in any gaps, so without a limit one
+// line of input could ask for any amount of memory. A line with a
+// bigger index is reported as a LineError.
+func WithMaxArrayIndex(n int) Option {
+	return func(c *config) {
+		c.maxIndex = n
+	}
+}
+
+// WithStreamKey makes GronStream key the statements for each line of
+// input by the value of one of its fields, rather than by its line
+// number; e.g. json["abc123"].name = "Tom"; for the line
+// {"id": "abc123", "name": "Tom"} with a key of id. The top-level value
+// is an object rather than an array. Lines that aren't objects, or that
+// don't have the field with a string, number or bool in it, are keyed by
+// '#' and their line number (from zero) instead, like json["#2"], with a
+// warning. Two lines with the same key are an error, since one would be
+// merged into the other; OptSkipErrors skips the later line instead.
+func WithStreamKey(field string) Option {
+	return func(c *config) {
+		c.streamKey = field
+	}
+}
+
+// WithArrayStart numbers the lines of GronStream's input from n rather
+// than zero; e.g. json[1000] for the first line with n of 1000, so that
+// the output for one chunk of a log can follow on from the last one's.
+// With WithStreamKey it applies to the line numbers used as fallbacks.
+func WithArrayStart(n int) Option {
+	return func(c *config) {
+		c.arrayStart = n
+	}
+}
+
+// WithNamespace inserts fixed keys after the top-level 'json' in every
+// statement; e.g. WithNamespace("svcA") produces statements like
+// json.svcA.users[0] = "Tom"; so that output from several sources stays
+// distinguishable when it's combined. Container statements are emitted
+// for the namespace keys so that the output still ungrons.
+//
+// When ungronning, the namespace is stripped from the result.
+func WithNamespace(keys ...string) Option {
+	return func(c *config) {
+		c.namespace = append(c.namespace, keys...)
+	}
+}
+
+// DefaultRoot is the bare word that every statement starts with
+const DefaultRoot = "json"
+
+// WithRoot replaces the top-level 'json' that every statement starts
+// with, so that the output for several inputs can be told apart; e.g.
+// with a root of users, json.name becomes users.name. The root must be
+// a valid identifier (see ValidIdentifier). Ungron doesn't need it, as
+// whatever bare word every statement starts with is taken as the root.
+func WithRoot(name string) Option {
+	return func(c *config) {
+		c.root = name
+	}
+}
+
+// prefix returns the statement that every statement made from the
+// input starts with: the top-level 'json' and any namespace keys
+func (c *config) prefix() statement {
+	p := statement{{c.root, typBare}}
+	for _, k := range c.namespace {
+		p = p.withKey(k)
+	}
+	return p
+}
+
+// namespaceStatements returns container statements for the top-level
+// 'json' and each namespace key leading up to the prefix statement
+func (c *config) namespaceStatements() statements {
+	ss := make(statements, 0, len(c.namespace))
+	p := statement{{c.root, typBare}}
+	for _, k := range c.namespace {
+		ss.addWithValue(p, token{"{}", typEmptyObject})
+		p = p.withKey(k)
+	}
+	return ss
+}
+
+// WithMaxDepth stops statements being made for anything more than n
+// levels below the top-level value; objects and arrays at that depth
+// are written as {} or [] without their contents. A depth of zero
+// writes just json = {}; or json = [];. For GronStream the depth is
+// counted from each line's value rather than the top-level array.
+func WithMaxDepth(n int) Option {
+	return func(c *config) {
+		c.maxDepth = n
+		c.depthLimited = true
+	}
+}
+
+// tooDeep returns true if the contents of a value at
+// the provided depth should be left out of the statements
+func (c *config) tooDeep(depth int) bool {
+	return c.depthLimited && depth >= c.maxDepth
+}
+
+// WithTLSServerName sets the server name that GetURL sends with SNI
+// and verifies the server's certificate against, in place of the host
+// in the URL; e.g. to fetch from an IP address with a named certificate
+func WithTLSServerName(name string) Option {
+	return func(c *config) {
+		c.tlsServerName = name
+	}
+}
+
+// WithSplitOn makes Ungron treat any line of input that matches the
+// provided pattern as the boundary between two separate documents; e.g.
+// blank lines between the concatenated output of several runs of gron.
+// The boundary lines themselves are discarded, as are empty documents.
+// It has no effect on CSV input.
+func WithSplitOn(re *regexp.Regexp) Option {
+	return func(c *config) {
+		c.splitOn = re
+	}
+}
+
+// WithFloatFormat formats every number in the output with the provided
+// fmt verb for floats (e.g. %.2f) rather than as it was in the input.
+// It's for display only: rounded numbers can't be ungronned exactly.
+//
+// A number too big for a float64, like 1e400, is an error. Nothing is
+// written for a whole document with one in it, but input that's read a
+// piece at a time (by GronStream, or by Gron with WithMaxMemory) has
+// been written up to the piece with the number in it.
+func WithFloatFormat(format string) Option {
+	return func(c *config) {
+		c.floatFormat = format
+	}
+}
+
+// WithTruncate cuts any string value longer than n characters (runes,
+// not bytes) down to n characters followed by an ellipsis, and notes how
+// much was cut off after the statement; e.g.
+//
+//   json.blob = "abcdef…"; // truncated 4096 bytes
+//
+// It's for display only: the output can't be ungronned. Zero, the
+// default, leaves strings as they are. The statement sink always
+// receives the whole string.
+func WithTruncate(n int) Option {
+	return func(c *config) {
+		c.truncate = n
+	}
+}
+
+// DefaultIndent is what each level of the JSON that
+// Ungron and Schema write is indented with by default
+const DefaultIndent = "  "
+
+// WithIndent sets what each level of the JSON that Ungron and Schema
+// write is indented with; e.g. "\t". An empty indent writes the JSON
+// on one line instead, which is never colorized.
+func WithIndent(indent string) Option {
+	return func(c *config) {
+		c.indent = indent
+	}
+}
+
+// WithWarnings passes messages about problems that aren't serious
+// enough to stop an action (e.g. two keys that collide) to the
+// provided function. By default they're discarded.
+func WithWarnings(fn func(msg string)) Option {
+	return func(c *config) {
+		c.warn = fn
+	}
+}
+
+// warnf formats a warning and passes it to the warning function, if any
+func (c *config) warnf(format string, args ...interface{}) {
+	if c.warn != nil {
+		c.warn(fmt.Sprintf(format, args...))
+	}
+}
+
+// newScanner returns a bufio.Scanner for r that accepts lines
+// up to the configured maximum line size
+func (c *config) newScanner(r io.Reader) *bufio.Scanner {
+	sc := bufio.NewScanner(r)
+	initial := 64 * 1024
+	if c.maxLineSize < initial {
+		initial = c.maxLineSize
+	}
+	sc.Buffer(make([]byte, 0, initial), c.maxLineSize)
+	return sc
+}
+
+// A LineTooLongError is returned by GronStream and Ungron when a line
+// of input is longer than the maximum set with WithMaxLineSize
+type LineTooLongError struct {
+	Line int // The number of the line, counting from one
+	Max  int // The maximum line size in bytes
+}
+
+func (e LineTooLongError) Error() string {
+	return fmt.Sprintf("line %d is longer than the maximum line size of %d bytes", e.Line, e.Max)
+}
+
+// scanError returns the error for a scanner's failure to read the n'th
+// line of input (counting from one): a LineTooLongError if it's too
+// long, and otherwise the error itself after a description
+func (c *config) scanError(err error, n int, desc string) error {
+	if err == bufio.ErrTooLong {
+		return LineTooLongError{n, c.maxLineSize}
+	}
+	return fmt.Errorf("%s: %s", desc, err)
+}
+
+// transform applies all of the registered transforms to a value
+func (c *config) transform(path statement, v interface{}) interface{} {
+	for _, fn := range c.transforms {
+		v = fn(path, v)
+	}
+	return v
+}
+
+// RedactedValue is the value used in place of redacted values
+const RedactedValue = "***"
+
+// WithRedact replaces any leaf value whose rendered path (e.g.
+// json.users[0].password) matches one of the provided patterns
+// with RedactedValue. Redacted values are plain strings, so the
+// output can still be ungronned.
+func WithRedact(patterns ...*regexp.Regexp) Option {
+	return func(c *config) {
+		c.transforms = append(c.transforms, func(path statement, v interface{}) interface{} {
+			p := path.String()
+			for _, re := range patterns {
+				if re.MatchString(p) {
+					return RedactedValue
+				}
+			}
+			return v
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package gron
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// A DupKeys is what to do about a key that appears more than
+// once in the same object of the input
+type DupKeys string
+
+// Ways of handling duplicate keys
+const (
+	// DupKeysLast uses the value of the last appearance of the
+	// key, without saying anything; as encoding/json does
+	DupKeysLast DupKeys = "last"
+
+	// DupKeysWarn uses the value of the last appearance of the key,
+	// with a warning naming the path given with WithWarnings
+	DupKeysWarn DupKeys = "warn"
+
+	// DupKeysError fails with ExitFormStatements
+	DupKeysError DupKeys = "error"
+)
+
+// DupKeysModes is the list of ways of handling duplicate keys
+var DupKeysModes = []DupKeys{DupKeysLast, DupKeysWarn, DupKeysError}
+
+// WithDupKeys sets what's done about a key that appears more than once
+// in the same object of JSON input, like {"a": 1, "a": 2}; which most
+// decoders, Gron included by default, quietly resolve by using the last
+// value, losing the others. It applies to the actions that gron JSON.
+func WithDupKeys(mode DupKeys) Option {
+	return func(c *config) {
+		c.dupKeys = mode
+	}
+}
+
+// a keyOrder records the order that the keys of each object decoded
+// with it were in, by the object's identity; maps are references, so
+// the object can be looked up again wherever the decoded value goes
+type keyOrder map[uintptr][]string
+
+// keys returns the keys of an object in the order they were in
+// the input, or false if it wasn't decoded with the keyOrder
+func (o keyOrder) keys(obj map[string]interface{}) ([]string, bool) {
+	if o == nil {
+		return nil, false
+	}
+	keys, ok := o[reflect.ValueOf(obj).Pointer()]
+	return keys, ok && len(keys) == len(obj)
+}
+
+// reset forgets the objects recorded so far; e.g. before decoding
+// the next line of GronStream's input, when the last is finished with
+func (o keyOrder) reset() {
+	for k := range o {
+		delete(o, k)
+	}
+}
+
+// a tokenDecoder decodes JSON token by token, for the things that
+// json.Decoder's Decode can't do: recording the order of the keys
+// of each object, and noticing keys that are repeated in an object
+type tokenDecoder struct {
+	// prefix is the path to the value being decoded, for messages
+	// about duplicate keys, and keys is the path below it to where
+	// the decoder's got to: a string for each object key and an int
+	// for each array index
+	prefix statement
+	keys   []interface{}
+
+	// order is where the order of keys is recorded, if it's wanted
+	order keyOrder
+
+	dupKeys DupKeys
+	warnf   func(format string, args ...interface{})
+}
+
+// tokenDecoder returns a tokenDecoder for a value at the path
+// prefix, or nil if json.Decoder's Decode will do
+func (c *config) tokenDecoder(prefix statement) *tokenDecoder {
+	if c.keyOrder == nil && (c.dupKeys == "" || c.dupKeys == DupKeysLast) {
+		return nil
+	}
+	return &tokenDecoder{
+		prefix:  prefix,
+		order:   c.keyOrder,
+		dupKeys: c.dupKeys,
+		warnf:   c.warnf,
+	}
+}
+
+// decode decodes the next value from d as d.Decode would, with numbers
+// as json.Number if d.UseNumber has been called. A key that's repeated
+// keeps the place of its first appearance, and the value of its last
+func (td *tokenDecoder) decode(d *json.Decoder) (interface{}, error) {
+	t, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	v, err := td.decodeFrom(d, t)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return v, err
+}
+
+// decodeFrom decodes the value that starts with the token t
+func (td *tokenDecoder) decodeFrom(d *json.Decoder, t json.Token) (interface{}, error) {
+	switch t {
+	case json.Delim('{'):
+		obj := make(map[string]interface{})
+		var keys []string
+		for d.More() {
+			k, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := k.(string)
+
+			td.keys = append(td.keys, key)
+			v, err := td.next(d)
+			td.keys = td.keys[:len(td.keys)-1]
+			if err != nil {
+				return nil, err
+			}
+
+			if _, exists := obj[key]; exists {
+				if err := td.duplicate(key); err != nil {
+					return nil, err
+				}
+			} else {
+				keys = append(keys, key)
+			}
+			obj[key] = v
+		}
+		if _, err := d.Token(); err != nil {
+			return nil, err
+		}
+		if td.order != nil {
+			td.order[reflect.ValueOf(obj).Pointer()] = keys
+		}
+		return obj, nil
+
+	case json.Delim('['):
+		arr := make([]interface{}, 0)
+		for i := 0; d.More(); i++ {
+			td.keys = append(td.keys, i)
+			v, err := td.next(d)
+			td.keys = td.keys[:len(td.keys)-1]
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		if _, err := d.Token(); err != nil {
+			return nil, err
+		}
+		return arr, nil
+	}
+
+	// Strings, numbers, bools and nulls are already values
+	return t, nil
+}
+
+// next decodes the value that starts with the next token from d
+func (td *tokenDecoder) next(d *json.Decoder) (interface{}, error) {
+	t, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	return td.decodeFrom(d, t)
+}
+
+// duplicate deals with a key that's already in the
+// object being decoded, as chosen by dupKeys
+func (td *tokenDecoder) duplicate(key string) error {
+	path := td.path()
+	switch td.dupKeys {
+	case DupKeysWarn:
+		td.warnf("duplicate key %s in %s; using the last value", quoteString(key), path)
+	case DupKeysError:
+		return fmt.Errorf("duplicate key %s in %s", quoteString(key), path)
+	}
+	return nil
+}
+
+// path returns the path to the value being decoded
+func (td *tokenDecoder) path() statement {
+	p := td.prefix
+	for _, k := range td.keys {
+		switch k := k.(type) {
+		case string:
+			p = p.withKey(k)
+		case int:
+			p = p.withNumericKey(k)
+		}
+	}
+	return p
+}
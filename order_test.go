@@ -0,0 +1,167 @@
+package gron
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGronKeepOrder(t *testing.T) {
+	in := `{"zeta": 1, "alpha": {"y": true, "b": null, "x": [3, {"q": "r", "p": "s"}]}, "mid": "m", "alpha2": {}}`
+	want := `json = {};
+json.zeta = 1;
+json.alpha = {};
+json.alpha.y = true;
+json.alpha.b = null;
+json.alpha.x = [];
+json.alpha.x[0] = 3;
+json.alpha.x[1] = {};
+json.alpha.x[1].q = "r";
+json.alpha.x[1].p = "s";
+json.mid = "m";
+json.alpha2 = {};
+`
+
+	// The order has to be the same every time, which
+	// isn't likely by chance with Go's map ordering
+	for i := 0; i < 20; i++ {
+		out := &bytes.Buffer{}
+		code, err := Gron(strings.NewReader(in), out, OptMonochrome|OptKeepOrder)
+		if code != ExitOK || err != nil {
+			t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+		}
+		if out.String() != want {
+			t.Fatalf("want %q; have %q", want, out.String())
+		}
+	}
+
+	// The output still ungrons to the same document
+	js := &bytes.Buffer{}
+	code, err := Ungron(strings.NewReader(want), js, OptMonochrome)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error from Ungron; have %d and %v", code, err)
+	}
+	var a, b interface{}
+	json.Unmarshal([]byte(in), &a)
+	json.Unmarshal(js.Bytes(), &b)
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("want %s from Ungron; have %s", in, js.String())
+	}
+}
+
+func TestGronStreamKeepOrder(t *testing.T) {
+	in := "{\"b\": 1, \"a\": 2}\n{\"d\": \"{\\\"y\\\": 1, \\\"x\\\": 2}\", \"c\": 3}\n"
+	want := `json = [];
+json[0] = {};
+json[0].b = 1;
+json[0].a = 2;
+json[1] = {};
+json[1].d = {};
+json[1].d.y = 1;
+json[1].d.x = 2;
+json[1].c = 3;
+`
+	out := &bytes.Buffer{}
+	code, err := GronStream(strings.NewReader(in), out, OptMonochrome|OptKeepOrder|OptUnwrapStrings)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	if out.String() != want {
+		t.Errorf("want %q; have %q", want, out.String())
+	}
+}
+
+func TestKeyOrderDecode(t *testing.T) {
+	cases := []string{
+		`{"b": 1, "a": [1, 2.5, "x", true, null, {}], "c": {"e": {}, "d": []}}`,
+		`{"a": 1, "b": 2, "a": 3}`,
+		`[]`,
+		`"str"`,
+		`12345678901234567890`,
+	}
+
+	for _, in := range cases {
+		d := json.NewDecoder(strings.NewReader(in))
+		d.UseNumber()
+		var want interface{}
+		d.Decode(&want)
+
+		d = json.NewDecoder(strings.NewReader(in))
+		d.UseNumber()
+		td := &tokenDecoder{order: make(keyOrder)}
+		have, err := td.decode(d)
+		if err != nil {
+			t.Fatalf("want nil error for %s; have %s", in, err)
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("want %#v for %s; have %#v", want, in, have)
+		}
+	}
+
+	// Repeated keys keep their first place
+	td := &tokenDecoder{order: make(keyOrder)}
+	v, _ := td.decode(json.NewDecoder(strings.NewReader(`{"b": 1, "a": 2, "b": 3}`)))
+	keys, ok := td.order.keys(v.(map[string]interface{}))
+	if !ok || !reflect.DeepEqual(keys, []string{"b", "a"}) {
+		t.Errorf("want keys [b a]; have %v", keys)
+	}
+
+	for _, in := range []string{`{"a": 1`, `[1, 2`, `{"a"`} {
+		td := &tokenDecoder{order: make(keyOrder)}
+		_, err := td.decode(json.NewDecoder(strings.NewReader(in)))
+		if err == nil {
+			t.Errorf("want an error for %s; have nil", in)
+		}
+	}
+}
+
+func TestGronDupKeys(t *testing.T) {
+	in := `{"a": 1, "b": {"c": [{"d": 1, "d": 2}]}, "a": 3}`
+
+	var warnings []string
+	out := &bytes.Buffer{}
+	code, err := Gron(strings.NewReader(in), out, OptMonochrome, WithDupKeys(DupKeysWarn), WithWarnings(func(msg string) {
+		warnings = append(warnings, msg)
+	}))
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	want := "json = {};\njson.a = 3;\njson.b = {};\njson.b.c = [];\njson.b.c[0] = {};\njson.b.c[0].d = 2;\n"
+	if out.String() != want {
+		t.Errorf("want %q; have %q", want, out.String())
+	}
+	wantWarnings := []string{
+		`duplicate key "d" in json.b.c[0]; using the last value`,
+		`duplicate key "a" in json; using the last value`,
+	}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("want warnings %q; have %q", wantWarnings, warnings)
+	}
+
+	out.Reset()
+	code, err = Gron(strings.NewReader(in), out, OptMonochrome, WithDupKeys(DupKeysError))
+	if code != ExitFormStatements || err == nil {
+		t.Fatalf("want ExitFormStatements and an error; have %d and %v", code, err)
+	}
+	if !strings.Contains(err.Error(), `duplicate key "d" in json.b.c[0]`) {
+		t.Errorf("want an error naming the duplicate key; have %s", err)
+	}
+
+	// Each line of GronStream's input is named by its index
+	out.Reset()
+	_, err = GronStream(strings.NewReader("{}\n{\"x\": 1, \"x\": 1}\n"), out, OptMonochrome, WithDupKeys(DupKeysError))
+	if err == nil || !strings.Contains(err.Error(), `duplicate key "x" in json[1]`) {
+		t.Errorf("want an error naming json[1]; have %v", err)
+	}
+
+	// The last value wins quietly by default
+	for _, mode := range []DupKeys{"", DupKeysLast} {
+		out.Reset()
+		code, err = Gron(strings.NewReader(in), out, OptMonochrome, WithDupKeys(mode))
+		if code != ExitOK || err != nil || out.String() != want {
+			t.Errorf("want %q with %q; have %q, %d and %v", want, mode, out.String(), code, err)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package gron
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// A patch is a list of statements that, applied in order to a base
+// document, turn it into another document. Assignments are written
+// just as the gron action writes them, and set the value at their path;
+// creating any containers that don't exist. Deletions remove a path:
+//
+//   delete json.users[2];
+//   json.users[0].name = "Tom";
+//
+// Deleting an array element removes it, moving any later elements
+// down; so patches written by Diff delete elements from the end of an
+// array first. Assigning {} or [] to an object or array that's already
+// there leaves its contents alone, so any gron output can also be used
+// as a patch to add to or update the base.
+//
+// Where a value in the base (or one set earlier in the patch) is in the
+// way of an assignment, the assignment wins; e.g. json.a.b = 2; replaces
+// a base with {"a": 1} rather than failing. With OptStrict that's an
+// error instead, as is assigning a different value to a path that has
+// one already, just as for two statements without a base. Nulls don't
+// count, so they can be filled in.
+
+// WithBase sets the document that a patch is relative to. Diff writes
+// the patch that turns the base into its input, and Ungron applies its
+// input to the base as a patch rather than building a new document.
+// The base is JSON, and is read in full straight away.
+func WithBase(r io.Reader) Option {
+	base, err := ioutil.ReadAll(r)
+	return func(c *config) {
+		c.base = base
+		c.baseErr = err
+	}
+}
+
+// Diff is like the gron action, but rather than every statement it
+// writes a patch that turns the base document set with WithBase into
+// the input; i.e. the deletions and the statements that were added or
+// changed. Ungronning the patch with the same base gives the input.
+// Without a base, every statement is written.
+//
+// The patch is always sorted so that it can be applied in order, and
+// filters like WithGlob don't apply; OptJSON and OptEvents aren't
+// supported because they can't represent deletions.
+func Diff(r io.Reader, w io.Writer, opts int, options ...Option) (int, error) {
+	c := newConfig(options)
+	opts = resolveOpts(opts)
+	c.setOpts(opts)
+
+	var base statements
+	if c.baseErr != nil {
+		return ExitReadInput, fmt.Errorf("failed to read base: %s", c.baseErr)
+	}
+	if c.base != nil {
+		ss, err := statementsFromJSON(bytes.NewReader(c.base), c.prefix(), c)
+		if err != nil {
+			return ExitFormStatements, fmt.Errorf("failed to form statements from base: %s", err)
+		}
+		base = append(c.namespaceStatements(), ss...)
+	}
+
+	target, err := statementsFromJSON(r, c.prefix(), c)
+	if err != nil {
+		return ExitFormStatements, fmt.Errorf("failed to form statements: %s", err)
+	}
+	target = append(c.namespaceStatements(), target...)
+
+	patch := diffStatements(base, target)
+	if err := c.checkFloatFormat(patch); err != nil {
+		return ExitFormStatements, fmt.Errorf("failed to form statements: %s", err)
+	}
+	for _, s := range patch {
+		err = writeStatement(w, s, opts, c)
+		if err != nil {
+			return ExitFormStatements, fmt.Errorf("failed to form statements: %s", err)
+		}
+	}
+	return ExitOK, nil
+}
+
+// diffStatements returns the patch that turns the base statements into
+// the target statements. Both lists are sorted as a side effect.
+func diffStatements(base, target statements) statements {
+	sort.Sort(base)
+	sort.Sort(target)
+
+	baseValues := make(map[string]token, len(base))
+	for _, s := range base {
+		if v, ok := s.value(); ok {
+			baseValues[s.path().String()] = v
+		}
+	}
+
+	// Containers that are replaced by something else lose their
+	// contents along with them, so those don't need deleting
+	changes := make(statements, 0)
+	inTarget := make(map[string]bool, len(target))
+	gone := make(map[string]bool)
+	for _, s := range target {
+		path := s.path().String()
+		inTarget[path] = true
+
+		v, _ := s.value()
+		was, exists := baseValues[path]
+		if exists && was == v {
+			continue
+		}
+		if exists && (was.typ == typEmptyObject || was.typ == typEmptyArray) {
+			gone[path] = true
+		}
+		changes = append(changes, s)
+	}
+
+	// Anything left in the base is deleted, unless its container is
+	// deleted or replaced anyway. Deletions are written deepest and
+	// last first, so that deleting array elements doesn't move any
+	// others that are yet to be deleted
+	var deletions statements
+	for _, s := range base {
+		path := s.path()
+		if inTarget[path.String()] || gone[path.String()] {
+			continue
+		}
+		if ancestorIn(path, gone) {
+			continue
+		}
+		gone[path.String()] = true
+
+		d := statement{{"delete", typDelete}}
+		d = append(d, path...)
+		d = append(d, token{";", typSemi})
+		deletions = append(deletions, d)
+	}
+	for i, j := 0, len(deletions)-1; i < j; i, j = i+1, j-1 {
+		deletions[i], deletions[j] = deletions[j], deletions[i]
+	}
+
+	return append(deletions, changes...)
+}
+
+// ancestorIn returns true if the path of any container
+// that the provided path is in is in the set of paths
+func ancestorIn(path statement, paths map[string]bool) bool {
+	for i, t := range path {
+		if i > 0 && (t.typ == typDot || t.typ == typLBrace) && paths[path[:i].String()] {
+			return true
+		}
+	}
+	return false
+}
+
+// deletions returns true if any of the statements are deletions
+func (ss statements) deletions() bool {
+	for _, s := range ss {
+		if s.deletion() {
+			return true
+		}
+	}
+	return false
+}
+
+// patching returns true if statements should be applied to the base
+// as a patch; i.e. if there is a base or there are any deletions
+func (c *config) patching(ss statements) bool {
+	return c.base != nil || c.baseErr != nil || ss.deletions()
+}
+
+// applyPatch applies statements to the base in order, returning the
+// resulting value without the root, along with the root. Without a
+// base the statements are applied to nothing.
+func (c *config) applyPatch(ss statements) (interface{}, string, error) {
+	if c.baseErr != nil {
+		return nil, "", fmt.Errorf("failed to read base: %s", c.baseErr)
+	}
+
+	var v interface{}
+	if c.base != nil {
+		var err error
+		v, err = decodeJSON(bytes.NewReader(c.base), c.lenient, nil)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to decode base: %s", err)
+		}
+
+		// Namespaced paths in the patch need to line up with the base,
+		// and the namespace keys are stripped again afterwards
+		for i := len(c.namespace) - 1; i >= 0; i-- {
+			v = map[string]interface{}{c.namespace[i]: v}
+		}
+	}
+
+	root := ss.root()
+	if root == "" && !ss.blank() {
+		return nil, "", fmt.Errorf("patch statements don't all start with the same bare word")
+	}
+
+	for _, s := range ss {
+		if len(s) == 0 || s[0].typ == typIgnored {
+			continue
+		}
+
+		if s.deletion() {
+			if !s[1:].validPath() {
+				return nil, "", fmt.Errorf("invalid deletion `%s`", s)
+			}
+			v = deletePath(v, s[1:].pathKeys())
+			continue
+		}
+
+		if !s.valid() {
+			return nil, "", fmt.Errorf("invalid statement `%s`", s)
+		}
+		t, _ := s.value()
+		var val interface{}
+		d := json.NewDecoder(strings.NewReader(t.text))
+		d.UseNumber()
+		if err := d.Decode(&val); err != nil {
+			return nil, "", fmt.Errorf("invalid value `%s`", t.text)
+		}
+
+		var err error
+		v, err = setPath(v, s.pathKeys(), val, c.strict)
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "failed to apply `%s`", s)
+		}
+	}
+
+	return v, root, nil
+}
+
+// validPath returns true if a statement is a path starting with
+// a bare word and ending in a semicolon; e.g. json.city;
+func (s statement) validPath() bool {
+	if len(s) < 2 || s[0].typ != typBare || s[len(s)-1].typ != typSemi {
+		return false
+	}
+	for _, t := range s[:len(s)-1] {
+		switch t.typ {
+		case typBare, typQuotedKey, typNumericKey, typDot, typLBrace, typRBrace:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// setPath sets the value at the path made of the provided keys within
+// v, creating any objects and arrays on the way that don't exist, and
+// replacing anything else that's in the way unless strict is true
+func setPath(v interface{}, keys []pathKey, val interface{}, strict bool) (interface{}, error) {
+	if len(keys) == 0 {
+		// An empty container keeps the contents of one of the same kind
+		switch vv := val.(type) {
+		case map[string]interface{}:
+			if _, ok := v.(map[string]interface{}); ok && len(vv) == 0 {
+				return v, nil
+			}
+		case []interface{}:
+			if _, ok := v.([]interface{}); ok && len(vv) == 0 {
+				return v, nil
+			}
+		}
+		if strict && v != nil && !reflect.DeepEqual(v, val) && !sameNumber(v, val) {
+			was, _ := json.Marshal(v)
+			return nil, fmt.Errorf("conflicts with the value %s that's already there", was)
+		}
+		return val, nil
+	}
+
+	k := keys[0]
+	if k.isIndex {
+		arr, ok := v.([]interface{})
+		if !ok && v != nil && strict {
+			return nil, fmt.Errorf("cannot set index %d of non-array", k.index)
+		}
+		for len(arr) <= k.index {
+			arr = append(arr, nil)
+		}
+		sub, err := setPath(arr[k.index], keys[1:], val, strict)
+		if err != nil {
+			return nil, err
+		}
+		arr[k.index] = sub
+		return arr, nil
+	}
+
+	m, ok := v.(map[string]interface{})
+	if !ok && v != nil && strict {
+		return nil, fmt.Errorf("cannot set key %s of non-object", quoteString(k.key))
+	}
+	if m == nil {
+		m = make(map[string]interface{})
+	}
+	sub, err := setPath(m[k.key], keys[1:], val, strict)
+	if err != nil {
+		return nil, err
+	}
+	m[k.key] = sub
+	return m, nil
+}
+
+// sameNumber returns true if a and b are both numbers with the
+// same value, however they're written; e.g. 1 and 1.0
+func sameNumber(a, b interface{}) bool {
+	na, ok := a.(json.Number)
+	nb, ok2 := b.(json.Number)
+	return ok && ok2 && normalizeNumber(string(na)) == normalizeNumber(string(nb))
+}
+
+// deletePath removes the value at the path made of the provided keys
+// within v. Deleting something that doesn't exist does nothing.
+func deletePath(v interface{}, keys []pathKey) interface{} {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	k := keys[0]
+	switch vv := v.(type) {
+	case []interface{}:
+		if !k.isIndex || k.index >= len(vv) {
+			return v
+		}
+		if len(keys) == 1 {
+			return append(vv[:k.index], vv[k.index+1:]...)
+		}
+		vv[k.index] = deletePath(vv[k.index], keys[1:])
+
+	case map[string]interface{}:
+		sub, exists := vv[k.key]
+		if k.isIndex || !exists {
+			return v
+		}
+		if len(keys) == 1 {
+			delete(vv, k.key)
+			return v
+		}
+		vv[k.key] = deletePath(sub, keys[1:])
+	}
+	return v
+}
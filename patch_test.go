@@ -0,0 +1,199 @@
+package gron
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffPatch(t *testing.T) {
+	base := `{
+		"name": "Tom",
+		"likes": ["code", "cheese", "meat"],
+		"contact": {"email": "mail@tomnomnom.com", "twitter": "@TomNomNom"},
+		"address": {"city": "Leeds"},
+		"age": 30
+	}`
+	target := `{
+		"name": "Tom",
+		"likes": ["code"],
+		"contact": {"twitter": "@TomNomNom", "github": "tomnomnom"},
+		"address": "Leeds",
+		"age": 31,
+		"tags": [{"id": 1}]
+	}`
+
+	patch := &bytes.Buffer{}
+	code, err := Diff(strings.NewReader(target), patch, OptMonochrome, WithBase(strings.NewReader(base)))
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error from Diff; have %d and %v", code, err)
+	}
+
+	want := `delete json.likes[2];
+delete json.likes[1];
+delete json.contact.email;
+json.address = "Leeds";
+json.age = 31;
+json.contact.github = "tomnomnom";
+json.tags = [];
+json.tags[0] = {};
+json.tags[0].id = 1;
+`
+	if patch.String() != want {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", patch.String())
+		t.Fatalf("patch does not match")
+	}
+
+	// Applying the patch to the base should give the target
+	out := &bytes.Buffer{}
+	code, err = Ungron(patch, out, OptMonochrome, WithBase(strings.NewReader(base)))
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error from Ungron; have %d and %v", code, err)
+	}
+
+	var have, wantJSON interface{}
+	if err := json.Unmarshal(out.Bytes(), &have); err != nil {
+		t.Fatalf("failed to unmarshal patched output: %s", err)
+	}
+	if err := json.Unmarshal([]byte(target), &wantJSON); err != nil {
+		t.Fatalf("failed to unmarshal target: %s", err)
+	}
+	if !reflect.DeepEqual(have, wantJSON) {
+		t.Errorf("want patched base to be %#v; have %#v", wantJSON, have)
+	}
+}
+
+func TestDiffPatchRoundTrips(t *testing.T) {
+	cases := []struct {
+		base   string
+		target string
+	}{
+		{`{"a": 1}`, `{"a": 1}`},
+		{`{"a": 1}`, `{}`},
+		{`{}`, `{"a": {"b": [1, 2]}}`},
+		{`[1, 2, 3, 4]`, `[4, 3]`},
+		{`{"a": [1, 2]}`, `{"a": {"b": 2}}`},
+		{`{"a": {"b": 2}}`, `{"a": [1, 2]}`},
+		{`{"a": "x"}`, `{"a": {"b": null}}`},
+		{`{"a.b": 1, "c": [[1], [2, 3]]}`, `{"c": [[], [2]]}`},
+		{`[{"a": 1}, {"b": 2}]`, `[{"b": 2}]`},
+		{`{"a": 1}`, `[1]`},
+	}
+
+	for _, c := range cases {
+		patch := &bytes.Buffer{}
+		code, err := Diff(strings.NewReader(c.target), patch, OptMonochrome, WithBase(strings.NewReader(c.base)))
+		if code != ExitOK || err != nil {
+			t.Errorf("want ExitOK and nil error from Diff for %s; have %d and %v", c.target, code, err)
+			continue
+		}
+
+		out := &bytes.Buffer{}
+		code, err = Ungron(bytes.NewReader(patch.Bytes()), out, OptMonochrome, WithBase(strings.NewReader(c.base)))
+		if code != ExitOK || err != nil {
+			t.Errorf("want ExitOK and nil error applying patch %q; have %d and %v", patch, code, err)
+			continue
+		}
+
+		var have, want interface{}
+		_ = json.Unmarshal(out.Bytes(), &have)
+		_ = json.Unmarshal([]byte(c.target), &want)
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("want %s patched with %q to be %s; have %s", c.base, patch, c.target, out)
+		}
+	}
+}
+
+func TestUngronDeletions(t *testing.T) {
+	in := `json = {};
+json.a = 1;
+json.b = [1, 2, 3];
+delete json.a;
+delete json.b[0];
+delete json.missing.key;
+json.delete = true;
+`
+	out := &bytes.Buffer{}
+	code, err := Ungron(strings.NewReader(in), out, OptMonochrome)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+
+	want := `{
+  "b": [
+    2,
+    3
+  ],
+  "delete": true
+}
+`
+	if out.String() != want {
+		t.Errorf("want %s; have %s", want, out.String())
+	}
+}
+
+func TestUngronInvalidPatch(t *testing.T) {
+	cases := []struct {
+		in   string
+		opts int
+	}{
+		{"delete json.a = 1;\n", 0},
+		{"json.a = 1;\nother.b = 2;\n", 0},
+		{"json.a = 1;\njson.a.b = 2;\n", OptStrict},
+		{"json.a = {};\njson.a[0] = 2;\n", OptStrict},
+	}
+
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		code, err := Ungron(strings.NewReader(c.in), out, OptMonochrome|c.opts, WithBase(strings.NewReader(`{}`)))
+		if code != ExitParseStatements || err == nil {
+			t.Errorf("want ExitParseStatements and an error for %q; have %d and %v", c.in, code, err)
+		}
+	}
+}
+
+func TestUngronPatchConflicts(t *testing.T) {
+	base := `{"a": 1, "b": {"c": [1, null]}, "d": null}`
+	cases := []struct {
+		in     string
+		want   string
+		strict bool
+	}{
+		// Statements win over the base...
+		{"json.a.x = 2;\n", `{"a":{"x":2},"b":{"c":[1,null]},"d":null}`, false},
+		{"json.b[0] = 3;\n", `{"a":1,"b":[3],"d":null}`, false},
+		{"json.a = 5;\n", `{"a":5,"b":{"c":[1,null]},"d":null}`, false},
+
+		// ...unless they're strict, when only
+		// nulls and the same values can be set
+		{"json.a.x = 2;\n", "", true},
+		{"json.b[0] = 3;\n", "", true},
+		{"json.a = 5;\n", "", true},
+		{"json.a = 1.0;\n", `{"a":1.0,"b":{"c":[1,null]},"d":null}`, true},
+		{"json.a = 1;\njson.b = {};\njson.b.c[1] = true;\njson.d = \"x\";\n", `{"a":1,"b":{"c":[1,true]},"d":"x"}`, true},
+	}
+
+	for _, c := range cases {
+		opts := OptMonochrome
+		if c.strict {
+			opts |= OptStrict
+		}
+		out := &bytes.Buffer{}
+		code, err := Ungron(strings.NewReader(c.in), out, opts, WithBase(strings.NewReader(base)), WithIndent(""))
+		if c.want == "" {
+			if code != ExitParseStatements || err == nil {
+				t.Errorf("want ExitParseStatements and an error for %q; have %d and %v", c.in, code, err)
+			}
+			continue
+		}
+		if code != ExitOK || err != nil {
+			t.Fatalf("want ExitOK and nil error for %q; have %d and %v", c.in, code, err)
+		}
+		if out.String() != c.want+"\n" {
+			t.Errorf("want %s for %q; have %s", c.want, c.in, out.String())
+		}
+	}
+}
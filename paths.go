@@ -0,0 +1,72 @@
+package gron
+
+import (
+	"bytes"
+	"strings"
+)
+
+// A PathDialect is a notation that the paths in statements are written in
+type PathDialect string
+
+// Supported path dialects
+const (
+	// PathJS is JavaScript notation, which is the default;
+	// e.g. json.users[0]["first name"]
+	PathJS PathDialect = "js"
+
+	// PathJSONPath is JSONPath notation; e.g. $.users[0]['first name']
+	PathJSONPath PathDialect = "jsonpath"
+)
+
+// PathDialects is the list of supported path dialects
+var PathDialects = []PathDialect{PathJS, PathJSONPath}
+
+// WithPathDialect sets the notation that the gron actions write paths
+// in; the default is PathJS. It has no effect on OptJSON or OptEvents.
+// Ungron reads paths written in either dialect.
+func WithPathDialect(d PathDialect) Option {
+	return func(c *config) {
+		c.pathDialect = d
+	}
+}
+
+// convert returns a copy of a statement with its path written in the
+// dialect. Statements are made in JavaScript notation, so that's left
+// as it is
+func (d PathDialect) convert(s statement) statement {
+	if d != PathJSONPath || len(s) == 0 {
+		return s
+	}
+
+	out := make(statement, len(s))
+	copy(out, s)
+	out[0] = token{"$", typBare}
+	for i, t := range out {
+		if t.typ == typQuotedKey {
+			out[i] = token{jsonPathQuote(t.key()), typQuotedKey}
+		}
+	}
+	return out
+}
+
+// jsonPathQuote quotes a key for JSONPath: with single quotes, and
+// the same escaping as JSON other than for the quotes themselves
+func jsonPathQuote(k string) string {
+	q := quoteString(k)
+	r := strings.NewReplacer(`\\`, `\\`, `\"`, `"`, `'`, `\'`)
+	return "'" + r.Replace(q[1:len(q)-1]) + "'"
+}
+
+// jsonPathUnquote turns a key quoted with single quotes for
+// JSONPath back into one quoted with double quotes for JSON
+func jsonPathUnquote(q string) string {
+	if len(q) < 2 {
+		return q
+	}
+	r := strings.NewReplacer(`\\`, `\\`, `\'`, `'`, `"`, `\"`)
+	out := &bytes.Buffer{}
+	out.WriteByte('"')
+	out.WriteString(r.Replace(q[1 : len(q)-1]))
+	out.WriteByte('"')
+	return out.String()
+}
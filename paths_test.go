@@ -0,0 +1,55 @@
+package gron
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGronJSONPath(t *testing.T) {
+	in := `{"users": [{"first name": "Tom", "it's": "a \"quote\"", "back\\slash": 1}]}`
+
+	out := &bytes.Buffer{}
+	code, err := Gron(strings.NewReader(in), out, OptMonochrome, WithPathDialect(PathJSONPath))
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+
+	want := `$ = {};
+$.users = [];
+$.users[0] = {};
+$.users[0]['back\\slash'] = 1;
+$.users[0]['first name'] = "Tom";
+$.users[0]['it\'s'] = "a \"quote\"";
+`
+	if out.String() != want {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", out.String())
+		t.Errorf("JSONPath output does not match")
+	}
+
+	// The output ungrons to the input
+	ungrond := &bytes.Buffer{}
+	code, err = Ungron(out, ungrond, OptMonochrome, WithIndent(""))
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	wantJSON := `{"users":[{"back\\slash":1,"first name":"Tom","it's":"a \"quote\""}]}` + "\n"
+	if ungrond.String() != wantJSON {
+		t.Errorf("want %s; have %s", wantJSON, ungrond.String())
+	}
+}
+
+func TestJSONPathQuote(t *testing.T) {
+	for _, k := range []string{"", "a key", "it's", `"`, `\`, `\'`, "tab\there", "é"} {
+		q := jsonPathQuote(k)
+		s := statementFromString("json[" + q + "] = 1;")
+		if !s.valid() {
+			t.Errorf("want a valid statement for key %q quoted as %s; have %s", k, q, s)
+			continue
+		}
+		if have := s.pathKeys()[0].key; have != k {
+			t.Errorf("want key %q back from %s; have %q", k, q, have)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package gron
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+)
+
+// PrecisionCheck is an action that writes only the statements with
+// numbers that would change if they were parsed as a float64 (as most
+// JSON implementations do) and formatted again; e.g. integers beyond
+// 2^53 or decimals with more than about 17 significant digits. Each
+// statement has the original number, followed by a comment giving the
+// number it would become:
+//
+//   json.id = 12345678901234567891; // float64: 12345678901234567000
+//
+// Numbers that are merely written differently when formatted again,
+// like 1.50 and 1.5, are not reported.
+func PrecisionCheck(r io.Reader, w io.Writer, opts int, options ...Option) (code int, err error) {
+	c := newConfig(options)
+	c.setOpts(opts)
+	r = c.limitInput(r)
+	defer c.checkInputSize(&code, &err)
+
+	ss, err := statementsFromJSON(r, c.prefix(), c)
+	if err != nil {
+		return ExitFormStatements, fmt.Errorf("failed to form statements: %s", err)
+	}
+	sortStatements(ss, opts)
+
+	var conv statementconv
+	if opts&OptMonochrome > 0 {
+		conv = statementToString
+	} else {
+		conv = statementToColorString
+	}
+
+	for _, s := range ss {
+		v, ok := s.value()
+		if !ok || v.typ != typNumber || !c.included(s) {
+			continue
+		}
+		f, exact := float64Form(v.text)
+		if exact {
+			continue
+		}
+		fmt.Fprintf(w, "%s // float64: %s\n", conv(s), f)
+	}
+
+	return ExitOK, nil
+}
+
+// float64Form returns the number that a JSON number becomes when it's
+// parsed as a float64 and formatted again, and whether that's the same
+// number, albeit perhaps written differently
+func float64Form(n string) (string, bool) {
+	f, err := strconv.ParseFloat(n, 64)
+	if err != nil {
+		if nerr, ok := err.(*strconv.NumError); !ok || nerr.Err != strconv.ErrRange {
+			return n, true
+		}
+	}
+	formatted := normalizeNumber(strconv.FormatFloat(f, 'g', -1, 64))
+
+	want, ok := new(big.Rat).SetString(n)
+	if !ok {
+		return n, true
+	}
+	have, ok := new(big.Rat).SetString(formatted)
+	if !ok {
+		// Infinity; the number was too big for a float64
+		return formatted, false
+	}
+	return formatted, want.Cmp(have) == 0
+}
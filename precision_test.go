@@ -0,0 +1,53 @@
+package gron
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFloat64Form(t *testing.T) {
+	cases := []struct {
+		in    string
+		want  string
+		exact bool
+	}{
+		{"1", "1", true},
+		{"1.50", "1.5", true},
+		{"0.1", "0.1", true},
+		{"1e3", "1000", true},
+		{"9007199254740993", "9007199254740992", false},
+		{"12345678901234567891", "12345678901234567000", false},
+		{"0.12345678901234567891", "0.12345678901234568", false},
+		{"1e400", "+Inf", false},
+	}
+
+	for _, c := range cases {
+		have, exact := float64Form(c.in)
+		if have != c.want || exact != c.exact {
+			t.Errorf("want %s, %t for %s; have %s, %t", c.want, c.exact, c.in, have, exact)
+		}
+	}
+}
+
+func TestPrecisionCheck(t *testing.T) {
+	in := `{"id": 12345678901234567891, "price": 1.50, "total": 0.12345678901234567891, "name": "x"}`
+
+	out := &bytes.Buffer{}
+	code, err := PrecisionCheck(strings.NewReader(in), out, OptMonochrome)
+	if code != ExitOK {
+		t.Errorf("want ExitOK; have %d", code)
+	}
+	if err != nil {
+		t.Errorf("want nil error; have %s", err)
+	}
+
+	want := `json.id = 12345678901234567891; // float64: 12345678901234567000
+json.total = 0.12345678901234567891; // float64: 0.12345678901234568
+`
+	if out.String() != want {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", out.String())
+		t.Errorf("precision check output does not match")
+	}
+}
@@ -0,0 +1,150 @@
+package gron
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// SchemaDialect is the JSON Schema draft that Schema writes
+const SchemaDialect = "http://json-schema.org/draft-07/schema#"
+
+// Schema is an action that infers a JSON Schema from its input and
+// writes that rather than statements. The schema records the types
+// seen at each path, with the elements of arrays merged into a single
+// schema for their items; where values differ in type the schema has
+// a union of types.
+//
+// The inference is conservative: a property is only required if every
+// object seen at that path has it, and nothing else (formats, patterns,
+// enums, additionalProperties) is inferred. The result is a starting
+// point, described as such in the schema, rather than a finished one.
+func Schema(r io.Reader, w io.Writer, opts int, options ...Option) (code int, err error) {
+	c := newConfig(options)
+	r = c.limitInput(r)
+	defer c.checkInputSize(&code, &err)
+	top, err := decodeJSON(r, opts&OptLenient > 0, nil)
+	if err != nil {
+		return ExitFormStatements, fmt.Errorf("failed to infer schema: %s", err)
+	}
+
+	s := newSchemaNode()
+	s.add(top)
+
+	out := s.toJSON()
+	out["$schema"] = SchemaDialect
+	out["description"] = "Inferred by gron from example data; review before use"
+
+	return writeJSON(w, out, opts, c.indent)
+}
+
+// a schemaNode accumulates what's known about the values at one path
+type schemaNode struct {
+	types map[string]bool
+
+	// For objects: the schema for each property, and the
+	// number of objects seen with it, out of the total
+	properties map[string]*schemaNode
+	seen       map[string]int
+	objects    int
+
+	// For arrays: the merged schema of all elements
+	items *schemaNode
+}
+
+func newSchemaNode() *schemaNode {
+	return &schemaNode{
+		types:      make(map[string]bool),
+		properties: make(map[string]*schemaNode),
+		seen:       make(map[string]int),
+	}
+}
+
+// add records a value in the schema
+func (s *schemaNode) add(v interface{}) {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		s.types["object"] = true
+		s.objects++
+		for k, sub := range vv {
+			p, ok := s.properties[k]
+			if !ok {
+				p = newSchemaNode()
+				s.properties[k] = p
+			}
+			p.add(sub)
+			s.seen[k]++
+		}
+
+	case []interface{}:
+		s.types["array"] = true
+		if s.items == nil {
+			s.items = newSchemaNode()
+		}
+		for _, sub := range vv {
+			s.items.add(sub)
+		}
+
+	case string:
+		s.types["string"] = true
+	case bool:
+		s.types["boolean"] = true
+	case nil:
+		s.types["null"] = true
+	default:
+		n := valueTokenFromInterface(v).text
+		if strings.ContainsAny(n, ".eE") {
+			s.types["number"] = true
+		} else {
+			s.types["integer"] = true
+		}
+	}
+}
+
+// toJSON returns the schema as a value for encoding/json
+func (s *schemaNode) toJSON() map[string]interface{} {
+	out := make(map[string]interface{})
+
+	// Integers are numbers too, so there's no need for both
+	if s.types["number"] {
+		delete(s.types, "integer")
+	}
+	types := make([]string, 0, len(s.types))
+	for t := range s.types {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	switch len(types) {
+	case 0:
+		// An array that's only ever been seen empty;
+		// nothing is known about its items
+	case 1:
+		out["type"] = types[0]
+	default:
+		out["type"] = types
+	}
+
+	if s.types["object"] {
+		props := make(map[string]interface{})
+		required := make([]string, 0)
+		for k, p := range s.properties {
+			props[k] = p.toJSON()
+			if s.seen[k] == s.objects {
+				required = append(required, k)
+			}
+		}
+		sort.Strings(required)
+		out["properties"] = props
+		if len(required) > 0 {
+			out["required"] = required
+		}
+	}
+
+	if s.types["array"] && s.items != nil {
+		out["items"] = s.items.toJSON()
+	}
+
+	return out
+}
@@ -0,0 +1,71 @@
+package gron
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	in := `{
+		"id": 1,
+		"tags": [],
+		"users": [
+			{"name": "Tom", "age": 30, "email": null},
+			{"name": "Bob", "age": 41.5}
+		],
+		"mixed": [1, "two", [3]]
+	}`
+
+	out := &bytes.Buffer{}
+	code, err := Schema(strings.NewReader(in), out, OptMonochrome)
+	if code != ExitOK {
+		t.Errorf("want ExitOK; have %d", code)
+	}
+	if err != nil {
+		t.Errorf("want nil error; have %s", err)
+	}
+
+	want := `{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"description": "Inferred by gron from example data; review before use",
+		"type": "object",
+		"required": ["id", "mixed", "tags", "users"],
+		"properties": {
+			"id": {"type": "integer"},
+			"tags": {"type": "array", "items": {}},
+			"users": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"required": ["age", "name"],
+					"properties": {
+						"age": {"type": "number"},
+						"email": {"type": "null"},
+						"name": {"type": "string"}
+					}
+				}
+			},
+			"mixed": {
+				"type": "array",
+				"items": {
+					"type": ["array", "integer", "string"],
+					"items": {"type": "integer"}
+				}
+			}
+		}
+	}`
+
+	var have, wantJSON interface{}
+	if err := json.Unmarshal(out.Bytes(), &have); err != nil {
+		t.Fatalf("failed to unmarshal schema: %s", err)
+	}
+	json.Unmarshal([]byte(want), &wantJSON)
+	if !reflect.DeepEqual(have, wantJSON) {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", out.String())
+		t.Errorf("inferred schema does not match")
+	}
+}
@@ -0,0 +1,65 @@
+package gron
+
+import (
+	"sort"
+)
+
+// WithSelectIndex limits the elements of a top-level array to those at
+// the provided indexes, which are used in ascending order regardless of
+// the order they're given in. Unless reindex is true the elements keep
+// their original indexes (e.g. json[5]) so the output can be matched up
+// with the input; with reindex they're numbered from zero, as they would
+// be in an array of just the selected elements.
+//
+// The other elements are skipped without being walked, and indexes
+// beyond the end of the array are reported with WithWarnings. So is a
+// top-level value that isn't an array, of which only the top-level
+// statement is written; e.g. json = {};
+//
+// GronStream doesn't use the indexes, as it has no top-level array.
+func WithSelectIndex(indexes []int, reindex bool) Option {
+	return func(c *config) {
+		sel := make([]int, 0, len(indexes))
+		seen := make(map[int]bool)
+		for _, i := range indexes {
+			if i < 0 || seen[i] {
+				continue
+			}
+			seen[i] = true
+			sel = append(sel, i)
+		}
+		sort.Ints(sel)
+
+		c.selectIndexes = sel
+		c.reindex = reindex
+	}
+}
+
+// selecting returns true if the elements of an array
+// at the provided path are limited by WithSelectIndex
+func (c *config) selecting(path statement) bool {
+	return c.selectIndexes != nil && len(path) == len(c.prefix())
+}
+
+// selected returns true if the element at index i of the top-level array
+// was selected with WithSelectIndex, along with the index it should have
+func (c *config) selected(i int) (int, bool) {
+	pos := sort.SearchInts(c.selectIndexes, i)
+	if pos == len(c.selectIndexes) || c.selectIndexes[pos] != i {
+		return 0, false
+	}
+	if c.reindex {
+		return pos, true
+	}
+	return i, true
+}
+
+// warnUnselected warns about any selected index that
+// isn't in a top-level array with n elements
+func (c *config) warnUnselected(n int) {
+	for _, i := range c.selectIndexes {
+		if i >= n {
+			c.warnf("index %d is out of range for an array of length %d", i, n)
+		}
+	}
+}
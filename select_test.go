@@ -0,0 +1,103 @@
+package gron
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGronSelectIndex(t *testing.T) {
+	in := `[{"id": 0}, {"id": 1}, {"id": 2, "tags": [0, 1, 2]}, {"id": 3}]`
+
+	cases := []struct {
+		reindex bool
+		want    string
+	}{
+		{false, `json = [];
+json[0] = {};
+json[0].id = 0;
+json[2] = {};
+json[2].id = 2;
+json[2].tags = [];
+json[2].tags[0] = 0;
+json[2].tags[1] = 1;
+json[2].tags[2] = 2;
+`},
+		{true, `json = [];
+json[0] = {};
+json[0].id = 0;
+json[1] = {};
+json[1].id = 2;
+json[1].tags = [];
+json[1].tags[0] = 0;
+json[1].tags[1] = 1;
+json[1].tags[2] = 2;
+`},
+	}
+
+	for _, c := range cases {
+		for _, memory := range []int64{0, 16} {
+			var warnings []string
+			options := []Option{
+				WithSelectIndex([]int{2, 7, 0, 2}, c.reindex),
+				WithWarnings(func(msg string) {
+					warnings = append(warnings, msg)
+				}),
+			}
+			if memory > 0 {
+				options = append(options, WithMaxMemory(memory))
+			}
+
+			out := &bytes.Buffer{}
+			code, err := Gron(strings.NewReader(in), out, OptMonochrome, options...)
+			if code != ExitOK {
+				t.Errorf("want ExitOK; have %d", code)
+			}
+			if err != nil {
+				t.Errorf("want nil error; have %s", err)
+			}
+			if out.String() != c.want {
+				t.Logf("want: %s", c.want)
+				t.Logf("have: %s", out.String())
+				t.Errorf("selected output with reindex %t and max memory %d does not match", c.reindex, memory)
+			}
+
+			wantWarnings := []string{"index 7 is out of range for an array of length 4"}
+			if !reflect.DeepEqual(warnings, wantWarnings) {
+				t.Errorf("want warnings %q; have %q", wantWarnings, warnings)
+			}
+		}
+	}
+}
+
+func TestGronSelectIndexNotArray(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{`{"a": [1, 2]}`, "json = {};\n"},
+		{`"x"`, "json = \"x\";\n"},
+	}
+
+	for _, c := range cases {
+		var warnings []string
+		warn := WithWarnings(func(msg string) {
+			warnings = append(warnings, msg)
+		})
+
+		out := &bytes.Buffer{}
+		code, err := Gron(strings.NewReader(c.in), out, OptMonochrome, WithSelectIndex([]int{0}, false), warn)
+		if code != ExitOK || err != nil {
+			t.Fatalf("want ExitOK and nil error for %s; have %d and %v", c.in, code, err)
+		}
+		if out.String() != c.want {
+			t.Errorf("want %q for %s; have %q", c.want, c.in, out.String())
+		}
+
+		wantWarnings := []string{"can't select indexes from json, which isn't an array"}
+		if !reflect.DeepEqual(warnings, wantWarnings) {
+			t.Errorf("want warnings %q for %s; have %q", wantWarnings, c.in, warnings)
+		}
+	}
+}
@@ -1,10 +1,16 @@
 package gron
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"math"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -21,6 +27,282 @@ import (
 // that the same type can easily be used when gronning and ungronning.
 type statement []token
 
+// A Statement is a single assignment statement as produced by
+// the gron action; e.g. json.city = "Leeds";
+type Statement struct {
+	tokens statement
+
+	// err is the first error from Append, if there was one
+	err error
+}
+
+// String returns the monochrome string form of a Statement
+func (s Statement) String() string {
+	return s.tokens.String()
+}
+
+// Path returns the left hand side of a Statement; e.g. json.city
+func (s Statement) Path() string {
+	return s.tokens.path().String()
+}
+
+// Value returns the right hand side of a Statement, without the
+// trailing semicolon; e.g. "Leeds"
+func (s Statement) Value() string {
+	v, ok := s.tokens.value()
+	if !ok {
+		return ""
+	}
+	return v.text
+}
+
+// A TokenType is a kind of part that can be appended to a Statement
+type TokenType int
+
+// Token types for building statements with Statement.Append
+const (
+	// TokenBare is the bare word every statement starts with, like
+	// json; or an object key that's written as a bare word, like city
+	TokenBare TokenType = iota
+
+	// TokenKey is an object key, written as a bare word where it's
+	// a valid identifier and quoted otherwise; e.g. "first name"
+	TokenKey
+
+	// TokenIndex is an array index, in decimal; e.g. 2 in json[2]
+	TokenIndex
+
+	// Value types: each of these ends the statement with an assignment.
+	// Strings are quoted and escaped, numbers must be valid JSON numbers,
+	// bools are true or false, and the text of the rest is ignored
+	TokenString
+	TokenNumber
+	TokenBool
+	TokenNull
+	TokenEmptyArray
+	TokenEmptyObject
+)
+
+// Append returns a copy of a Statement with another part added; so that
+// statements can be built without going through JSON. For example:
+//
+//   s := Statement{}.Append(TokenBare, "json").
+//       Append(TokenKey, "first name").
+//       Append(TokenString, "Tom")
+//
+// is json["first name"] = "Tom"; The first part must be TokenBare, and
+// nothing can be appended after a value. A part that breaks those rules,
+// or whose text isn't valid for its type (e.g. a TokenBool of "yes"), is
+// left out; and it and any parts after it are reported by Err.
+func (s Statement) Append(kind TokenType, text string) Statement {
+	if s.err != nil {
+		return s
+	}
+	if err := s.checkAppend(kind, text); err != nil {
+		return Statement{tokens: s.tokens, err: err}
+	}
+
+	if kind == TokenKey {
+		return Statement{tokens: s.tokens.withKey(text)}
+	}
+
+	ts := make(statement, len(s.tokens), len(s.tokens)+3)
+	copy(ts, s.tokens)
+	switch kind {
+	case TokenBare:
+		if len(ts) > 0 {
+			ts = append(ts, token{".", typDot})
+		}
+		ts = append(ts, token{text, typBare})
+	case TokenIndex:
+		ts = append(ts, token{"[", typLBrace}, token{text, typNumericKey}, token{"]", typRBrace})
+	default:
+		ts = append(ts, token{"=", typEquals}, kind.valueToken(text), token{";", typSemi})
+	}
+	return Statement{tokens: ts}
+}
+
+// Err returns the error for the first part that couldn't be
+// appended to a Statement with Append, or nil if there wasn't one
+func (s Statement) Err() error {
+	return s.err
+}
+
+// checkAppend returns an error if a part can't be appended to a Statement
+func (s Statement) checkAppend(kind TokenType, text string) error {
+	if len(s.tokens) > 0 && s.tokens[len(s.tokens)-1].typ == typSemi {
+		return fmt.Errorf("can't append to `%s`; it already has a value", s)
+	}
+	if len(s.tokens) == 0 && kind != TokenBare {
+		return fmt.Errorf("the first part of a statement must be TokenBare")
+	}
+
+	switch kind {
+	case TokenBare, TokenKey, TokenString, TokenNull, TokenEmptyArray, TokenEmptyObject:
+		return nil
+	case TokenIndex:
+		if n, err := strconv.Atoi(text); err != nil || n < 0 || strconv.Itoa(n) != text {
+			return fmt.Errorf("invalid array index %q", text)
+		}
+	case TokenNumber:
+		if !isJSONNumber(text) {
+			return fmt.Errorf("invalid number %q", text)
+		}
+	case TokenBool:
+		if text != "true" && text != "false" {
+			return fmt.Errorf("invalid bool %q; must be true or false", text)
+		}
+	default:
+		return fmt.Errorf("unknown token type %d", kind)
+	}
+	return nil
+}
+
+// valueToken returns the value token for a value type and its text
+func (kind TokenType) valueToken(text string) token {
+	switch kind {
+	case TokenString:
+		return token{quoteString(text), typString}
+	case TokenNumber:
+		return token{text, typNumber}
+	case TokenBool:
+		switch text {
+		case "true":
+			return token{text, typTrue}
+		case "false":
+			return token{text, typFalse}
+		}
+	case TokenNull:
+		return token{"null", typNull}
+	case TokenEmptyArray:
+		return token{"[]", typEmptyArray}
+	case TokenEmptyObject:
+		return token{"{}", typEmptyObject}
+	}
+	return token{text, typError}
+}
+
+// Render returns the string form of a Statement just as the gron
+// action writes it; with color unless monochrome is true
+func (s Statement) Render(monochrome bool) string {
+	if monochrome {
+		return statementToString(s.tokens)
+	}
+	return statementToColorString(s.tokens)
+}
+
+// ParseStatement parses a single assignment statement in the form
+// written by the gron action; e.g. json.city = "Leeds";
+func ParseStatement(str string) (Statement, error) {
+	s := statementFromString(str)
+	if !s.valid() {
+		return Statement{}, fmt.Errorf("invalid statement `%s`", str)
+	}
+	return Statement{tokens: s}, nil
+}
+
+// StatementsToJSON reconstructs compact JSON from a list of statements,
+// as the ungron action does from text; the statements don't need to be
+// in any particular order. An error is returned if there are no
+// statements, if any of them isn't a valid assignment (e.g. the zero
+// Statement), or if they conflict; e.g. by assigning both an object and
+// a string to the same path.
+func StatementsToJSON(ss []Statement) ([]byte, error) {
+	tokens := make(statements, 0, len(ss))
+	for _, s := range ss {
+		if s.err != nil {
+			return nil, s.err
+		}
+		if !s.tokens.valid() {
+			return nil, fmt.Errorf("invalid statement `%s`", s)
+		}
+		tokens = append(tokens, s.tokens)
+	}
+
+	v, _, err := ungronStatements(tokens, newConfig(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	out := &bytes.Buffer{}
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, errors.Wrap(err, "failed to convert statements to JSON")
+	}
+	return bytes.TrimSpace(out.Bytes()), nil
+}
+
+// valid returns true if a statement is a complete assignment
+// starting with a bare word; e.g. json.city = "Leeds";
+func (s statement) valid() bool {
+	if len(s) < 4 || s[0].typ != typBare || s[len(s)-1].typ != typSemi {
+		return false
+	}
+	for _, t := range s {
+		if t.typ == typError || t.typ == typIgnored {
+			return false
+		}
+	}
+	v := s[len(s)-2]
+	if v.typ == typNumber && !isJSONNumber(v.text) {
+		return false
+	}
+	return v.isValue() && s[len(s)-3].typ == typEquals
+}
+
+// parseError returns what's wrong with a statement that can't be
+// ungronned, or nil if there's nothing wrong with it; lines that
+// are skipped, like blank ones, and deletions are fine. Assignments
+// to array indexes more than maxIndex aren't
+func (s statement) parseError(maxIndex int) error {
+	if len(s) == 0 || s[0].typ == typIgnored || s.deletion() {
+		return nil
+	}
+	if !s.valid() {
+		_, err := ungronTokens(s, maxIndex)
+		if _, ok := err.(errRecoverable); ok {
+			return nil
+		}
+		return err
+	}
+	for _, k := range s.pathKeys() {
+		if !k.isIndex {
+			continue
+		}
+		if err := checkIndex(k.index, maxIndex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deletion returns true if a statement deletes a path rather
+// than assigning to it; e.g. delete json.city;
+func (s statement) deletion() bool {
+	return len(s) > 0 && s[0].typ == typDelete
+}
+
+// path returns the tokens in a statement before the assignment
+func (s statement) path() statement {
+	for i, t := range s {
+		if t.typ == typEquals {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// value returns the value token of an assignment statement
+func (s statement) value() (token, bool) {
+	for i, t := range s {
+		if t.typ == typEquals && i+1 < len(s) && s[i+1].isValue() {
+			return s[i+1], true
+		}
+	}
+	return token{}, false
+}
+
 // String returns the string form of a statement rather than the
 // underlying slice of tokens
 func (s statement) String() string {
@@ -102,6 +384,79 @@ func (s statement) jsonify() (statement, error) {
 	return j, nil
 }
 
+// withKey returns a copy of a statement with a new object key
+// appended to it; as a bare word if possible or quoted otherwise
+func (s statement) withKey(k string) statement {
+	if validIdentifier(k) {
+		return s.withBare(k)
+	}
+	return s.withQuotedKey(k)
+}
+
+// withNormalizedNumbers returns a copy of a statement with
+// any number value normalized by normalizeNumber
+func (s statement) withNormalizedNumbers() statement {
+	new := make(statement, len(s))
+	copy(new, s)
+	for i, t := range new {
+		if t.typ == typNumber {
+			new[i].text = normalizeNumber(t.text)
+		}
+	}
+	return new
+}
+
+// withFormattedNumbers returns a copy of a statement with any
+// number value formatted as a float64 with the provided verb. A
+// number too big for a float64 (e.g. 1e400) is an error rather than
+// being formatted as +Inf or -Inf
+func (s statement) withFormattedNumbers(format string) (statement, error) {
+	new := make(statement, len(s))
+	copy(new, s)
+	for i, t := range new {
+		if t.typ != typNumber {
+			continue
+		}
+		f, err := strconv.ParseFloat(t.text, 64)
+		if math.IsInf(f, 0) {
+			return nil, fmt.Errorf("number %s at %s is too big to format as a float64", t.text, s.path())
+		}
+		if err != nil {
+			continue
+		}
+		new[i].text = fmt.Sprintf(format, f)
+	}
+	return new, nil
+}
+
+// withTruncatedString returns a copy of a statement with a string value
+// longer than n runes cut down to n runes and an ellipsis, along with the
+// number of bytes that were cut off. Other statements are returned as
+// they are, with zero
+func (s statement) withTruncatedString(n int) (statement, int) {
+	for i, t := range s {
+		if t.typ != typString {
+			continue
+		}
+		var str string
+		if err := json.Unmarshal([]byte(t.text), &str); err != nil {
+			return s, 0
+		}
+		runes := []rune(str)
+		if len(runes) <= n {
+			return s, 0
+		}
+		kept := string(runes[:n])
+		q := quoteString(kept)
+
+		new := make(statement, len(s))
+		copy(new, s)
+		new[i].text = q[:len(q)-1] + "…\""
+		return new, len(str) - len(kept)
+	}
+	return s, 0
+}
+
 // withQuotedKey returns a copy of a statement with a new
 // quoted key token appended to it
 func (s statement) withQuotedKey(k string) statement {
@@ -128,6 +483,25 @@ func (s statement) withNumericKey(k int) statement {
 	)
 }
 
+// keys returns the object keys and array indexes in a statement's
+// path, not including the top-level bare word. Quoted keys are
+// unquoted and array indexes are returned in their decimal form.
+func (s statement) keys() []string {
+	keys := make([]string, 0, len(s)/2)
+	for i, t := range s {
+		if i == 0 {
+			continue
+		}
+		switch t.typ {
+		case typBare, typNumericKey, typQuotedKey:
+			keys = append(keys, t.key())
+		case typEquals:
+			return keys
+		}
+	}
+	return keys
+}
+
 // statements is a list of assignment statements.
 // E.g statement: json.foo = "bar";
 type statements []statement
@@ -158,7 +532,8 @@ func (ss statements) Swap(i, j int) {
 }
 
 // a statementmaker is a function that makes a statement
-// from string
+// from string. Its errors don't need to quote the string,
+// as the LineError they're returned in does that
 type statementmaker func(str string) (statement, error)
 
 // statementFromString takes statement string, lexes it and returns
@@ -174,6 +549,238 @@ func statementFromStringMaker(str string) (statement, error) {
 	return statementFromString(str), nil
 }
 
+// statementFromInferredString makes a statement from a relaxed
+// 'key.path = value' line, like those found in INI files. The path
+// doesn't need to start with 'json', and the value is bare, with its
+// type inferred using inferValue. Keys in the path that aren't valid
+// identifiers, like my-key or 'a key', are quoted; so my-key = 1 is
+// json["my-key"] = 1. Blank lines and comments (lines starting with
+// ';' or '#') are ignored.
+func statementFromInferredString(str string) (statement, error) {
+	trimmed := strings.TrimSpace(str)
+	if trimmed == "" {
+		return statement{}, nil
+	}
+	if trimmed[0] == ';' || trimmed[0] == '#' {
+		return statement{{trimmed, typIgnored}}, nil
+	}
+
+	parts := strings.SplitN(trimmed, "=", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("no '=' found")
+	}
+
+	path := strings.TrimSpace(parts[0])
+	if path == "" {
+		return nil, fmt.Errorf("no key found")
+	}
+	path = inferredPath(path)
+
+	s := statementFromString("json" + path + " = " + inferValue(strings.TrimSpace(parts[1])) + ";")
+	if len(s) == 0 || s[len(s)-1].typ != typSemi {
+		return nil, fmt.Errorf("invalid path or value")
+	}
+	return s, nil
+}
+
+// inferredPath converts the path of a 'key.path = value' line into
+// the rest of a statement's path after json; e.g. a.my-key[0] becomes
+// .a["my-key"][0]. The path is split at dots that aren't in brackets,
+// and the key at the start of each part is quoted if it needs to be
+func inferredPath(path string) string {
+	out := &strings.Builder{}
+	part := func(p string) {
+		key, rest := p, ""
+		if i := strings.IndexByte(p, '['); i >= 0 {
+			key, rest = p[:i], p[i:]
+		}
+		key = strings.TrimSpace(key)
+		switch {
+		case validIdentifier(key):
+			out.WriteString("." + key)
+		case key != "":
+			out.WriteString("[" + quoteString(key) + "]")
+		case rest == "":
+			// An empty part, as in a..b, is left invalid
+			out.WriteString(".")
+		}
+		out.WriteString(rest)
+	}
+
+	depth, start, inString := 0, 0, false
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case inString && c == '\\':
+			i++
+		case inString:
+			inString = c != '"'
+		case depth > 0 && c == '"':
+			inString = true
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == '.' && depth == 0:
+			part(path[start:i])
+			start = i + 1
+		}
+	}
+	part(path[start:])
+	return out.String()
+}
+
+// statementFromColumns makes a statement from a path, like json.a[0],
+// and a value whose type is inferred with inferValue
+func statementFromColumns(path, value string) (statement, error) {
+	path = strings.TrimSpace(path)
+	s := statementFromString(path + " = " + inferValue(value) + ";")
+	if len(s) == 0 || s[0].typ != typBare || s[len(s)-1].typ != typSemi {
+		return nil, fmt.Errorf("invalid path `%s`", path)
+	}
+	return s, nil
+}
+
+// statementFromTSV makes a statement from a path<TAB>value line
+func statementFromTSV(str string) (statement, error) {
+	if strings.TrimSpace(str) == "" {
+		return statement{}, nil
+	}
+	parts := strings.SplitN(str, "\t", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("no tab found")
+	}
+	return statementFromColumns(parts[0], parts[1])
+}
+
+// statementsFromCSV reads path,value records from CSV input and
+// returns the statements they represent. A header record of
+// path,value is skipped if there is one.
+func statementsFromCSV(r io.Reader) (statements, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 2
+
+	var ss statements
+	for line := 1; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return ss, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && record[0] == "path" && record[1] == "value" {
+			continue
+		}
+		s, err := statementFromColumns(record[0], record[1])
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %s", line, err)
+		}
+		ss.add(s)
+	}
+}
+
+// inferValue turns a bare value into a JSON value. The rules are:
+//
+//   true or false -> boolean
+//   null          -> null
+//   a JSON number -> number (so no leading zeros, e.g. 007 is a string)
+//   a JSON string -> string (i.e. values that are already quoted)
+//   anything else -> string
+func inferValue(v string) string {
+	switch v {
+	case "true", "false", "null":
+		return v
+	}
+
+	if isJSONNumber(v) {
+		return v
+	}
+
+	if len(v) > 1 && v[0] == '"' {
+		var str string
+		if err := json.Unmarshal([]byte(v), &str); err == nil {
+			return v
+		}
+	}
+
+	return quoteString(v)
+}
+
+// isJSONNumber returns true if the string is a valid JSON number
+func isJSONNumber(s string) bool {
+	var n json.Number
+	if err := json.Unmarshal([]byte(s), &n); err != nil {
+		return false
+	}
+	return s != "" && s[0] != '"'
+}
+
+// inlineArrayToken returns a single value token for v if it's a
+// non-empty array containing only scalar values; applying any
+// registered transforms to each of the values
+func inlineArrayToken(prefix statement, v interface{}, c *config) (token, bool) {
+	vv, ok := v.([]interface{})
+	if !ok || len(vv) == 0 || !scalarsOnly(vv) {
+		return token{}, false
+	}
+
+	elems := make([]string, 0, len(vv))
+	for k, sub := range vv {
+		sub = c.transform(prefix.withNumericKey(k), sub)
+		elems = append(elems, valueTokenFromInterface(sub).text)
+	}
+	return token{"[" + strings.Join(elems, ",") + "]", typInlineArray}, true
+}
+
+// scalarsOnly returns true if none of the values are objects or arrays
+func scalarsOnly(vs []interface{}) bool {
+	for _, v := range vs {
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			return false
+		}
+	}
+	return true
+}
+
+// sortedByEncoding returns a copy of vs ordered by the JSON encoding
+// of each value; which is canonical because encoding/json sorts keys
+func sortedByEncoding(vs []interface{}) []interface{} {
+	b := byEncoding{
+		values: make([]interface{}, len(vs)),
+		keys:   make([]string, len(vs)),
+	}
+	copy(b.values, vs)
+	for i, v := range vs {
+		enc, _ := json.Marshal(v)
+		b.keys[i] = string(enc)
+	}
+	sort.Stable(b)
+	return b.values
+}
+
+// byEncoding sorts values by their pre-computed JSON encoding
+type byEncoding struct {
+	values []interface{}
+	keys   []string
+}
+
+// Len returns the number of values for sort.Sort
+func (b byEncoding) Len() int {
+	return len(b.keys)
+}
+
+// Swap swaps two values for sort.Sort
+func (b byEncoding) Swap(i, j int) {
+	b.values[i], b.values[j] = b.values[j], b.values[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
+
+// Less compares the encodings of two values for sort.Sort
+func (b byEncoding) Less(i, j int) bool {
+	return b.keys[i] < b.keys[j]
+}
+
 // statementFromJson returns statement encoded by
 // JSON specification
 func statementFromJSONSpec(str string) (statement, error) {
@@ -236,11 +843,14 @@ func statementFromJSONSpec(str string) (statement, error) {
 	case string:
 		t = typString
 	case []interface{}:
-		ok = (len(v) == 0)
+		ok = scalarsOnly(v)
 		if !ok {
 			goto out
 		}
 		t = typEmptyArray
+		if len(v) > 0 {
+			t = typInlineArray
+		}
 	case map[string]interface{}:
 		ok = (len(v) == 0)
 		if !ok {
@@ -271,13 +881,14 @@ out:
 	return s, nil
 }
 
-// ungron turns statements into a proper datastructure
-func (ss statements) toInterface() (interface{}, error) {
+// ungron turns statements into a proper datastructure,
+// refusing any array index more than maxIndex
+func (ss statements) toInterface(maxIndex int) (interface{}, error) {
 
 	// Get all the individually parsed statements
 	var parsed []interface{}
 	for _, s := range ss {
-		u, err := ungronTokens(s)
+		u, err := ungronTokens(s, maxIndex)
 
 		switch err.(type) {
 		case nil:
@@ -309,23 +920,42 @@ func (ss statements) toInterface() (interface{}, error) {
 
 // Less compares two statements for sort.Sort
 // Implements a natural sort to keep array indexes in order
+//
+// Statements are compared token by token, and the first tokens that
+// differ decide the order:
+//
+//   - a statement that's a prefix of another comes first,
+//     and so does an equals; i.e. parents before children
+//   - array indexes and number values compare numerically
+//   - anything else compares by its text
+//
+// Where those are equal (e.g. the numbers 1 and 1.0, or an index of
+// 1 and 01) the text and then the type of token decide, so the order
+// is total: any two statements that aren't identical have one order,
+// so sorted output is the same every time whatever the input order.
 func (ss statements) Less(a, b int) bool {
+	return lessStatements(ss[a], ss[b])
+}
 
-	// ss[a] and ss[b] are both slices of tokens. The first
+// lessStatements returns true if statement a comes before statement b
+// in the order described for statements.Less
+func lessStatements(a, b statement) bool {
+
+	// a and b are both slices of tokens. The first
 	// thing we need to do is find the first token (if any)
 	// that differs, then we can use that token to decide
-	// if ss[a] or ss[b] should come first in the sort.
+	// if a or b should come first in the sort.
 	diffIndex := -1
-	for i := range ss[a] {
+	for i := range a {
 
-		if len(ss[b]) < i+1 {
+		if len(b) < i+1 {
 			// b must be shorter than a, so it
 			// should come first
 			return false
 		}
 
 		// The tokens match, so just carry on
-		if ss[a][i] == ss[b][i] {
+		if a[i] == b[i] {
 			continue
 		}
 
@@ -334,15 +964,15 @@ func (ss statements) Less(a, b int) bool {
 		break
 	}
 
-	// If diffIndex is still -1 then the only difference must be
-	// that ss[b] is longer than ss[a], so ss[a] should come first
+	// If diffIndex is still -1 then either b is longer than
+	// a, so a should come first, or they're identical
 	if diffIndex == -1 {
-		return true
+		return len(a) < len(b)
 	}
 
 	// Get the tokens that differ
-	ta := ss[a][diffIndex]
-	tb := ss[b][diffIndex]
+	ta := a[diffIndex]
+	tb := b[diffIndex]
 
 	// An equals always comes first
 	if ta.typ == typEquals {
@@ -356,20 +986,221 @@ func (ss statements) Less(a, b int) bool {
 	if ta.typ == typNumericKey && tb.typ == typNumericKey {
 		ia, _ := strconv.Atoi(ta.text)
 		ib, _ := strconv.Atoi(tb.text)
-		return ia < ib
+		if ia != ib {
+			return ia < ib
+		}
+		return lessText(ta, tb)
 	}
 
 	// If neither token is a number, just do a string comparison
 	if ta.typ != typNumber || tb.typ != typNumber {
-		return ta.text < tb.text
+		return lessText(ta, tb)
 	}
 
 	// We have two numbers to compare so turn them into json.Number
 	// for comparison
 	na, _ := json.Number(ta.text).Float64()
 	nb, _ := json.Number(tb.text).Float64()
-	return na < nb
+	if na != nb {
+		return na < nb
+	}
+	return lessText(ta, tb)
+
+}
+
+// lessText compares two tokens by their text, and
+// then by their type if the text is the same
+func lessText(ta, tb token) bool {
+	if ta.text != tb.text {
+		return ta.text < tb.text
+	}
+	return ta.typ < tb.typ
+}
+
+// blank returns true if none of the statements are assignments;
+// i.e. they're all empty lines or comments
+func (ss statements) blank() bool {
+	for _, s := range ss {
+		if len(s) > 0 && s[0].typ != typIgnored {
+			return false
+		}
+	}
+	return true
+}
+
+// root returns the bare word that every statement starts with,
+// or an empty string if they don't all start with the same one.
+// Lines that aren't assignments or deletions, like a stray word,
+// are skipped when ungronning so they don't count either
+func (ss statements) root() string {
+	root := ""
+	for _, s := range ss {
+		if len(s) == 0 || s[0].typ == typIgnored {
+			continue
+		}
+		if s.deletion() {
+			s = s[1:]
+		} else if _, ok := s.value(); !ok {
+			continue
+		}
+		if len(s) == 0 || s[0].typ != typBare || (root != "" && s[0].text != root) {
+			return ""
+		}
+		root = s[0].text
+	}
+	return root
+}
+
+// indexes returns the set of array indexes that immediately
+// follow the provided prefix in any of the statements
+func (ss statements) indexes(prefix statement) map[int]bool {
+	out := make(map[int]bool)
+	for _, s := range ss {
+		if len(s) < len(prefix)+2 || s[len(prefix)+1].typ != typNumericKey {
+			continue
+		}
+		if !reflect.DeepEqual(s[:len(prefix)], prefix) {
+			continue
+		}
+		i, err := strconv.Atoi(s[len(prefix)+1].text)
+		if err != nil {
+			continue
+		}
+		out[i] = true
+	}
+	return out
+}
+
+// preorder sorts statements into a pre-order traversal of the tree
+// they describe: every container comes before its children, and all
+// of a container's descendants are contiguous. Siblings are ordered
+// by their key, regardless of whether it's bare or quoted, with array
+// indexes in numeric order.
+type preorder struct {
+	ss   statements
+	keys [][]pathKey
+}
+
+// a pathKey is a single object key or array index in a path
+type pathKey struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// newPreorder prepares a list of statements for pre-order sorting
+func newPreorder(ss statements) *preorder {
+	p := &preorder{ss: ss, keys: make([][]pathKey, len(ss))}
+	for i, s := range ss {
+		p.keys[i] = s.pathKeys()
+	}
+	return p
+}
+
+// pathKeys returns the object keys and array indexes in a
+// statement's path, not including the top-level bare word
+func (s statement) pathKeys() []pathKey {
+	var keys []pathKey
+	for j, t := range s.path() {
+		if j == 0 {
+			continue
+		}
+		switch t.typ {
+		case typBare, typQuotedKey:
+			keys = append(keys, pathKey{key: t.key()})
+		case typNumericKey:
+			n, _ := strconv.Atoi(t.text)
+			keys = append(keys, pathKey{index: n, isIndex: true})
+		}
+	}
+	return keys
+}
+
+// Len returns the number of statements for sort.Sort
+func (p *preorder) Len() int {
+	return len(p.ss)
+}
+
+// Swap swaps two statements for sort.Sort
+func (p *preorder) Swap(i, j int) {
+	p.ss[i], p.ss[j] = p.ss[j], p.ss[i]
+	p.keys[i], p.keys[j] = p.keys[j], p.keys[i]
+}
+
+// Less compares two statements for sort.Sort
+func (p *preorder) Less(a, b int) bool {
+	ka, kb := p.keys[a], p.keys[b]
+	for i := range ka {
+		if i >= len(kb) {
+			// b is an ancestor of a
+			return false
+		}
+		if ka[i] == kb[i] {
+			continue
+		}
+		if ka[i].isIndex && kb[i].isIndex {
+			return ka[i].index < kb[i].index
+		}
+		if ka[i].isIndex != kb[i].isIndex {
+			return ka[i].isIndex
+		}
+		return ka[i].key < kb[i].key
+	}
+
+	// Either a is an ancestor of b, or they have the same path; in
+	// which case the natural order of the statements decides
+	if len(ka) != len(kb) {
+		return len(ka) < len(kb)
+	}
+	return p.ss.Less(a, b)
+}
+
+// byValue sorts statements by their values rather than their paths,
+// so that equal and similar values are next to each other: nulls come
+// first, then booleans, numbers in numeric order, strings, arrays and
+// objects. The path decides the order of statements with equal values.
+type byValue struct {
+	statements
+}
+
+// Less compares two statements for sort.Sort
+func (v byValue) Less(a, b int) bool {
+	ta, _ := v.statements[a].value()
+	tb, _ := v.statements[b].value()
+	if ra, rb := valueRank(ta.typ), valueRank(tb.typ); ra != rb {
+		return ra < rb
+	}
+
+	if ta.typ == typNumber && tb.typ == typNumber {
+		na, _ := json.Number(ta.text).Float64()
+		nb, _ := json.Number(tb.text).Float64()
+		if na != nb {
+			return na < nb
+		}
+	}
+	if ta.text != tb.text {
+		return ta.text < tb.text
+	}
+	return v.statements.Less(a, b)
+}
 
+// valueRank returns where values of a type come
+// relative to values of other types when sorting by value
+func valueRank(typ tokenTyp) int {
+	switch typ {
+	case typNull:
+		return 0
+	case typFalse, typTrue:
+		return 1
+	case typNumber:
+		return 2
+	case typString:
+		return 3
+	case typEmptyArray, typInlineArray:
+		return 4
+	default:
+		return 5
+	}
 }
 
 // Contains searches the statements for a given statement
@@ -383,45 +1214,215 @@ func (ss statements) Contains(search statement) bool {
 	return false
 }
 
+// A decoder makes statements from a document in one of the input
+// formats, with the path of every statement starting with prefix
+type decoder func(r io.Reader, prefix statement, c *config) (statements, error)
+
+// decoderFor returns the decoder for the input format set in opts;
+// JSON unless it's one of the options for another format, like OptYAML
+func decoderFor(opts int) decoder {
+	switch {
+	case opts&OptAvro > 0:
+		return statementsFromAvro
+	case opts&OptBSON > 0:
+		return statementsFromBSON
+	case opts&OptMsgpack > 0:
+		return statementsFromMsgpack
+	case opts&OptXML > 0:
+		return statementsFromXML
+	case opts&OptYAML > 0:
+		return statementsFromYAML
+	case opts&OptTOML > 0:
+		return statementsFromTOML
+	default:
+		return statementsFromJSON
+	}
+}
+
 // statementsFromJSON takes an io.Reader containing JSON
 // and returns statements or an error on failure
-func statementsFromJSON(r io.Reader, prefix statement) (statements, error) {
-	var top interface{}
-	d := json.NewDecoder(r)
-	d.UseNumber()
-	err := d.Decode(&top)
+func statementsFromJSON(r io.Reader, prefix statement, c *config) (statements, error) {
+	top, err := decodeJSON(r, c.lenient, c.tokenDecoder(prefix))
 	if err != nil {
 		return nil, err
 	}
 	ss := make(statements, 0, 32)
-	ss.fill(prefix, top)
+	ss.fill(prefix, top, 0, c)
 	return ss, nil
 }
 
+// unwrapString returns the object or array that a string
+// contains as JSON; or the string itself if it doesn't
+func unwrapString(str string, td *tokenDecoder) interface{} {
+	trimmed := strings.TrimSpace(str)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return str
+	}
+	v, err := decodeJSON(strings.NewReader(trimmed), false, td)
+	if err != nil {
+		return str
+	}
+	return v
+}
+
+// decodeJSON decodes a single JSON value from r, with numbers decoded
+// as json.Number so no precision is lost. Unless lenient is true, it's
+// an error for anything but whitespace to follow the value; which is
+// usually a sign of a corrupted or concatenated input. If td isn't nil
+// it's used to decode the value rather than json.Decoder's Decode
+func decodeJSON(r io.Reader, lenient bool, td *tokenDecoder) (interface{}, error) {
+	var v interface{}
+	cr := &countingReader{r: r}
+	d := json.NewDecoder(cr)
+	d.UseNumber()
+	var err error
+	if td != nil {
+		v, err = td.decode(d)
+	} else {
+		err = d.Decode(&v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !lenient {
+		if err := expectEOF(d, cr); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// expectEOF returns an error if anything but whitespace follows
+// the last value read by d, which is reading from cr
+func expectEOF(d *json.Decoder, cr *countingReader) error {
+	buffered, err := ioutil.ReadAll(d.Buffered())
+	if err != nil {
+		return err
+	}
+	offset := cr.n - int64(len(buffered))
+
+	rest := bufio.NewReader(io.MultiReader(bytes.NewReader(buffered), cr))
+	for {
+		b, err := rest.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch b {
+		case ' ', '\t', '\n', '\r':
+			offset++
+		default:
+			return trailingDataError{offset}
+		}
+	}
+}
+
+// a trailingDataError is returned when there's
+// something other than whitespace after a value
+type trailingDataError struct {
+	offset int64
+}
+
+func (e trailingDataError) Error() string {
+	return fmt.Sprintf("trailing data after JSON value at offset %d", e.offset)
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
 // fill takes a prefix statement and some value and recursively fills
-// the statement list using that value
-func (ss *statements) fill(prefix statement, v interface{}) {
+// the statement list using that value. The depth is how many levels
+// below the top-level value (or each line's value, for GronStream) it is
+func (ss *statements) fill(prefix statement, v interface{}, depth int, c *config) {
+
+	// Strings can be unwrapped into the objects and arrays they contain
+	if str, ok := v.(string); ok && c.unwrapStrings {
+		v = unwrapString(str, c.tokenDecoder(prefix))
+	}
+
+	// Arrays can be treated as sets, in which case order doesn't matter
+	if vv, ok := v.([]interface{}); ok && c.arraysAsSets {
+		v = sortedByEncoding(vv)
+	}
+
+	// Arrays of scalars can be written as a single statement,
+	// unless only some of their elements are wanted
+	if c.inlineArrays && !c.selecting(prefix) && !c.tooDeep(depth) {
+		if t, ok := inlineArrayToken(prefix, v, c); ok {
+			ss.addWithValue(prefix, t)
+			return
+		}
+	}
+
+	// Leaf values can be replaced by any registered transforms
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+	default:
+		v = c.transform(prefix, v)
+	}
+	if v == nil && c.skipNulls && depth > 0 {
+		return
+	}
 
 	// Add a statement for the current prefix and value
 	ss.addWithValue(prefix, valueTokenFromInterface(v))
+	if c.tooDeep(depth) {
+		return
+	}
+
+	// Only the elements of an array can be selected, so
+	// there's nothing in any other value to write
+	if _, ok := v.([]interface{}); !ok && c.selecting(prefix) {
+		c.warnf("can't select indexes from %s, which isn't an array", prefix)
+		return
+	}
 
 	// Recurse into objects and arrays
 	switch vv := v.(type) {
 
 	case map[string]interface{}:
 		// It's an object
-		for k, sub := range vv {
-			if validIdentifier(k) {
-				ss.fill(prefix.withBare(k), sub)
-			} else {
-				ss.fill(prefix.withQuotedKey(k), sub)
+		if c.keysCase != "" {
+			keys, converted := c.convertKeys(prefix, vv)
+			for _, k := range keys {
+				ss.fill(prefix.withKey(converted[k]), vv[k], depth+1, c)
+			}
+			return
+		}
+		if keys, ok := c.keyOrder.keys(vv); ok {
+			for _, k := range keys {
+				ss.fill(prefix.withKey(k), vv[k], depth+1, c)
 			}
+			return
+		}
+		for k, sub := range vv {
+			ss.fill(prefix.withKey(k), sub, depth+1, c)
 		}
 
 	case []interface{}:
 		// It's an array
+		if c.selecting(prefix) {
+			for k, sub := range vv {
+				if k, ok := c.selected(k); ok {
+					ss.fill(prefix.withNumericKey(k), sub, depth+1, c)
+				}
+			}
+			c.warnUnselected(len(vv))
+			return
+		}
 		for k, sub := range vv {
-			ss.fill(prefix.withNumericKey(k), sub)
+			ss.fill(prefix.withNumericKey(k), sub, depth+1, c)
 		}
 	}
 
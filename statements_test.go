@@ -3,8 +3,10 @@ package gron
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 )
 
@@ -33,7 +35,7 @@ func TestStatementsSimple(t *testing.T) {
 		"": 2
 	}`)
 
-	ss, err := statementsFromJSON(bytes.NewReader(j), statement{{"json", typBare}})
+	ss, err := statementsFromJSON(bytes.NewReader(j), statement{{"json", typBare}}, &config{})
 
 	if err != nil {
 		t.Errorf("Want nil error from makeStatementsFromJSON() but got %s", err)
@@ -130,7 +132,7 @@ func BenchmarkFill(b *testing.B) {
 
 	for i := 0; i < b.N; i++ {
 		ss := make(statements, 0)
-		ss.fill(statement{{"json", typBare}}, top)
+		ss.fill(statement{{"json", typBare}}, top, 0, &config{})
 	}
 }
 
@@ -156,7 +158,7 @@ func TestUngronStatementsSimple(t *testing.T) {
 		},
 	}
 
-	have, err := in.toInterface()
+	have, err := in.toInterface(DefaultMaxArrayIndex)
 
 	if err != nil {
 		t.Fatalf("want nil error but have: %s", err)
@@ -179,7 +181,7 @@ func TestUngronStatementsInvalid(t *testing.T) {
 	}
 
 	for _, c := range cases {
-		_, err := c.toInterface()
+		_, err := c.toInterface(DefaultMaxArrayIndex)
 		if err == nil {
 			t.Errorf("want non-nil error; have nil")
 		}
@@ -202,3 +204,330 @@ func TestStatement(t *testing.T) {
 		t.Errorf("have: `%s` want: `%s`", have, want)
 	}
 }
+
+func TestStatementAppend(t *testing.T) {
+	root := Statement{}.Append(TokenBare, "json")
+
+	cases := []struct {
+		s    Statement
+		want string
+	}{
+		{root.Append(TokenEmptyObject, ""), `json = {};`},
+		{root.Append(TokenBare, "city").Append(TokenString, "Leeds"), `json.city = "Leeds";`},
+		{root.Append(TokenKey, "first name").Append(TokenString, "Tom \"T\""), `json["first name"] = "Tom \"T\"";`},
+		{root.Append(TokenKey, "id").Append(TokenNumber, "1e3"), `json.id = 1e3;`},
+		{root.Append(TokenIndex, "2").Append(TokenBool, "true"), `json[2] = true;`},
+		{root.Append(TokenKey, "tags").Append(TokenEmptyArray, ""), `json.tags = [];`},
+		{root.Append(TokenKey, "x").Append(TokenNull, ""), `json.x = null;`},
+	}
+
+	for _, c := range cases {
+		if have := c.s.Render(true); have != c.want {
+			t.Errorf("want %s; have %s", c.want, have)
+		}
+		if _, err := ParseStatement(c.s.String()); err != nil {
+			t.Errorf("want %s to parse; have %s", c.s, err)
+		}
+	}
+
+	// Parts that can't be appended are left out and reported by Err
+	bad := []struct {
+		s    Statement
+		want string
+	}{
+		{root.Append(TokenKey, "a").Append(TokenBool, "yes"), `json.a`},
+		{root.Append(TokenKey, "a").Append(TokenBool, "maybe").Append(TokenKey, "b"), `json.a`},
+		{root.Append(TokenKey, "a").Append(TokenNumber, "01"), `json.a`},
+		{root.Append(TokenKey, "a").Append(TokenNumber, "1").Append(TokenString, "x"), `json.a = 1;`},
+		{root.Append(TokenKey, "a").Append(TokenNumber, "1").Append(TokenKey, "b"), `json.a = 1;`},
+		{root.Append(TokenIndex, "-1"), `json`},
+		{root.Append(TokenIndex, "x"), `json`},
+		{Statement{}.Append(TokenKey, "a"), ``},
+	}
+	for _, c := range bad {
+		if c.s.Err() == nil {
+			t.Errorf("want an error from Err for %s; have nil", c.s)
+		}
+		if have := c.s.Render(true); have != c.want {
+			t.Errorf("want %s; have %s", c.want, have)
+		}
+		if _, err := StatementsToJSON([]Statement{c.s}); err == nil {
+			t.Errorf("want error from StatementsToJSON for %s; have nil", c.s)
+		}
+	}
+	if err := root.Append(TokenKey, "a").Append(TokenNumber, "1").Err(); err != nil {
+		t.Errorf("want nil error; have %s", err)
+	}
+
+	// Appending doesn't change the statement appended to
+	a := root.Append(TokenKey, "a")
+	a.Append(TokenKey, "b")
+	a.Append(TokenIndex, "0")
+	if have := a.Append(TokenNumber, "1").Render(true); have != "json.a = 1;" {
+		t.Errorf("want json.a = 1; have %s", have)
+	}
+
+	want := root.Append(TokenKey, "a").Append(TokenNumber, "1").tokens.colorString()
+	if have := root.Append(TokenKey, "a").Append(TokenNumber, "1").Render(false); have != want {
+		t.Errorf("want %q; have %q", want, have)
+	}
+}
+
+func TestInferValue(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{`true`, `true`},
+		{`false`, `false`},
+		{`null`, `null`},
+		{`42`, `42`},
+		{`-3`, `-3`},
+		{`1.5`, `1.5`},
+		{`2e10`, `2e10`},
+		{`007`, `"007"`},
+		{`1.`, `"1."`},
+		{`True`, `"True"`},
+		{`localhost`, `"localhost"`},
+		{`"quoted value"`, `"quoted value"`},
+		{`"01234"`, `"01234"`},
+		{`"1234"`, `"1234"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{``, `""`},
+	}
+
+	for _, c := range cases {
+		have := inferValue(c.in)
+		if have != c.want {
+			t.Errorf("want inferValue(%s) to be %s; have %s", c.in, c.want, have)
+		}
+	}
+}
+
+func TestStatementsPreorder(t *testing.T) {
+	in := statementsFromStringSlice([]string{
+		`json.b = 1;`,
+		`json["a b"][10] = 1;`,
+		`json = {};`,
+		`json["a b"] = [];`,
+		`json["a b"][2] = 1;`,
+		`json.a = {};`,
+		`json.a.z = 1;`,
+		`json["a-"] = 1;`,
+	})
+
+	lexical := make(statements, len(in))
+	copy(lexical, in)
+	sort.Sort(lexical)
+
+	preordered := make(statements, len(in))
+	copy(preordered, in)
+	sort.Sort(newPreorder(preordered))
+
+	wantLexical := statementsFromStringSlice([]string{
+		`json = {};`,
+		`json.a = {};`,
+		`json.a.z = 1;`,
+		`json.b = 1;`,
+		`json["a b"] = [];`,
+		`json["a b"][2] = 1;`,
+		`json["a b"][10] = 1;`,
+		`json["a-"] = 1;`,
+	})
+	wantPreorder := statementsFromStringSlice([]string{
+		`json = {};`,
+		`json.a = {};`,
+		`json.a.z = 1;`,
+		`json["a b"] = [];`,
+		`json["a b"][2] = 1;`,
+		`json["a b"][10] = 1;`,
+		`json["a-"] = 1;`,
+		`json.b = 1;`,
+	})
+
+	if !reflect.DeepEqual(lexical, wantLexical) {
+		t.Errorf("want lexical order %s; have %s", wantLexical, lexical)
+	}
+	if !reflect.DeepEqual(preordered, wantPreorder) {
+		t.Errorf("want pre-order %s; have %s", wantPreorder, preordered)
+	}
+}
+
+func TestGronPreorderIndexes(t *testing.T) {
+	elems := make([]string, 12)
+	for i := range elems {
+		elems[i] = fmt.Sprintf(`{"z": %d, "a": [%d]}`, i, i)
+	}
+	in := `{"b": [` + strings.Join(elems, ",") + `], "a b": {"y": 1, "x": 2}, "a": 3}`
+
+	out := &bytes.Buffer{}
+	code, err := Gron(strings.NewReader(in), out, OptMonochrome|OptPreorder)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+
+	// Keys are in order at each level, and indexes in numeric order
+	want := []string{"json = {};", "json.a = 3;", `json["a b"] = {};`, `json["a b"].x = 2;`, `json["a b"].y = 1;`, "json.b = [];"}
+	for i := range elems {
+		want = append(want,
+			fmt.Sprintf("json.b[%d] = {};", i),
+			fmt.Sprintf("json.b[%d].a = [];", i),
+			fmt.Sprintf("json.b[%d].a[0] = %d;", i, i),
+			fmt.Sprintf("json.b[%d].z = %d;", i, i),
+		)
+	}
+	have := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("want %q; have %q", want, have)
+	}
+}
+
+func TestStatementsByValue(t *testing.T) {
+	ss := statementsFromStringSlice([]string{
+		`json = {};`,
+		`json.a = "b";`,
+		`json.b = 10;`,
+		`json.c = 9.5;`,
+		`json.d = [];`,
+		`json.e = true;`,
+		`json.f = null;`,
+		`json.g = "a";`,
+		`json.h = false;`,
+		`json[10] = 2;`,
+		`json[2] = 2;`,
+		`json.i = 1e1;`,
+	})
+	sort.Sort(byValue{ss})
+
+	want := statementsFromStringSlice([]string{
+		`json.f = null;`,
+		`json.h = false;`,
+		`json.e = true;`,
+		`json[2] = 2;`,
+		`json[10] = 2;`,
+		`json.c = 9.5;`,
+		`json.b = 10;`,
+		`json.i = 1e1;`,
+		`json.g = "a";`,
+		`json.a = "b";`,
+		`json.d = [];`,
+		`json = {};`,
+	})
+	if !reflect.DeepEqual(ss, want) {
+		t.Errorf("want %s; have %s", want, ss)
+	}
+}
+
+func TestStatementsToJSON(t *testing.T) {
+	parse := func(strs ...string) []Statement {
+		ss := make([]Statement, 0, len(strs))
+		for _, str := range strs {
+			s, err := ParseStatement(str)
+			if err != nil {
+				t.Fatalf("want nil error from ParseStatement; have %s", err)
+			}
+			ss = append(ss, s)
+		}
+		return ss
+	}
+
+	ss := parse(
+		`json.users[1].name = "Bob";`,
+		`json = {};`,
+		`json.users = [];`,
+		`json.users[0] = {};`,
+		`json.users[0]["full name"] = "Tom Hudson";`,
+		`json.users[1] = {};`,
+		`json.total = 2;`,
+	)
+	have, err := StatementsToJSON(ss)
+	if err != nil {
+		t.Fatalf("want nil error; have %s", err)
+	}
+	want := `{"total":2,"users":[{"full name":"Tom Hudson"},{"name":"Bob"}]}`
+	if string(have) != want {
+		t.Errorf("want %s; have %s", want, have)
+	}
+
+	// Statements from the gron action round-trip
+	var collected []Statement
+	in := `{"id":1,"tags":["a","<b>"]}`
+	Gron(strings.NewReader(in), nil, OptMonochrome, WithStatementSink(func(s Statement) {
+		collected = append(collected, s)
+	}))
+	have, err = StatementsToJSON(collected)
+	if err != nil {
+		t.Fatalf("want nil error; have %s", err)
+	}
+	if string(have) != in {
+		t.Errorf("want %s; have %s", in, have)
+	}
+
+	// Conflicting, invalid and missing statements are errors
+	errCases := [][]Statement{
+		parse(`json.a = {};`, `json.a = "str";`),
+		{Statement{}},
+		{},
+	}
+	for _, c := range errCases {
+		if _, err := StatementsToJSON(c); err == nil {
+			t.Errorf("want non-nil error for %v", c)
+		}
+	}
+
+	for _, str := range []string{`json.a = ;`, `json.a`, `= 1;`, `-- separator`, ``} {
+		if _, err := ParseStatement(str); err == nil {
+			t.Errorf("want non-nil error from ParseStatement for %q", str)
+		}
+	}
+}
+
+func TestStatementsSortingTotal(t *testing.T) {
+	want := statementsFromStringSlice([]string{
+		`json = [];`,
+		`json = {};`,
+		`json.a = "1";`,
+		`json.a = 1;`,
+		`json.a = 1.0;`,
+		`json.a = 1e0;`,
+		`json.a = 2;`,
+		`json.a = true;`,
+		`json["a"] = 1;`,
+		`json[01] = true;`,
+		`json[1] = true;`,
+	})
+
+	// Every rotation of the statements, forwards and backwards, has
+	// comparator ties in different places but should sort into exactly
+	// the same order; with both the natural sort and pre-order
+	var wantPreorder statements
+	for i := range want {
+		for _, reverse := range []bool{false, true} {
+			in := make(statements, 0, len(want))
+			in = append(in, want[i:]...)
+			in = append(in, want[:i]...)
+			if reverse {
+				for l, r := 0, len(in)-1; l < r; l, r = l+1, r-1 {
+					in[l], in[r] = in[r], in[l]
+				}
+			}
+
+			natural := make(statements, len(in))
+			copy(natural, in)
+			sort.Sort(natural)
+			if !reflect.DeepEqual(natural, want) {
+				t.Errorf("want %s; have %s", want, natural)
+			}
+
+			preordered := make(statements, len(in))
+			copy(preordered, in)
+			sort.Sort(newPreorder(preordered))
+			if wantPreorder == nil {
+				wantPreorder = preordered
+			}
+			if !reflect.DeepEqual(preordered, wantPreorder) {
+				t.Errorf("want pre-order %s; have %s", wantPreorder, preordered)
+			}
+		}
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"unicode"
 )
 
@@ -42,6 +45,10 @@ const (
 	typNull        // null
 	typEmptyArray  // []
 	typEmptyObject // {}
+	typInlineArray // ["foo", 4]
+
+	// Keyword types
+	typDelete // delete
 
 	// Ignored token
 	typIgnored
@@ -54,25 +61,34 @@ const (
 type sprintFn func(...interface{}) string
 
 // mapping of token types to the appropriate color sprintFn
-var sprintFns = map[tokenTyp]sprintFn{
-	typBare:        BareColor.SprintFunc(),
-	typNumericKey:  NumColor.SprintFunc(),
-	typQuotedKey:   StrColor.SprintFunc(),
-	typLBrace:      BraceColor.SprintFunc(),
-	typRBrace:      BraceColor.SprintFunc(),
-	typString:      StrColor.SprintFunc(),
-	typNumber:      NumColor.SprintFunc(),
-	typTrue:        BoolColor.SprintFunc(),
-	typFalse:       BoolColor.SprintFunc(),
-	typNull:        BoolColor.SprintFunc(),
-	typEmptyArray:  BraceColor.SprintFunc(),
-	typEmptyObject: BraceColor.SprintFunc(),
+var sprintFns = colorSprintFns()
+
+// colorSprintFns returns the mapping of token types
+// to sprintFns for the current output colors
+func colorSprintFns() map[tokenTyp]sprintFn {
+	return map[tokenTyp]sprintFn{
+		typBare:        BareColor.SprintFunc(),
+		typNumericKey:  NumColor.SprintFunc(),
+		typQuotedKey:   StrColor.SprintFunc(),
+		typLBrace:      BraceColor.SprintFunc(),
+		typRBrace:      BraceColor.SprintFunc(),
+		typString:      StrColor.SprintFunc(),
+		typNumber:      NumColor.SprintFunc(),
+		typTrue:        BoolColor.SprintFunc(),
+		typFalse:       BoolColor.SprintFunc(),
+		typNull:        NullColor.SprintFunc(),
+		typEmptyArray:  BraceColor.SprintFunc(),
+		typEmptyObject: BraceColor.SprintFunc(),
+		typInlineArray: BraceColor.SprintFunc(),
+		typEquals:      PunctColor.SprintFunc(),
+		typSemi:        PunctColor.SprintFunc(),
+	}
 }
 
 // isValue returns true if the token is a valid value type
 func (t token) isValue() bool {
 	switch t.typ {
-	case typString, typNumber, typTrue, typFalse, typNull, typEmptyArray, typEmptyObject:
+	case typString, typNumber, typTrue, typFalse, typNull, typEmptyArray, typEmptyObject, typInlineArray:
 		return true
 	default:
 		return false
@@ -89,11 +105,27 @@ func (t token) isPunct() bool {
 	}
 }
 
+// key returns the object key or array index that a key token
+// represents; i.e. the token text, unquoted for quoted keys
+func (t token) key() string {
+	if t.typ != typQuotedKey {
+		return t.text
+	}
+	var k string
+	if err := json.Unmarshal([]byte(t.text), &k); err != nil {
+		return t.text
+	}
+	return k
+}
+
 // format returns the formatted version of the token text
 func (t token) format() string {
 	if t.typ == typEquals {
 		return " " + t.text + " "
 	}
+	if t.typ == typDelete {
+		return t.text + " "
+	}
 	return t.text
 }
 
@@ -103,6 +135,9 @@ func (t token) formatColor() string {
 	if t.typ == typEquals {
 		text = " " + text + " "
 	}
+	if t.typ == typDelete {
+		text = text + " "
+	}
 	fn, ok := sprintFns[t.typ]
 	if ok {
 		return fn(text)
@@ -112,7 +147,9 @@ func (t token) formatColor() string {
 }
 
 // valueTokenFromInterface takes any valid value and
-// returns a value token to represent it
+// returns a value token to represent it. Strings are always
+// quoted, even if they look like numbers (e.g. "01234"), and
+// nothing that works on number tokens ever touches them
 func valueTokenFromInterface(v interface{}) token {
 	switch vv := v.(type) {
 
@@ -201,3 +238,42 @@ func quoteString(s string) string {
 	return out.String()
 
 }
+
+// normalizeNumber returns a canonical form of a JSON number so
+// that equal numbers written differently (e.g. 1.0, 1e0 and 1)
+// are rendered the same way. Integers are kept verbatim so that
+// no precision is lost, apart from negative zero becoming zero.
+// Other numbers use the shortest representation that parses back
+// to the same float64, formatted as encoding/json would format it.
+// Anything that isn't a finite number is returned unchanged.
+func normalizeNumber(n string) string {
+	if !strings.ContainsAny(n, ".eE") {
+		if strings.Trim(n, "-0") == "" && n != "" {
+			return "0"
+		}
+		return n
+	}
+
+	f, err := strconv.ParseFloat(n, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return n
+	}
+
+	abs := math.Abs(f)
+	if abs == 0 {
+		return "0"
+	}
+	if abs >= 1e-6 && abs < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	// Clean up exponents like e-07 into e-7
+	out := strconv.FormatFloat(f, 'e', -1, 64)
+	if i := strings.Index(out, "e-0"); i != -1 {
+		out = out[:i+2] + out[i+3:]
+	}
+	if i := strings.Index(out, "e+0"); i != -1 {
+		out = out[:i+2] + out[i+3:]
+	}
+	return out
+}
@@ -50,3 +50,31 @@ func BenchmarkValueTokenFromInterface(b *testing.B) {
 		}
 	}
 }
+
+func TestNormalizeNumber(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"1", "1"},
+		{"-0", "0"},
+		{"0", "0"},
+		{"10000000000000001", "10000000000000001"},
+		{"1.0", "1"},
+		{"1e2", "100"},
+		{"1E2", "100"},
+		{"1.50", "1.5"},
+		{"-0.0", "0"},
+		{"0.000001", "0.000001"},
+		{"1e-7", "1e-7"},
+		{"1.5e300", "1.5e+300"},
+		{"1e400", "1e400"},
+	}
+
+	for _, c := range cases {
+		have := normalizeNumber(c.in)
+		if have != c.want {
+			t.Errorf("want normalizeNumber(%s) to be %s; have %s", c.in, c.want, have)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package gron
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// statementsFromTOML takes an io.Reader containing TOML and returns
+// statements or an error on failure. The document is a table, so the
+// top-level value is always an object
+func statementsFromTOML(r io.Reader, prefix statement, c *config) (statements, error) {
+	var doc map[string]interface{}
+	if _, err := toml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	v, err := tomlToJSON(doc, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	ss := make(statements, 0, 32)
+	ss.fill(prefix, v, 0, c)
+	return ss, nil
+}
+
+// maxTOMLDepth limits how deeply values can be nested, so that
+// filling in statements for them can't run out of stack
+const maxTOMLDepth = 10000
+
+// tomlToJSON converts a value decoded from TOML into the types used for
+// values decoded from JSON. Tables are objects and arrays of tables are
+// arrays of objects. Datetimes are written as RFC 3339 strings; local
+// datetimes, dates and times without the parts they don't have. Numbers
+// that JSON can't represent (infinities and NaN) are an error, as are
+// values nested more than maxTOMLDepth deep. depth is how deeply v is
+// nested, counting the document as 1
+func tomlToJSON(v interface{}, depth int) (interface{}, error) {
+	if depth > maxTOMLDepth {
+		return nil, fmt.Errorf("TOML values are nested more than %d deep", maxTOMLDepth)
+	}
+
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, sub := range vv {
+			conv, err := tomlToJSON(sub, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			vv[k] = conv
+		}
+		return vv, nil
+
+	case []map[string]interface{}:
+		out := make([]interface{}, len(vv))
+		for i, sub := range vv {
+			conv, err := tomlToJSON(sub, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = conv
+		}
+		return out, nil
+
+	case []interface{}:
+		for i, sub := range vv {
+			conv, err := tomlToJSON(sub, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			vv[i] = conv
+		}
+		return vv, nil
+
+	case int64:
+		return json.Number(strconv.FormatInt(vv, 10)), nil
+	case float64:
+		if math.IsInf(vv, 0) || math.IsNaN(vv) {
+			return nil, fmt.Errorf("TOML number %v can't be represented in JSON", vv)
+		}
+		return json.Number(strconv.FormatFloat(vv, 'g', -1, 64)), nil
+
+	case time.Time:
+		// The decoder marks local datetimes with these locations
+		switch vv.Location().String() {
+		case "datetime-local":
+			return vv.Format("2006-01-02T15:04:05.999999999"), nil
+		case "date-local":
+			return vv.Format("2006-01-02"), nil
+		case "time-local":
+			return vv.Format("15:04:05.999999999"), nil
+		}
+		return vv.Format(time.RFC3339Nano), nil
+
+	case string, bool:
+		return vv, nil
+
+	default:
+		return fmt.Sprint(vv), nil
+	}
+}
@@ -0,0 +1,140 @@
+package gron
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGronTOML(t *testing.T) {
+	in := `title = "config"
+ratio = 0.5
+
+[owner]
+name = "Tom"
+dob = 1979-05-27T07:32:00-08:00
+local = 1979-05-27T07:32:00
+day = 1979-05-27
+at = 07:32:00
+
+[[servers]]
+host = "a"
+ports = [80, 443]
+
+[[servers]]
+host = "b"
+`
+	want := `json = {};
+json.owner = {};
+json.owner.at = "07:32:00";
+json.owner.day = "1979-05-27";
+json.owner.dob = "1979-05-27T07:32:00-08:00";
+json.owner.local = "1979-05-27T07:32:00";
+json.owner.name = "Tom";
+json.ratio = 0.5;
+json.servers = [];
+json.servers[0] = {};
+json.servers[0].host = "a";
+json.servers[0].ports = [];
+json.servers[0].ports[0] = 80;
+json.servers[0].ports[1] = 443;
+json.servers[1] = {};
+json.servers[1].host = "b";
+json.title = "config";
+`
+
+	out := &bytes.Buffer{}
+	code, err := Gron(strings.NewReader(in), out, OptMonochrome|OptTOML)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	if out.String() != want {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", out.String())
+		t.Errorf("TOML output does not match")
+	}
+}
+
+func TestGronTOMLTables(t *testing.T) {
+	// Like a Cargo.toml: inline tables, arrays of inline tables,
+	// quoted table names and arrays of tables within arrays of tables
+	in := `points = [{ x = 1 }, { x = 2 }]
+
+[package]
+name = "gron"
+version = "0.1.0"
+edition = "2021"
+
+[dependencies]
+serde = { version = "1.0", features = ["derive"] }
+log = "0.4"
+
+[target.'cfg(unix)'.dependencies]
+libc = "0.2"
+
+[[bench]]
+name = "a"
+
+[[bench.cases]]
+n = 1
+
+[[bench.cases]]
+n = 2
+`
+	want := `json = {};
+json.bench = [];
+json.bench[0] = {};
+json.bench[0].cases = [];
+json.bench[0].cases[0] = {};
+json.bench[0].cases[0].n = 1;
+json.bench[0].cases[1] = {};
+json.bench[0].cases[1].n = 2;
+json.bench[0].name = "a";
+json.dependencies = {};
+json.dependencies.log = "0.4";
+json.dependencies.serde = {};
+json.dependencies.serde.features = [];
+json.dependencies.serde.features[0] = "derive";
+json.dependencies.serde.version = "1.0";
+json.package = {};
+json.package.edition = "2021";
+json.package.name = "gron";
+json.package.version = "0.1.0";
+json.points = [];
+json.points[0] = {};
+json.points[0].x = 1;
+json.points[1] = {};
+json.points[1].x = 2;
+json.target = {};
+json.target["cfg(unix)"] = {};
+json.target["cfg(unix)"].dependencies = {};
+json.target["cfg(unix)"].dependencies.libc = "0.2";
+`
+
+	out := &bytes.Buffer{}
+	code, err := Gron(strings.NewReader(in), out, OptMonochrome|OptTOML)
+	if code != ExitOK || err != nil {
+		t.Fatalf("want ExitOK and nil error; have %d and %v", code, err)
+	}
+	if out.String() != want {
+		t.Logf("want: %s", want)
+		t.Logf("have: %s", out.String())
+		t.Errorf("TOML output does not match")
+	}
+}
+
+func TestGronTOMLInvalid(t *testing.T) {
+	cases := []string{
+		"a = \n",
+		"a = 1\na = 2\n",
+		"a = inf\n",
+		"a = " + strings.Repeat("[", 20000) + strings.Repeat("]", 20000) + "\n",
+	}
+
+	for _, c := range cases {
+		code, err := Gron(strings.NewReader(c), &bytes.Buffer{}, OptMonochrome|OptTOML)
+		if code != ExitFormStatements || err == nil {
+			t.Errorf("want ExitFormStatements and an error for %q; have %d and %v", c, code, err)
+		}
+	}
+}
@@ -1,13 +1,15 @@
 // Ungronning is the reverse of gronning: turn statements
 // back into JSON. The expected input grammar is:
 //
-//   Input ::= '--'* Statement (Statement | '--')*
+//   Input ::= '--'* (Statement | Deletion) (Statement | Deletion | '--')*
 //   Statement ::= Path Space* "=" Space* Value ";" "\n"
+//   Deletion ::= "delete" Space+ Path ";" "\n"
 //   Path ::= (BareWord) ("." BareWord | ("[" Key "]"))*
 //   Value ::= String | Number | "true" | "false" | "null" | "[]" | "{}"
 //   BareWord ::= (UnicodeLu | UnicodeLl | UnicodeLm | UnicodeLo | UnicodeNl | '$' | '_') (UnicodeLu | UnicodeLl | UnicodeLm | UnicodeLo | UnicodeNl | UnicodeMn | UnicodeMc | UnicodeNd | UnicodePc | '$' | '_')*
-//   Key ::= [0-9]+ | String
+//   Key ::= [0-9]+ | String | SingleQuotedString
 //   String ::= '"' (UnescapedRune | ("\" (["\/bfnrt] | ('u' Hex))))* '"'
+//   SingleQuotedString ::= "'" ([^#x0-#x1f'\] | ("\" (['\/bfnrt] | ('u' Hex))))* "'"
 //   UnescapedRune ::= [^#x0-#x1f"\]
 
 package gron
@@ -182,6 +184,30 @@ func (l *lexer) acceptUntilUnescaped(delims string) {
 	}
 }
 
+// acceptInlineArray accepts runes up to and including the
+// closing bracket of an array, ignoring any brackets that
+// are part of strings within it
+func (l *lexer) acceptInlineArray() {
+	inString := false
+	inEscape := false
+	for {
+		r := l.next()
+		if l.cur == utf8.RuneError {
+			return
+		}
+		switch {
+		case inEscape:
+			inEscape = false
+		case inString && r == '\\':
+			inEscape = true
+		case r == '"':
+			inString = !inString
+		case r == ']' && !inString:
+			return
+		}
+	}
+}
+
 // a lexFn accepts a lexer, performs some action on it and
 // then returns an appropriate lexFn for the next stage
 type lexFn func(*lexer) lexFn
@@ -192,6 +218,12 @@ func lexStatement(l *lexer) lexFn {
 	r := l.peek()
 
 	switch {
+	case len(l.tokens) == 0 && atDelete(l.text):
+		return lexDelete
+	case r == ';' && len(l.tokens) > 0 && l.tokens[0].typ == typDelete:
+		l.accept(";")
+		l.emit(typSemi)
+		return nil
 	case r == '.' || validFirstRune(r):
 		return lexBareWord
 	case r == '[':
@@ -212,6 +244,27 @@ func lexStatement(l *lexer) lexFn {
 
 }
 
+// atDelete returns true if text starts with the delete keyword
+// followed by a path, rather than with a bare word like 'deleted'
+// or a top-level bare word called delete
+func atDelete(text string) bool {
+	if !strings.HasPrefix(text, "delete ") {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(strings.TrimLeft(text[len("delete"):], " "))
+	return validFirstRune(r)
+}
+
+// lexDelete lexes the delete keyword at the start of a
+// deletion; e.g. the 'delete' in 'delete json.foo;'
+func lexDelete(l *lexer) lexFn {
+	l.pos += len("delete")
+	l.emit(typDelete)
+	l.acceptRun(" ")
+	l.ignore()
+	return lexStatement
+}
+
 // lexBareWord lexes for bare identifiers.
 // E.g: the 'foo' in 'foo.bar' or 'foo[0]' is a bare identifier
 func lexBareWord(l *lexer) lexFn {
@@ -239,6 +292,8 @@ func lexBraces(l *lexer) lexFn {
 		return lexNumericKey
 	case l.peek() == '"':
 		return lexQuotedKey
+	case l.peek() == '\'':
+		return lexSingleQuotedKey
 	default:
 		l.emit(typError)
 		return nil
@@ -284,6 +339,29 @@ func lexQuotedKey(l *lexer) lexFn {
 	return lexStatement
 }
 
+// lexSingleQuotedKey lexes keys quoted with single quotes between
+// square braces, as in JSONPath, into the usual quoted key tokens
+func lexSingleQuotedKey(l *lexer) lexFn {
+	l.accept("'")
+	l.acceptUntilUnescaped("'")
+	if !l.accept("'") {
+		l.emit(typError)
+		return nil
+	}
+	l.emit(typQuotedKey)
+	k := &l.tokens[len(l.tokens)-1]
+	k.text = jsonPathUnquote(k.text)
+
+	if l.accept("]") {
+		l.emit(typRBrace)
+	} else {
+		l.emit(typError)
+		return nil
+	}
+	l.ignore()
+	return lexStatement
+}
+
 // lexValue lexes a value at the end of a statement
 func lexValue(l *lexer) lexFn {
 	l.acceptRun(" ")
@@ -317,8 +395,12 @@ func lexValue(l *lexer) lexFn {
 		l.emit(typNull)
 
 	case l.accept("["):
-		l.accept("]")
-		l.emit(typEmptyArray)
+		if l.accept("]") {
+			l.emit(typEmptyArray)
+			break
+		}
+		l.acceptInlineArray()
+		l.emit(typInlineArray)
 
 	case l.accept("{"):
 		l.accept("}")
@@ -352,8 +434,9 @@ func lexIgnore(l *lexer) lexFn {
 	return nil
 }
 
-// ungronTokens turns a slice of tokens into an actual datastructure
-func ungronTokens(ts []token) (interface{}, error) {
+// ungronTokens turns a slice of tokens into an actual datastructure;
+// refusing any array index more than maxIndex
+func ungronTokens(ts []token, maxIndex int) (interface{}, error) {
 	if len(ts) == 0 {
 		return nil, errRecoverable{"empty input"}
 	}
@@ -377,7 +460,7 @@ func ungronTokens(ts []token) (interface{}, error) {
 	switch {
 	case t.isPunct():
 		// Skip the token
-		val, err := ungronTokens(ts[1:])
+		val, err := ungronTokens(ts[1:], maxIndex)
 		if err != nil {
 			return nil, err
 		}
@@ -394,7 +477,7 @@ func ungronTokens(ts []token) (interface{}, error) {
 		return val, nil
 
 	case t.typ == typBare:
-		val, err := ungronTokens(ts[1:])
+		val, err := ungronTokens(ts[1:], maxIndex)
 		if err != nil {
 			return nil, err
 		}
@@ -403,7 +486,7 @@ func ungronTokens(ts []token) (interface{}, error) {
 		return out, nil
 
 	case t.typ == typQuotedKey:
-		val, err := ungronTokens(ts[1:])
+		val, err := ungronTokens(ts[1:], maxIndex)
 		if err != nil {
 			return nil, err
 		}
@@ -422,8 +505,11 @@ func ungronTokens(ts []token) (interface{}, error) {
 		if err != nil {
 			return nil, fmt.Errorf("invalid integer key `%s`", t.text)
 		}
+		if err := checkIndex(key, maxIndex); err != nil {
+			return nil, err
+		}
 
-		val, err := ungronTokens(ts[1:])
+		val, err := ungronTokens(ts[1:], maxIndex)
 		if err != nil {
 			return nil, err
 		}
@@ -438,6 +524,15 @@ func ungronTokens(ts []token) (interface{}, error) {
 	}
 }
 
+// checkIndex returns an error if an array index is more than maxIndex,
+// as the array would need space for every index up to it
+func checkIndex(key, maxIndex int) error {
+	if key > maxIndex {
+		return fmt.Errorf("array index %d is more than the maximum of %d", key, maxIndex)
+	}
+	return nil
+}
+
 // recursiveMerge merges maps and slices, or returns b for scalars
 func recursiveMerge(a, b interface{}) (interface{}, error) {
 	switch a.(type) {
@@ -456,7 +551,7 @@ func recursiveMerge(a, b interface{}) (interface{}, error) {
 		}
 		return recursiveSliceMerge(a.([]interface{}), bSlice)
 
-	case string, int, float64, bool, nil:
+	case string, int, float64, json.Number, bool, nil:
 		// Can't merge them, second one wins
 		return b, nil
 
@@ -486,30 +581,85 @@ func recursiveMapMerge(a, b map[string]interface{}) (map[string]interface{}, err
 	return a, nil
 }
 
-// recursiveSliceMerge recursively merged []interface{} values
+// recursiveSliceMerge recursively merges []interface{} values. The
+// values from b are merged into a, which is only grown if b is longer,
+// so that merging the statements for a long array one at a time
+// doesn't copy the whole array for every one of them
 func recursiveSliceMerge(a, b []interface{}) ([]interface{}, error) {
-	// We need a new slice with the capacity of whichever
-	// slive is biggest
-	outLen := len(a)
-	if len(b) > outLen {
-		outLen = len(b)
+	// Statements can come in any order, so b may be the longer one;
+	// e.g. json[0] = 1; followed by json[2] = 3;
+	for len(a) < len(b) {
+		a = append(a, nil)
 	}
-	out := make([]interface{}, outLen)
-
-	// Copy the values from 'a' into the output slice
-	copy(out, a)
 
-	// Add the values from 'b'; merging existing keys
+	// Add the values from 'b'; merging existing keys. A nil in b is
+	// a gap for an index that the statement wasn't about, so it
+	// leaves whatever is already there
 	for k, v := range b {
-		if out[k] == nil {
-			out[k] = v
+		if a[k] == nil {
+			a[k] = v
 		} else if v != nil {
-			merged, err := recursiveMerge(out[k], b[k])
+			merged, err := recursiveMerge(a[k], v)
 			if err != nil {
 				return nil, err
 			}
-			out[k] = merged
+			a[k] = merged
 		}
 	}
-	return out, nil
+	return a, nil
+}
+
+// conflict returns an error for the first pair of statements that
+// assign different values to the same path. Assigning to a member of
+// an object or array counts as assigning {} or [] to the object or
+// array, so that json.a = 1; conflicts with json.a.b = 2; Numbers are
+// compared by value, so json.a = 1; doesn't conflict with json.a = 1.0;
+func (ss statements) conflict() error {
+	values := make(map[string]token)
+	check := func(path statement, v token) error {
+		p := path.String()
+		was, exists := values[p]
+		if exists && !sameValue(was, v) {
+			return fmt.Errorf("conflicting values for %s: %s and %s", p, was.text, v.text)
+		}
+		values[p] = v
+		return nil
+	}
+
+	for _, s := range ss {
+		if !s.valid() {
+			continue
+		}
+
+		// Paths are rebuilt key by key so that json["a"] and json.a match
+		path := statement{s[0]}
+		for _, k := range s.pathKeys() {
+			if k.isIndex {
+				if err := check(path, token{"[]", typEmptyArray}); err != nil {
+					return err
+				}
+				path = path.withNumericKey(k.index)
+				continue
+			}
+			if err := check(path, token{"{}", typEmptyObject}); err != nil {
+				return err
+			}
+			path = path.withKey(k.key)
+		}
+
+		v, _ := s.value()
+		if err := check(path, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameValue returns true if two value tokens are for the same value;
+// numbers are the same if they're equal, however they're written
+func sameValue(a, b token) bool {
+	if a.typ == typNumber && b.typ == typNumber {
+		return normalizeNumber(a.text) == normalizeNumber(b.text)
+	}
+	return a.text == b.text
 }
@@ -205,7 +205,7 @@ func TestUngronTokensSimple(t *testing.T) {
 
 	l := newLexer(in)
 	tokens := l.lex()
-	have, err := ungronTokens(tokens)
+	have, err := ungronTokens(tokens, DefaultMaxArrayIndex)
 
 	if err != nil {
 		t.Fatalf("failed to ungron statement: %s", err)
@@ -232,7 +232,7 @@ func TestUngronTokensInvalid(t *testing.T) {
 	}
 
 	for _, c := range cases {
-		_, err := ungronTokens(c.in)
+		_, err := ungronTokens(c.in, DefaultMaxArrayIndex)
 		if err == nil {
 			t.Errorf("want non-nil error for %#v; have nil", c.in)
 		}
@@ -290,3 +290,35 @@ func TestMerge(t *testing.T) {
 	}
 
 }
+
+func TestStatementsConflict(t *testing.T) {
+	tests := []struct {
+		in   []string
+		want string
+	}{
+		{[]string{`json = {};`, `json.a = {};`, `json.a.b = 1;`, `json.a = {};`}, ""},
+		{[]string{`json.a = 1;`, `json["a"] = 1;`}, ""},
+		{[]string{`json.a = 1;`, `json.a = 1.0;`, `json.a = 1e0;`}, ""},
+		{[]string{`json.a = 1;`, `json.a = "1";`}, `conflicting values for json.a: 1 and "1"`},
+		{[]string{`json.a = 1.0;`, `json.a = 1.5;`}, "conflicting values for json.a: 1.0 and 1.5"},
+		{[]string{`json.a = 1;`, `json.a = 2;`}, "conflicting values for json.a: 1 and 2"},
+		{[]string{`json.a = "x";`, `json["a"] = "y";`}, `conflicting values for json.a: "x" and "y"`},
+		{[]string{`json.a = 1;`, `json.a.b = 2;`}, "conflicting values for json.a: 1 and {}"},
+		{[]string{`json.a[0] = 1;`, `json.a = {};`}, "conflicting values for json.a: [] and {}"},
+	}
+
+	for _, test := range tests {
+		var ss statements
+		for _, s := range test.in {
+			ss.add(statementFromString(s))
+		}
+		err := ss.conflict()
+		have := ""
+		if err != nil {
+			have = err.Error()
+		}
+		if have != test.want {
+			t.Errorf("want error %q for %v; have %q", test.want, test.in, have)
+		}
+	}
+}
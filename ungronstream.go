@@ -0,0 +1,234 @@
+package gron
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// UngronStream is like Ungron, but when the top-level value is an array
+// (e.g. the output of GronStream) it writes each element of the array as
+// soon as the statements for the next element begin, rather than holding
+// the whole document in memory. That needs the statements for each
+// element to be together and in order, as gron writes them.
+//
+// Input that doesn't start with a statement like json = []; is ungronned
+// as Ungron would, as is input where the statements for an element come
+// after those for a later one; unless some of the output has already been
+// written, in which case that's an error. WithBase, WithSplitOn,
+// WithNamespace, OptFromCSV, OptFromForm and OptSplitKeys always use Ungron.
+func UngronStream(r io.Reader, w io.Writer, opts int, options ...Option) (code int, err error) {
+	c := newConfig(options)
+	if c.base != nil || c.baseErr != nil || c.splitOn != nil || len(c.namespace) > 0 || opts&(OptFromCSV|OptFromForm|OptSplitKeys) > 0 {
+		return Ungron(r, w, opts, options...)
+	}
+	c.setOpts(opts)
+	r = c.limitInput(r)
+	defer c.checkInputSize(&code, &err)
+	if r, code, err = checkEmpty(r); err != nil {
+		return code, err
+	}
+	scanner := c.newScanner(r)
+	maker := ungronMaker(opts)
+
+	a := &arrayStream{w: w, opts: opts, c: c}
+	var buffered statements
+	streaming := true
+	n := 1
+	for ; scanner.Scan(); n++ {
+		s, err := parseLine(maker, n, scanner.Text(), c)
+		if err != nil {
+			return ExitParseStatements, err
+		}
+		if !streaming {
+			buffered.add(s)
+			continue
+		}
+		if len(s) == 0 || s[0].typ == typIgnored {
+			continue
+		}
+
+		ok, code, err := a.add(s)
+		if err != nil {
+			return code, err
+		}
+		if ok {
+			continue
+		}
+		if a.written > 0 {
+			return ExitParseStatements, fmt.Errorf("statement for %s is out of order; it must come before the statements for later elements of %s", s.path(), a.top.path())
+		}
+
+		// Nothing's been written yet, so carry on as Ungron would
+		streaming = false
+		if a.top != nil {
+			buffered.add(a.top)
+		}
+		buffered = append(buffered, a.elem...)
+		buffered.add(s)
+	}
+	if err := scanner.Err(); err != nil {
+		return ExitReadInput, c.scanError(err, n, "failed to read input statements")
+	}
+
+	if !streaming {
+		return writeUngronned(w, buffered, opts, c)
+	}
+	return a.close()
+}
+
+// an arrayStream collects the statements for each element of a
+// top-level array, and writes the element as JSON once they're complete
+type arrayStream struct {
+	w    io.Writer
+	opts int
+	c    *config
+
+	// top is the statement for the array itself (e.g. json = [];)
+	// or nil if it hasn't been seen yet
+	top statement
+
+	// index is the index of the element being collected,
+	// and elem the statements for it
+	index int
+	elem  statements
+
+	// written is the number of elements written so far, counting
+	// the nulls written for any that there weren't statements for
+	written int
+}
+
+// add adds a statement to the stream, writing the previous element if
+// it's the first statement for a new one. It returns false, without
+// adding it, if the statement isn't one that can be streamed
+func (a *arrayStream) add(s statement) (bool, int, error) {
+	if a.top == nil {
+		v, ok := s.value()
+		if !ok || len(s.path()) != 1 || v.typ != typEmptyArray {
+			return false, ExitOK, nil
+		}
+		a.top = s
+		return true, ExitOK, nil
+	}
+
+	i, ok := a.elementIndex(s)
+	if !ok || i < a.index {
+		return false, ExitOK, nil
+	}
+	if i > a.index && len(a.elem) > 0 {
+		if code, err := a.flush(); err != nil {
+			return false, code, err
+		}
+	}
+	a.index = i
+	a.elem.add(s)
+	return true, ExitOK, nil
+}
+
+// elementIndex returns the index of the element of the
+// top-level array that a statement is for, if it's for one
+func (a *arrayStream) elementIndex(s statement) (int, bool) {
+	p := s.path()
+	if len(p) < 4 || p[0] != a.top[0] || p[1].typ != typLBrace || p[2].typ != typNumericKey || p[3].typ != typRBrace {
+		return 0, false
+	}
+	i, err := strconv.Atoi(p[2].text)
+	return i, err == nil
+}
+
+// flush writes the element being collected, after a null for each
+// element before it that there weren't any statements for
+func (a *arrayStream) flush() (int, error) {
+	// Without the index, the statements for an element make a
+	// document of their own; e.g. json[3].a = 1; becomes json.a = 1;
+	ss := make(statements, 0, len(a.elem))
+	for _, s := range a.elem {
+		ss.add(append(statement{s[0]}, s[4:]...))
+	}
+	if a.c.strict {
+		if err := ss.conflict(); err != nil {
+			return ExitParseStatements, err
+		}
+	}
+	v, _, err := ss.merge(a.c.maxIndex)
+	if err != nil {
+		return ExitParseStatements, err
+	}
+	a.elem = a.elem[:0]
+
+	// Lines of NDJSON leave out the gaps, as Ungron does
+	if a.opts&OptNDJSON > 0 {
+		a.written = a.index + 1
+		return a.writeElement(v)
+	}
+	for a.written < a.index {
+		if code, err := a.writeElement(nil); err != nil {
+			return code, err
+		}
+	}
+	return a.writeElement(v)
+}
+
+// writeElement writes the next element of the array, laid out
+// as it would be in the JSON that Ungron writes for the whole array
+func (a *arrayStream) writeElement(v interface{}) (int, error) {
+	if a.opts&OptNDJSON > 0 {
+		j, err := encodeJSON(v, OptMonochrome, "")
+		if err != nil {
+			return ExitJSONEncode, fmt.Errorf("failed to convert statements to JSON: %s", err)
+		}
+		fmt.Fprintf(a.w, "%s\n", j)
+		return ExitOK, nil
+	}
+
+	indent := a.c.indent
+	j, err := encodeJSON(v, a.opts, indent)
+	if err != nil {
+		return ExitJSONEncode, fmt.Errorf("failed to convert statements to JSON: %s", err)
+	}
+
+	sep := ","
+	if a.written == 0 {
+		sep = a.brace("[")
+	}
+	if indent != "" {
+		sep += "\n" + indent
+		j = bytes.Replace(j, []byte("\n"), []byte("\n"+indent), -1)
+	}
+	fmt.Fprintf(a.w, "%s%s", sep, j)
+	a.written++
+	return ExitOK, nil
+}
+
+// close writes the last element and the end of the array
+func (a *arrayStream) close() (int, error) {
+	if a.top == nil {
+		// There weren't any statements
+		return writeUngronned(a.w, nil, a.opts, a.c)
+	}
+	if len(a.elem) > 0 {
+		if code, err := a.flush(); err != nil {
+			return code, err
+		}
+	}
+
+	switch {
+	case a.opts&OptNDJSON > 0:
+	case a.written == 0:
+		fmt.Fprintf(a.w, "%s\n", a.brace("[]"))
+	case a.c.indent == "":
+		fmt.Fprintf(a.w, "%s\n", a.brace("]"))
+	default:
+		fmt.Fprintf(a.w, "\n%s\n", a.brace("]"))
+	}
+	return ExitOK, nil
+}
+
+// brace returns the text of a bracket colored as Ungron colors them
+func (a *arrayStream) brace(text string) string {
+	if a.opts&OptMonochrome > 0 || a.c.indent == "" {
+		return text
+	}
+	return BraceColor.Sprint(text)
+}
@@ -0,0 +1,52 @@
+package gron
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestUngronStreamMatchesUngron(t *testing.T) {
+	inputs := []string{
+		// An array, as GronStream writes it
+		"json = [];\njson[0] = {};\njson[0].a = 1;\njson[1] = [];\njson[1][0] = \"x\";\njson[2] = true;\n",
+		// Gaps are nulls
+		"json = [];\njson[1] = {};\njson[1].a = 1;\njson[3] = 2;\n",
+		// An empty array
+		"json = [];\n",
+		// Not an array
+		"json = {};\njson.a = [];\njson.a[0] = 1;\n",
+		// Out of order before anything's been written
+		"json = [];\njson[1] = 2;\njson[0] = 1;\n",
+		// No statements at all
+		"",
+	}
+
+	for _, in := range inputs {
+		for _, opts := range []int{OptMonochrome, OptMonochrome | OptNDJSON} {
+			for _, indent := range []string{DefaultIndent, ""} {
+				want := &bytes.Buffer{}
+				wantCode, wantErr := Ungron(strings.NewReader(in), want, opts, WithIndent(indent))
+
+				have := &bytes.Buffer{}
+				code, err := UngronStream(strings.NewReader(in), have, opts, WithIndent(indent))
+				if code != wantCode || (err == nil) != (wantErr == nil) {
+					t.Errorf("want %d and %v for %q; have %d and %v", wantCode, wantErr, in, code, err)
+				}
+				if have.String() != want.String() {
+					t.Errorf("want %q for %q with opts %d and indent %q; have %q", want, in, opts, indent, have)
+				}
+			}
+		}
+	}
+}
+
+func TestUngronStreamOutOfOrder(t *testing.T) {
+	in := "json = [];\njson[0] = 1;\njson[1] = 2;\njson[0] = 3;\n"
+
+	out := &bytes.Buffer{}
+	code, err := UngronStream(strings.NewReader(in), out, OptMonochrome)
+	if code != ExitParseStatements || err == nil {
+		t.Errorf("want ExitParseStatements and an error; have %d and %v", code, err)
+	}
+}
@@ -2,9 +2,11 @@ package gron
 
 import (
 	"bufio"
+	"compress/gzip"
 	"crypto/tls"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"regexp"
 	"time"
@@ -15,13 +17,59 @@ func ValidURL(url string) bool {
 	return r.MatchString(url)
 }
 
-func GetURL(url string, insecure bool, gronVersion string) (io.Reader, error) {
+// DefaultTimeout is the default time limit for fetching a URL,
+// including reading the response body
+const DefaultTimeout = 20 * time.Second
+
+// WithTimeout sets the time limit for GetURL to fetch a URL, including
+// reading the response body. A timeout of zero means no limit.
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.timeout = d
+	}
+}
+
+// DefaultRetryDelay is how long GetURL waits before
+// its first retry, if it's set to retry with WithRetry
+const DefaultRetryDelay = time.Second
+
+// WithRetry makes GetURL try again, up to n more times, when it can't
+// connect or the server responds with a 5xx status; waiting for delay
+// before the first retry and twice as long before each one after that.
+// A 4xx status, like 404, fails straight away with an error that names
+// it. If every attempt fails, the error says how many were made. By
+// default there are no retries, and the response is used whatever its
+// status.
+func WithRetry(n int, delay time.Duration) Option {
+	return func(c *config) {
+		c.retries = n
+		c.retryDelay = delay
+	}
+}
+
+// WithHeader adds a header to the request that GetURL makes, in place
+// of any header it would otherwise send with the same key, such as
+// Accept. Headers with the same key can be added more than once.
+func WithHeader(key, value string) Option {
+	return func(c *config) {
+		if c.headers == nil {
+			c.headers = make(http.Header)
+		}
+		c.headers.Add(key, value)
+	}
+}
+
+func GetURL(url string, insecure bool, gronVersion string, options ...Option) (io.Reader, error) {
+	c := newConfig(options)
 	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure},
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: insecure,
+			ServerName:         c.tlsServerName,
+		},
 	}
 	client := http.Client{
 		Transport: tr,
-		Timeout:   20 * time.Second,
+		Timeout:   c.timeout,
 	}
 
 	req, err := http.NewRequest("GET", url, nil)
@@ -30,12 +78,79 @@ func GetURL(url string, insecure bool, gronVersion string) (io.Reader, error) {
 	}
 	req.Header.Set("User-Agent", fmt.Sprintf("gron/%s", gronVersion))
 	req.Header.Set("Accept", "application/json")
+	for k, vs := range c.headers {
+		req.Header[k] = vs
+	}
 
-	resp, err := client.Do(req)
-
+	resp, err := c.fetch(client, req)
 	if err != nil {
 		return nil, err
 	}
 
-	return bufio.NewReader(resp.Body), err
+	var body io.Reader = &timeoutReader{resp.Body, c}
+
+	// The body is only still compressed if the server compressed it
+	// without being asked; otherwise it's decompressed by the transport
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		body, err = gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress response: %s", err)
+		}
+	}
+
+	return bufio.NewReader(body), nil
+}
+
+// fetch makes a request, retrying as set with WithRetry. Only with
+// retries is a 4xx or 5xx status an error
+func (c *config) fetch(client http.Client, req *http.Request) (*http.Response, error) {
+	delay := c.retryDelay
+	for attempt := 1; ; attempt++ {
+		resp, err := client.Do(req)
+		if c.retries == 0 || (err == nil && resp.StatusCode < 400) {
+			if err != nil {
+				return nil, c.timeoutError(err)
+			}
+			return resp, nil
+		}
+
+		if err != nil {
+			err = c.timeoutError(err)
+		} else {
+			resp.Body.Close()
+			err = fmt.Errorf("server responded with %s", resp.Status)
+			if resp.StatusCode < 500 {
+				return nil, err
+			}
+		}
+		if attempt > c.retries {
+			return nil, fmt.Errorf("giving up after %d attempts: %s", attempt, err)
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// timeoutError returns a clearer error in place of
+// any error caused by the timeout elapsing
+func (c *config) timeoutError(err error) error {
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		return fmt.Errorf("request timed out after %s", c.timeout)
+	}
+	return err
+}
+
+// a timeoutReader reads a response body, replacing
+// any error caused by the timeout elapsing
+type timeoutReader struct {
+	r io.Reader
+	c *config
+}
+
+func (t *timeoutReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = t.c.timeoutError(err)
+	}
+	return n, err
 }
@@ -1,7 +1,12 @@
 package gron
 
 import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestValidURL(t *testing.T) {
@@ -23,3 +28,140 @@ func TestValidURL(t *testing.T) {
 		}
 	}
 }
+
+func TestGetURLServerName(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"servername": %q}`, r.TLS.ServerName)
+	}))
+	defer srv.Close()
+
+	r, err := GetURL(srv.URL, true, "test", WithTLSServerName("api.example.com"))
+	if err != nil {
+		t.Fatalf("want nil error from GetURL; have %s", err)
+	}
+
+	have, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("want nil error reading response; have %s", err)
+	}
+	want := `{"servername": "api.example.com"}`
+	if string(have) != want {
+		t.Errorf("want %s; have %s", want, have)
+	}
+}
+
+func TestGetURLTimeout(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	_, err := GetURL(srv.URL, false, "test", WithTimeout(50*time.Millisecond))
+	if err == nil {
+		t.Fatalf("want error from GetURL; have nil")
+	}
+	want := "request timed out after 50ms"
+	if err.Error() != want {
+		t.Errorf("want %q; have %q", want, err)
+	}
+}
+
+func TestGetURLHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s|%s|%s", r.Header.Get("Authorization"), r.Header.Get("Accept"), r.Header.Get("User-Agent"))
+	}))
+	defer srv.Close()
+
+	r, err := GetURL(srv.URL, false, "test",
+		WithHeader("Authorization", "Bearer xyz"),
+		WithHeader("accept", "application/vnd.api+json"),
+	)
+	if err != nil {
+		t.Fatalf("want nil error from GetURL; have %s", err)
+	}
+
+	have, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("want nil error reading response; have %s", err)
+	}
+	want := "Bearer xyz|application/vnd.api+json|gron/test"
+	if string(have) != want {
+		t.Errorf("want %s; have %s", want, have)
+	}
+}
+
+func TestGetURLGzip(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(gzipped(t, `{"name": "Tom"}`))
+	}))
+	defer srv.Close()
+
+	// Ask for it so that the transport doesn't decompress it itself
+	r, err := GetURL(srv.URL, false, "test", WithHeader("Accept-Encoding", "gzip"))
+	if err != nil {
+		t.Fatalf("want nil error from GetURL; have %s", err)
+	}
+
+	have, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("want nil error reading response; have %s", err)
+	}
+	want := `{"name": "Tom"}`
+	if string(have) != want {
+		t.Errorf("want %s; have %s", want, have)
+	}
+}
+
+func TestGetURLRetry(t *testing.T) {
+	fails := 2
+	status := http.StatusServiceUnavailable
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= fails {
+			w.WriteHeader(status)
+			return
+		}
+		fmt.Fprint(w, `{"ok": true}`)
+	}))
+	defer srv.Close()
+
+	// Enough retries gets through
+	r, err := GetURL(srv.URL, false, "test", WithRetry(2, time.Millisecond))
+	if err != nil {
+		t.Fatalf("want nil error from GetURL; have %s", err)
+	}
+	have, _ := ioutil.ReadAll(r)
+	if string(have) != `{"ok": true}` || calls != 3 {
+		t.Errorf("want the response after 3 calls; have %q after %d", have, calls)
+	}
+
+	// Too few doesn't, and the error says how many attempts there were
+	calls = 0
+	_, err = GetURL(srv.URL, false, "test", WithRetry(1, time.Millisecond))
+	want := "giving up after 2 attempts: server responded with 503 Service Unavailable"
+	if err == nil || err.Error() != want {
+		t.Errorf("want error %q; have %v", want, err)
+	}
+
+	// 4xx statuses fail without being retried
+	calls, status = 0, http.StatusNotFound
+	_, err = GetURL(srv.URL, false, "test", WithRetry(3, time.Millisecond))
+	want = "server responded with 404 Not Found"
+	if err == nil || err.Error() != want || calls != 1 {
+		t.Errorf("want error %q after 1 call; have %v after %d", want, err, calls)
+	}
+
+	// Nor is anything without WithRetry, and the
+	// response is used whatever its status
+	for _, status = range []int{http.StatusNotFound, http.StatusInternalServerError} {
+		calls = 0
+		_, err = GetURL(srv.URL, false, "test")
+		if err != nil || calls != 1 {
+			t.Errorf("want nil error for %d after 1 call; have %v after %d", status, err, calls)
+		}
+	}
+}
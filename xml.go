@@ -0,0 +1,197 @@
+package gron
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// statementsFromXML takes an io.Reader containing XML and returns
+// statements or an error on failure. XML has no types, so every value
+// is a string; the top-level value is an object with the root element
+// as its only key, e.g. json.users.user[0]["@id"] = "1"; The document
+// must be in UTF-8, US-ASCII or ISO-8859-1 (Latin-1)
+func statementsFromXML(r io.Reader, prefix statement, c *config) (statements, error) {
+	d := xml.NewDecoder(r)
+	d.CharsetReader = xmlCharsetReader
+	var top map[string]interface{}
+	for {
+		// Raw tokens keep the namespace prefixes in names as
+		// they're written, rather than the namespaces' URLs
+		t, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Anything outside the root element other than whitespace,
+		// comments, processing instructions and the like is an error
+		switch tt := t.(type) {
+		case xml.StartElement:
+			if top != nil {
+				return nil, fmt.Errorf("XML document has more than one root element")
+			}
+			v, err := xmlElement(d, tt, 1)
+			if err != nil {
+				return nil, err
+			}
+			top = map[string]interface{}{xmlName(tt.Name): v}
+		case xml.EndElement:
+			return nil, fmt.Errorf("XML document has </%s> without a start tag", xmlName(tt.Name))
+		case xml.CharData:
+			if len(strings.TrimSpace(string(tt))) > 0 {
+				return nil, fmt.Errorf("XML document has text outside of the root element")
+			}
+		}
+	}
+	if top == nil {
+		return nil, fmt.Errorf("XML document has no root element")
+	}
+
+	ss := make(statements, 0, 32)
+	ss.fill(prefix, top, 0, c)
+	return ss, nil
+}
+
+// xmlElement reads the contents of an element up to and including its
+// end tag, and returns them as a value of the types used for values
+// decoded from JSON:
+//
+//   - attributes are keys with an @ in front of their names; e.g. @id
+//   - child elements are keys with their names; several with the same
+//     name are an array of them, in the order they're in the document
+//   - text is a string if there's nothing else in the element, or
+//     the #text key if there is; leading and trailing whitespace is
+//     removed, and the text of mixed content is joined together
+//   - an empty element, with no attributes, is null
+//
+// Names keep their namespace prefixes, so that elements and attributes
+// from different namespaces don't collide; e.g. media:title and
+// @xmlns:media. The end tag must match the start tag. depth is how
+// deeply the element is nested, counting the root element as 1
+func xmlElement(d *xml.Decoder, start xml.StartElement, depth int) (interface{}, error) {
+	if depth > maxXMLDepth {
+		return nil, fmt.Errorf("XML elements are nested more than %d deep", maxXMLDepth)
+	}
+
+	obj := make(map[string]interface{})
+	for _, a := range start.Attr {
+		obj[xmlAttrKey(a.Name)] = a.Value
+	}
+
+	// Keys that are already arrays of repeated elements
+	repeated := make(map[string]bool)
+	text := &strings.Builder{}
+	for {
+		t, err := d.RawToken()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+
+		switch tt := t.(type) {
+		case xml.StartElement:
+			v, err := xmlElement(d, tt, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			k := xmlName(tt.Name)
+			existing, exists := obj[k]
+			switch {
+			case !exists:
+				obj[k] = v
+			case repeated[k]:
+				obj[k] = append(existing.([]interface{}), v)
+			default:
+				obj[k] = []interface{}{existing, v}
+				repeated[k] = true
+			}
+
+		case xml.CharData:
+			text.Write(tt)
+
+		case xml.EndElement:
+			if tt.Name != start.Name {
+				return nil, fmt.Errorf("XML element <%s> is closed by </%s>", xmlName(start.Name), xmlName(tt.Name))
+			}
+			s := strings.TrimSpace(text.String())
+			if len(obj) == 0 {
+				if s == "" {
+					return nil, nil
+				}
+				return s, nil
+			}
+			if s != "" {
+				obj["#text"] = s
+			}
+			return obj, nil
+		}
+	}
+}
+
+// maxXMLDepth limits how deeply elements can be nested, so a document
+// can't make xmlElement recurse until it runs out of stack
+const maxXMLDepth = 10000
+
+// xmlAttrKey returns the key for an attribute
+func xmlAttrKey(name xml.Name) string {
+	return "@" + xmlName(name)
+}
+
+// xmlName returns a name as it's written, with its prefix if it
+// has one; e.g. media:title. The Space of a raw token is the prefix
+func xmlName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// xmlCharsetReader converts documents in the encodings other than UTF-8
+// that can be read to UTF-8; those are US-ASCII, which already is, and
+// ISO-8859-1, where each byte is the code point of the same number
+func xmlCharsetReader(label string, r io.Reader) (io.Reader, error) {
+	switch strings.ToLower(label) {
+	case "us-ascii", "ascii":
+		return r, nil
+	case "iso-8859-1", "iso8859-1", "latin1", "l1":
+		return &latin1Reader{r: bufio.NewReader(r)}, nil
+	}
+	return nil, fmt.Errorf("XML encoding %q isn't supported; only UTF-8, US-ASCII and ISO-8859-1 are", label)
+}
+
+// a latin1Reader reads ISO-8859-1 as UTF-8
+type latin1Reader struct {
+	r *bufio.Reader
+	// pending is the rest of a character that didn't fit in p
+	pending []byte
+}
+
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	n := copy(p, l.pending)
+	l.pending = l.pending[n:]
+	for n < len(p) {
+		b, err := l.r.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return n, err
+		}
+		if b < utf8.RuneSelf {
+			p[n] = b
+			n++
+			continue
+		}
+		var buf [2]byte
+		size := utf8.EncodeRune(buf[:], rune(b))
+		m := copy(p[n:], buf[:size])
+		l.pending = append(l.pending[:0], buf[m:size]...)
+		n += m
+	}
+	return n, nil
+}
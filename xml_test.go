@@ -0,0 +1,104 @@
+package gron
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGronXML(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{
+			"<?xml version=\"1.0\"?>\n<!-- users -->\n<users count=\"2\">\n  <user id=\"1\">Tom</user>\n  <user id=\"2\"><name>Bob</name><admin/></user>\n  <note>  hi  </note>\n</users>\n",
+			`json = {};
+json.users = {};
+json.users.note = "hi";
+json.users.user = [];
+json.users.user[0] = {};
+json.users.user[0]["#text"] = "Tom";
+json.users.user[0]["@id"] = "1";
+json.users.user[1] = {};
+json.users.user[1].admin = null;
+json.users.user[1].name = "Bob";
+json.users.user[1]["@id"] = "2";
+json.users["@count"] = "2";
+`,
+		},
+		{
+			`<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/"><title><![CDATA[a & b]]></title><media:thumbnail url="x.png"/></feed>`,
+			`json = {};
+json.feed = {};
+json.feed.title = "a & b";
+json.feed["@xmlns"] = "http://www.w3.org/2005/Atom";
+json.feed["@xmlns:media"] = "http://search.yahoo.com/mrss/";
+json.feed["media:thumbnail"] = {};
+json.feed["media:thumbnail"]["@url"] = "x.png";
+`,
+		},
+		{
+			`<r xmlns:a="urn:a" xmlns:b="urn:b"><a:x>1</a:x><b:x xml:lang="en">2</b:x><x>3</x></r>`,
+			`json = {};
+json.r = {};
+json.r.x = "3";
+json.r["@xmlns:a"] = "urn:a";
+json.r["@xmlns:b"] = "urn:b";
+json.r["a:x"] = "1";
+json.r["b:x"] = {};
+json.r["b:x"]["#text"] = "2";
+json.r["b:x"]["@xml:lang"] = "en";
+`,
+		},
+		{
+			"<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<p>caf\xe9</p>",
+			`json = {};
+json.p = "café";
+`,
+		},
+		{
+			"<p>Hello <b>world</b>!</p>",
+			`json = {};
+json.p = {};
+json.p.b = "world";
+json.p["#text"] = "Hello !";
+`,
+		},
+	}
+
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		code, err := Gron(strings.NewReader(c.in), out, OptMonochrome|OptXML)
+		if code != ExitOK || err != nil {
+			t.Errorf("want ExitOK and nil error for %q; have %d and %v", c.in, code, err)
+			continue
+		}
+		if out.String() != c.want {
+			t.Logf("want: %s", c.want)
+			t.Logf("have: %s", out.String())
+			t.Errorf("output for %q does not match", c.in)
+		}
+	}
+}
+
+func TestGronXMLInvalid(t *testing.T) {
+	cases := []string{
+		"<!-- only a comment -->\n",
+		"<a><b></a>",
+		"<a>1</a><b>2</b>",
+		"<a>1</a>text",
+		"<a>",
+		"</a>",
+		"<a:x></b:x>",
+		"<?xml version=\"1.0\" encoding=\"Shift_JIS\"?>\n<p>x</p>",
+		strings.Repeat("<a>", 20000) + strings.Repeat("</a>", 20000),
+	}
+
+	for _, c := range cases {
+		code, err := Gron(strings.NewReader(c), &bytes.Buffer{}, OptMonochrome|OptXML)
+		if code != ExitFormStatements || err == nil {
+			t.Errorf("want ExitFormStatements and an error for %q; have %d and %v", c, code, err)
+		}
+	}
+}
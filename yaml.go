@@ -0,0 +1,117 @@
+package gron
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// statementsFromYAML takes an io.Reader containing YAML and returns
+// statements or an error on failure. A single document is treated like
+// a JSON document; several (separated by ---) are treated as elements of
+// a top-level array, as GronStream treats lines of JSON
+func statementsFromYAML(r io.Reader, prefix statement, c *config) (statements, error) {
+	var docs []interface{}
+	d := yaml.NewDecoder(r)
+	for {
+		var v interface{}
+		err := d.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		v, err = yamlToJSON(v)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, v)
+	}
+
+	ss := make(statements, 0, 32)
+	switch len(docs) {
+	case 0:
+		return nil, fmt.Errorf("no YAML documents in input")
+	case 1:
+		ss.fill(prefix, docs[0], 0, c)
+	default:
+		ss.addWithValue(prefix, token{"[]", typEmptyArray})
+		for i, doc := range docs {
+			ss.fill(prefix.withNumericKey(i), doc, 0, c)
+		}
+	}
+	return ss, nil
+}
+
+// yamlToJSON converts a value decoded from YAML into the types used
+// for values decoded from JSON. Keys that aren't strings, which YAML
+// allows, are converted to strings; e.g. the key in 1: one becomes "1"
+// and the key in ~: none becomes "null". Timestamps are written as
+// RFC 3339 strings, and numbers that JSON can't represent (infinities
+// and NaN) are an error
+func yamlToJSON(v interface{}) (interface{}, error) {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, sub := range vv {
+			conv, err := yamlToJSON(sub)
+			if err != nil {
+				return nil, err
+			}
+			vv[k] = conv
+		}
+		return vv, nil
+
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(vv))
+		for k, sub := range vv {
+			conv, err := yamlToJSON(sub)
+			if err != nil {
+				return nil, err
+			}
+			if k == nil {
+				out["null"] = conv
+				continue
+			}
+			out[fmt.Sprint(k)] = conv
+		}
+		return out, nil
+
+	case []interface{}:
+		for i, sub := range vv {
+			conv, err := yamlToJSON(sub)
+			if err != nil {
+				return nil, err
+			}
+			vv[i] = conv
+		}
+		return vv, nil
+
+	case int:
+		return json.Number(strconv.Itoa(vv)), nil
+	case int64:
+		return json.Number(strconv.FormatInt(vv, 10)), nil
+	case uint64:
+		return json.Number(strconv.FormatUint(vv, 10)), nil
+	case float64:
+		if math.IsInf(vv, 0) || math.IsNaN(vv) {
+			return nil, fmt.Errorf("YAML number %v can't be represented in JSON", vv)
+		}
+		return json.Number(strconv.FormatFloat(vv, 'g', -1, 64)), nil
+
+	case time.Time:
+		return vv.Format(time.RFC3339Nano), nil
+
+	case string, bool, nil:
+		return vv, nil
+
+	default:
+		return fmt.Sprint(vv), nil
+	}
+}
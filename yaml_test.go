@@ -0,0 +1,86 @@
+package gron
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGronYAML(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{
+			"kind: Pod\nmetadata:\n  name: web\n  labels: {app: web}\nspec:\n  replicas: 3\n  ratio: 0.5\n  ports: [80, 443]\n",
+			`json = {};
+json.kind = "Pod";
+json.metadata = {};
+json.metadata.labels = {};
+json.metadata.labels.app = "web";
+json.metadata.name = "web";
+json.spec = {};
+json.spec.ports = [];
+json.spec.ports[0] = 80;
+json.spec.ports[1] = 443;
+json.spec.ratio = 0.5;
+json.spec.replicas = 3;
+`,
+		},
+		{
+			"---\nname: one\n---\nname: two\n",
+			`json = [];
+json[0] = {};
+json[0].name = "one";
+json[1] = {};
+json[1].name = "two";
+`,
+		},
+		{
+			"1: one\ntrue: yes\nnull: ~\n\"a b\": c\n",
+			`json = {};
+json["1"] = "one";
+json["a b"] = "c";
+json["null"] = null;
+json["true"] = "yes";
+`,
+		},
+		{
+			"when: 2020-01-02T03:04:05Z\nquoted: \"42\"\n",
+			`json = {};
+json.quoted = "42";
+json.when = "2020-01-02T03:04:05Z";
+`,
+		},
+	}
+
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		code, err := Gron(strings.NewReader(c.in), out, OptMonochrome|OptYAML)
+		if code != ExitOK || err != nil {
+			t.Errorf("want ExitOK and nil error for %q; have %d and %v", c.in, code, err)
+			continue
+		}
+		if out.String() != c.want {
+			t.Logf("want: %s", c.want)
+			t.Logf("have: %s", out.String())
+			t.Errorf("output for %q does not match", c.in)
+		}
+	}
+}
+
+func TestGronYAMLInvalid(t *testing.T) {
+	cases := []string{
+		"# only a comment\n",
+		"a: [1, 2\n",
+		"a: b\n  c: d\n",
+		"a: .inf\n",
+	}
+
+	for _, c := range cases {
+		code, err := Gron(strings.NewReader(c), &bytes.Buffer{}, OptMonochrome|OptYAML)
+		if code != ExitFormStatements || err == nil {
+			t.Errorf("want ExitFormStatements and an error for %q; have %d and %v", c, code, err)
+		}
+	}
+}